@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// entityEventPublisher publishes the events of the writes of a repository on the event bus
+// of its DI container and, when enabled, records them in the outbox and in the history.
+type entityEventPublisher struct {
+	entityId string
+	session  sdk.Session
+	di       sdk.EndorDIContainerInterface
+	history  *mongoHistory
+}
+
+// entityChange is the outcome of a write; before and after are the instances (nil if missing).
+type entityChange struct {
+	eventType  sdk.EntityEventType
+	instanceId string
+	category   string
+	before     any
+	after      any
+}
+
+// maxHistoryAttempts bounds the runs of a write whose history version is taken by
+// concurrent writes of the same instance.
+const maxHistoryAttempts = 3
+
+// track runs write and publishes the event of its change. With the outbox or the history
+// enabled, write runs in a transaction together with the insert of the outbox and history
+// entries; within sdk.WithTransaction the event is published after the commit. observed
+// tells write whether the event is needed at all, so that it can skip reading the
+// previous state.
+func (p entityEventPublisher) track(ctx context.Context, write func(ctx context.Context, observed bool) (entityChange, error)) error {
+	bus := p.bus()
+	var outbox *sdk.Outbox
+	if p.di != nil {
+		outbox = p.di.GetOutbox()
+	}
+	if outbox == nil && p.history == nil {
+		observed := bus != nil
+		change, err := write(ctx, observed)
+		if err != nil || !observed {
+			return err
+		}
+		event, err := p.event(ctx, change)
+		if err != nil {
+			return err
+		}
+		return p.publish(ctx, bus, event)
+	}
+
+	var event sdk.EntityEvent
+	transaction := func(ctx context.Context) error {
+		change, err := write(ctx, true)
+		if err != nil {
+			return err
+		}
+		if event, err = p.event(ctx, change); err != nil {
+			return err
+		}
+		if p.history != nil {
+			if err := p.history.record(ctx, event); err != nil {
+				return err
+			}
+		}
+		if outbox == nil {
+			return nil
+		}
+		return outbox.Add(ctx, event)
+	}
+	// a write joining the transaction of ctx cannot be run again: the conflict aborts it
+	joined := mongo.SessionFromContext(ctx) != nil
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = inTransaction(ctx, transaction)
+		if joined || attempt == maxHistoryAttempts || !errors.Is(err, errHistoryVersionTaken) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return p.publish(ctx, bus, event)
+}
+
+// publish publishes event on bus, after the commit if ctx belongs to a transaction. The
+// write is saved by then, so the errors of the subscribers are logged, not returned.
+func (p entityEventPublisher) publish(ctx context.Context, bus *sdk.EventBus, event sdk.EntityEvent) error {
+	if bus == nil {
+		return nil
+	}
+	return sdk.AfterCommit(ctx, func(ctx context.Context) error {
+		bus.PublishCommitted(ctx, event)
+		return nil
+	})
+}
+
+// bus returns the event bus if anyone observes the entity, nil otherwise.
+func (p entityEventPublisher) bus() *sdk.EventBus {
+	if p.di == nil {
+		return nil
+	}
+	bus := p.di.GetEventBus()
+	if !bus.HasSubscribers(p.entityId) {
+		return nil
+	}
+	return bus
+}
+
+// event builds the event of change; the session is the one of ctx, the repository one otherwise.
+func (p entityEventPublisher) event(ctx context.Context, change entityChange) (sdk.EntityEvent, error) {
+	before, err := toEventDocument(change.before)
+	if err != nil {
+		return sdk.EntityEvent{}, err
+	}
+	after, err := toEventDocument(change.after)
+	if err != nil {
+		return sdk.EntityEvent{}, err
+	}
+	session, ok := sdk.SessionFromContext(ctx)
+	if !ok {
+		session = p.session
+	}
+	return sdk.NewEntityEvent(change.eventType, p.entityId, change.category, change.instanceId, before, after, session), nil
+}
+
+// toEventDocument returns the JSON representation of instance, as returned by the actions.
+func toEventDocument(instance any) (map[string]any, error) {
+	if instance == nil {
+		return nil, nil
+	}
+	jsonBytes, err := json.Marshal(instance)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(jsonBytes, &doc); err != nil {
+		return nil, sdk.NewInternalServerError(err)
+	}
+	return doc, nil
+}
+
+// categoryOf returns the category of a specialized instance, empty otherwise.
+func categoryOf(instance any) string {
+	if specialized, ok := instance.(sdk.EntityInstanceSpecializedInterface); ok {
+		return specialized.GetCategoryType()
+	}
+	return ""
+}
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 	"go.mongodb.org/mongo-driver/bson"
@@ -664,11 +667,17 @@ type mongoBaseRepository[T sdk.EntityInstanceInterface] struct {
 	autoGenerateID bool
 	objectIDFields *ObjectIDFieldRegistry
 	documentMapper *DocumentMapper[T]
+	// versioned keeps sdk.VersionField in every document (optimistic concurrency)
+	versioned bool
+	// softDelete marks deleted documents with sdk.DeletedAtField instead of removing them
+	softDelete bool
 }
 
 func newMongoBaseRepository[T sdk.EntityInstanceInterface](
 	collection *mongo.Collection,
 	autoGenerateID bool,
+	versioned bool,
+	softDelete bool,
 ) *mongoBaseRepository[T] {
 	return &mongoBaseRepository[T]{
 		collection:     collection,
@@ -676,15 +685,21 @@ func newMongoBaseRepository[T sdk.EntityInstanceInterface](
 		autoGenerateID: autoGenerateID,
 		objectIDFields: NewObjectIDFieldRegistry[T](),
 		documentMapper: &DocumentMapper[T]{},
+		versioned:      versioned,
+		softDelete:     softDelete,
 	}
 }
 
-// FindByID retrieves a single document by its _id.
-func (r *mongoBaseRepository[T]) FindByID(ctx context.Context, id string) (bson.M, error) {
+// FindByID retrieves a single document by its _id; soft-deleted documents are found only
+// with includeDeleted.
+func (r *mongoBaseRepository[T]) FindByID(ctx context.Context, id string, includeDeleted bool) (bson.M, error) {
 	filter, err := r.idStrategy.CreateFilter(id)
 	if err != nil {
 		return nil, sdk.NewBadRequestError(err)
 	}
+	if r.softDelete && !includeDeleted {
+		filter[sdk.DeletedAtField] = nil
+	}
 
 	var result bson.M
 	err = r.collection.FindOne(ctx, filter).Decode(&result)
@@ -698,37 +713,107 @@ func (r *mongoBaseRepository[T]) FindByID(ctx context.Context, id string) (bson.
 	return result, nil
 }
 
-// Find retrieves documents matching the filter with optional projection.
-func (r *mongoBaseRepository[T]) Find(ctx context.Context, filter bson.M, projection bson.M) ([]bson.M, error) {
-	mongoFilter := filter
-	if mongoFilter == nil {
-		mongoFilter = bson.M{}
-	} else {
-		mongoFilter = cloneBsonM(mongoFilter)
+// storageFilter returns a copy of the dto filter with ObjectID fields in storage format.
+func (r *mongoBaseRepository[T]) storageFilter(dto sdk.ReadDTO) (bson.M, error) {
+	mongoFilter := bson.M{}
+	if dto.Filter != nil {
+		mongoFilter = cloneBsonM(dto.Filter)
 	}
 
 	// Convert ObjectID fields in filter to primitive.ObjectID
 	if err := r.objectIDFields.ConvertFilterToStorage(mongoFilter); err != nil {
 		return nil, sdk.NewBadRequestError(err)
 	}
+	if r.softDelete && !dto.IncludeDeleted {
+		mongoFilter = notDeleted(mongoFilter)
+	}
+	return mongoFilter, nil
+}
+
+// notDeleted restricts filter to the documents not soft-deleted.
+func notDeleted(filter bson.M) bson.M {
+	if len(filter) == 0 {
+		return bson.M{sdk.DeletedAtField: nil}
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{sdk.DeletedAtField: nil}}}
+}
 
-	var opts *options.FindOptions
-	if projection != nil {
-		opts = options.Find().SetProjection(projection)
+// Find retrieves the documents selected by dto (filter, projection, sort and page). The
+// pagination is nil unless dto asks for a limited page or for the total.
+func (r *mongoBaseRepository[T]) Find(ctx context.Context, dto sdk.ReadDTO) ([]bson.M, *sdk.Pagination, error) {
+	mongoFilter, err := r.storageFilter(dto)
+	if err != nil {
+		return nil, nil, err
+	}
+	query, err := newFindQuery(dto, mongoFilter)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	cursor, err := r.collection.Find(ctx, mongoFilter, opts)
+	cursor, err := r.collection.Find(ctx, query.page, query.opts)
 	if err != nil {
-		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find entities: %w", err))
+		return nil, nil, sdk.NewInternalServerError(fmt.Errorf("failed to find entities: %w", err))
 	}
 	defer cursor.Close(ctx)
 
 	var results []bson.M
 	if err := cursor.All(ctx, &results); err != nil {
-		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode entities: %w", err))
+		return nil, nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode entities: %w", err))
+	}
+
+	if dto.Limit == 0 && !dto.Count {
+		query.hide(results...)
+		return results, nil, nil
+	}
+	pagination := &sdk.Pagination{}
+	if dto.Limit > 0 && int64(len(results)) == dto.Limit {
+		if pagination.Next, err = nextPageCursor(query.sort, results[len(results)-1]); err != nil {
+			return nil, nil, err
+		}
+	}
+	query.hide(results...)
+	if dto.Count {
+		total, err := r.collection.CountDocuments(ctx, query.filter)
+		if err != nil {
+			return nil, nil, sdk.NewInternalServerError(fmt.Errorf("failed to count entities: %w", err))
+		}
+		pagination.Total = &total
+	}
+	return results, pagination, nil
+}
+
+// FindEach calls fn for every document selected by dto, decoding the cursor one document
+// at a time instead of loading the whole result in memory. dto.Count is ignored.
+func (r *mongoBaseRepository[T]) FindEach(ctx context.Context, dto sdk.ReadDTO, fn func(doc bson.M) error) error {
+	mongoFilter, err := r.storageFilter(dto)
+	if err != nil {
+		return err
+	}
+	query, err := newFindQuery(dto, mongoFilter)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := r.collection.Find(ctx, query.page, query.opts)
+	if err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to find entities: %w", err))
 	}
+	defer cursor.Close(ctx)
 
-	return results, nil
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return sdk.NewInternalServerError(fmt.Errorf("failed to decode entity: %w", err))
+		}
+		query.hide(doc)
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to iterate entities: %w", err))
+	}
+	return nil
 }
 
 // Insert creates a new document. If autoGenerateID is true, a new ID is generated.
@@ -745,7 +830,7 @@ func (r *mongoBaseRepository[T]) Insert(ctx context.Context, doc bson.M, provide
 		idStr = idToString(providedID)
 
 		// Check for existing document
-		_, err := r.FindByID(ctx, idStr)
+		_, err := r.FindByID(ctx, idStr, false)
 		if err == nil {
 			return "", sdk.NewConflictError(fmt.Errorf("entity with id %s already exists", idStr))
 		}
@@ -761,6 +846,10 @@ func (r *mongoBaseRepository[T]) Insert(ctx context.Context, doc bson.M, provide
 		doc["_id"] = storageID
 	}
 
+	if r.versioned {
+		doc[sdk.VersionField] = int64(1)
+	}
+
 	result, err := r.collection.InsertOne(ctx, doc)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
@@ -776,10 +865,12 @@ func (r *mongoBaseRepository[T]) Insert(ctx context.Context, doc bson.M, provide
 	return idStr, nil
 }
 
-// Update modifies an existing document by its _id.
-func (r *mongoBaseRepository[T]) Update(ctx context.Context, id string, updateData bson.M) error {
+// Update modifies an existing document by its _id. On versioned repositories it increments
+// the version and, if expectedVersion is set, fails with a conflict when the document has a
+// different version.
+func (r *mongoBaseRepository[T]) Update(ctx context.Context, id string, updateData bson.M, expectedVersion *int64) error {
 	// Verify existence
-	if _, err := r.FindByID(ctx, id); err != nil {
+	if _, err := r.FindByID(ctx, id, false); err != nil {
 		return err
 	}
 
@@ -787,33 +878,51 @@ func (r *mongoBaseRepository[T]) Update(ctx context.Context, id string, updateDa
 	if err != nil {
 		return sdk.NewBadRequestError(err)
 	}
-
-	if len(updateData) == 0 {
-		return sdk.NewBadRequestError(fmt.Errorf("no fields to update"))
+	if r.softDelete {
+		filter[sdk.DeletedAtField] = nil
 	}
 
 	// Clone and convert ObjectID fields
 	data := cloneBsonM(updateData)
+	// the version and the deletion marks are maintained by the repository only
+	if r.versioned {
+		delete(data, sdk.VersionField)
+	}
+	if r.softDelete {
+		delete(data, sdk.DeletedAtField)
+		delete(data, sdk.DeletedByField)
+	}
+	if len(data) == 0 {
+		return sdk.NewBadRequestError(fmt.Errorf("no fields to update"))
+	}
 	if err := r.objectIDFields.ConvertToStorage(data); err != nil {
 		return sdk.NewBadRequestError(err)
 	}
 
 	update := bson.M{"$set": data}
+	if r.versioned {
+		update["$inc"] = bson.M{sdk.VersionField: 1}
+		if expectedVersion != nil {
+			filter[sdk.VersionField] = versionCondition(*expectedVersion)
+		}
+	}
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return sdk.NewInternalServerError(fmt.Errorf("failed to update entity: %w", err))
 	}
 	if result.MatchedCount == 0 {
-		return sdk.NewNotFoundError(fmt.Errorf("entity with id %s not found", id))
+		return r.missedWriteError(ctx, id, expectedVersion)
 	}
 
 	return nil
 }
 
-// Delete removes a document by its _id.
-func (r *mongoBaseRepository[T]) Delete(ctx context.Context, id string) error {
+// Delete removes a document by its _id, or marks it as deleted on soft-delete repositories.
+// On versioned repositories, if expectedVersion is set, it fails with a conflict when the
+// document has a different version.
+func (r *mongoBaseRepository[T]) Delete(ctx context.Context, id string, expectedVersion *int64) error {
 	// Verify existence
-	if _, err := r.FindByID(ctx, id); err != nil {
+	if _, err := r.FindByID(ctx, id, false); err != nil {
 		return err
 	}
 
@@ -821,15 +930,199 @@ func (r *mongoBaseRepository[T]) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return sdk.NewBadRequestError(err)
 	}
+	if r.versioned && expectedVersion != nil {
+		filter[sdk.VersionField] = versionCondition(*expectedVersion)
+	}
+
+	if r.softDelete {
+		filter[sdk.DeletedAtField] = nil
+		marks := bson.M{sdk.DeletedAtField: time.Now().UTC()}
+		if session, ok := sdk.SessionFromContext(ctx); ok && session.UserId != "" {
+			marks[sdk.DeletedByField] = session.UserId
+		}
+		update := bson.M{"$set": marks}
+		if r.versioned {
+			update["$inc"] = bson.M{sdk.VersionField: 1}
+		}
+		result, err := r.collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return sdk.NewInternalServerError(fmt.Errorf("failed to delete entity: %w", err))
+		}
+		if result.MatchedCount == 0 {
+			return r.missedWriteError(ctx, id, expectedVersion)
+		}
+		return nil
+	}
 
 	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return sdk.NewInternalServerError(fmt.Errorf("failed to delete entity: %w", err))
 	}
 	if result.DeletedCount == 0 {
+		return r.missedWriteError(ctx, id, expectedVersion)
+	}
+
+	return nil
+}
+
+// Restore brings back a soft-deleted document by its _id.
+func (r *mongoBaseRepository[T]) Restore(ctx context.Context, id string) error {
+	if !r.softDelete {
+		return sdk.NewBadRequestError(fmt.Errorf("entity does not support restore: soft delete is not enabled")).WithTranslation("sdk.entity.messages.restore_not_permitted", nil)
+	}
+	filter, err := r.idStrategy.CreateFilter(id)
+	if err != nil {
+		return sdk.NewBadRequestError(err)
+	}
+	filter[sdk.DeletedAtField] = bson.M{"$ne": nil}
+
+	update := bson.M{"$unset": bson.M{sdk.DeletedAtField: "", sdk.DeletedByField: ""}}
+	if r.versioned {
+		update["$inc"] = bson.M{sdk.VersionField: 1}
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to restore entity: %w", err))
+	}
+	if result.MatchedCount == 0 {
+		return sdk.NewNotFoundError(fmt.Errorf("deleted entity with id %s not found", id)).WithTranslation("sdk.entity.messages.not_deleted", map[string]any{"id": id})
+	}
+	return nil
+}
+
+// Replace replaces the document with the given _id with doc, or inserts it if missing, and
+// reports whether it has been inserted. Soft-deleted documents are restored; on versioned
+// repositories the version is incremented.
+func (r *mongoBaseRepository[T]) Replace(ctx context.Context, id string, doc bson.M) (bool, error) {
+	filter, err := r.idStrategy.CreateFilter(id)
+	if err != nil {
+		return false, sdk.NewBadRequestError(err)
+	}
+	storageID, err := r.idStrategy.ToStorageFormat(id)
+	if err != nil {
+		return false, sdk.NewBadRequestError(err)
+	}
+	data := cloneBsonM(doc)
+	data["_id"] = storageID
+	// the version and the deletion marks are maintained by the repository only
+	delete(data, sdk.VersionField)
+	delete(data, sdk.DeletedAtField)
+	delete(data, sdk.DeletedByField)
+
+	current, err := r.FindByID(ctx, id, true)
+	var endorErr *sdk.EndorError
+	if err != nil && (!errors.As(err, &endorErr) || endorErr.StatusCode != 404) {
+		return false, err
+	}
+	exists := err == nil
+
+	var currentVersion int64
+	if r.versioned {
+		if exists {
+			currentVersion, _ = sdk.InstanceVersion(current)
+			filter[sdk.VersionField] = versionCondition(currentVersion)
+		}
+		data[sdk.VersionField] = currentVersion + 1
+	}
+
+	if !exists {
+		if _, err := r.collection.InsertOne(ctx, data); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return false, sdk.NewConflictError(fmt.Errorf("entity already exists: %w", err))
+			}
+			return false, sdk.NewInternalServerError(fmt.Errorf("failed to create entity: %w", err))
+		}
+		return true, nil
+	}
+	result, err := r.collection.ReplaceOne(ctx, filter, data)
+	if err != nil {
+		return false, sdk.NewInternalServerError(fmt.Errorf("failed to replace entity: %w", err))
+	}
+	if result.MatchedCount == 0 {
+		return false, r.missedWriteError(ctx, id, &currentVersion)
+	}
+	return false, nil
+}
+
+// versionCondition matches the expected version; documents written before versioning was
+// enabled have no version and match version 0.
+func versionCondition(expected int64) interface{} {
+	if expected == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return expected
+}
+
+// missedWriteError explains why a write by id matched no document: the document has been
+// deleted or, on versioned repositories, its version is not the expected one.
+func (r *mongoBaseRepository[T]) missedWriteError(ctx context.Context, id string, expectedVersion *int64) error {
+	doc, err := r.FindByID(ctx, id, false)
+	if err != nil || !r.versioned || expectedVersion == nil {
 		return sdk.NewNotFoundError(fmt.Errorf("entity with id %s not found", id))
 	}
+	current, _ := sdk.InstanceVersion(doc)
+	return sdk.NewConflictError(fmt.Errorf("entity with id %s has version %d, expected %d", id, current, *expectedVersion)).WithTranslation("sdk.entity.messages.version_conflict", map[string]any{
+		"id":       id,
+		"version":  current,
+		"expected": *expectedVersion,
+	})
+}
+
+// referenceFilter matches the documents whose field holds id, stored as string or ObjectID.
+func referenceFilter(field, id string) bson.M {
+	values := bson.A{id}
+	if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+		values = append(values, oid)
+	}
+	return bson.M{field: bson.M{"$in": values}}
+}
 
+// FindReferencing returns the ids of the documents (not soft-deleted) whose field holds id.
+func (r *mongoBaseRepository[T]) FindReferencing(ctx context.Context, field, id string) ([]string, error) {
+	filter := referenceFilter(field, id)
+	if r.softDelete {
+		filter = notDeleted(filter)
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find referencing entities: %w", err))
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode referencing entities: %w", err))
+	}
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, idToString(doc["_id"]))
+	}
+	return ids, nil
+}
+
+// ClearReference sets to null the reference field of the document with the given _id where
+// it holds referencedId (every matching element for fields in arrays of objects).
+func (r *mongoBaseRepository[T]) ClearReference(ctx context.Context, id string, reference sdk.EntityReference, referencedId string) error {
+	filter, err := r.idStrategy.CreateFilter(id)
+	if err != nil {
+		return sdk.NewBadRequestError(err)
+	}
+	set := bson.M{reference.Field: nil}
+	opts := options.Update()
+	if reference.Array != "" {
+		inner := strings.TrimPrefix(reference.Field, reference.Array+".")
+		set = bson.M{reference.Array + ".$[ref]." + inner: nil}
+		opts.SetArrayFilters(options.ArrayFilters{Filters: []interface{}{referenceFilter("ref."+inner, referencedId)}})
+	}
+	update := bson.M{"$set": set}
+	if r.versioned {
+		update["$inc"] = bson.M{sdk.VersionField: 1}
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to clear reference: %w", err))
+	}
+	if result.MatchedCount == 0 {
+		return r.missedWriteError(ctx, id, nil)
+	}
 	return nil
 }
 
@@ -843,31 +1136,19 @@ func (r *mongoBaseRepository[T]) GetDocumentMapper() *DocumentMapper[T] {
 	return r.documentMapper
 }
 
-// FindReferences retrieves id->description pairs for the given entity IDs.
-// descriptionKey specifies the document field used as the human-readable description.
+// FindReferences retrieves id->description pairs for the given entity IDs, rendering the
+// description template with format; only the fields of the template are read.
 // String IDs are converted to the appropriate storage format via the ID strategy.
-func (r *mongoBaseRepository[T]) FindReferences(ctx context.Context, dto sdk.ReadInstancesDTO, descriptionKey string) (sdk.EntityReferenceGroupDescriptions, error) {
+func (r *mongoBaseRepository[T]) FindReferences(ctx context.Context, dto sdk.ReadInstancesDTO, template *sdk.DescriptionTemplate, format sdk.DescriptionFormat) (sdk.EntityReferenceGroupDescriptions, error) {
 	if len(dto.Ids) == 0 {
 		return make(sdk.EntityReferenceGroupDescriptions), nil
 	}
 
-	// Convert string IDs to the correct storage format (primitive.ObjectID or string).
-	storageIDs := make([]interface{}, 0, len(dto.Ids))
-	for _, id := range dto.Ids {
-		storageID, err := r.idStrategy.ToStorageFormat(id)
-		if err != nil {
-			return nil, sdk.NewBadRequestError(fmt.Errorf("invalid id %s: %w", id, err))
-		}
-		storageIDs = append(storageIDs, storageID)
-	}
-
-	filter := bson.M{"_id": bson.M{"$in": storageIDs}}
-	projection := bson.M{
-		"_id":          1,
-		descriptionKey: 1,
+	filter, err := r.idsFilter(dto.Ids)
+	if err != nil {
+		return nil, err
 	}
-
-	opts := options.Find().SetProjection(projection)
+	opts := options.Find().SetProjection(descriptionProjection(template.Fields()))
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find references: %w", err))
@@ -889,33 +1170,132 @@ func (r *mongoBaseRepository[T]) FindReferences(ctx context.Context, dto sdk.Rea
 		if err != nil {
 			idStr = fmt.Sprintf("%v", rawID)
 		}
-		desc, _ := doc[descriptionKey]
-		result[idStr] = fmt.Sprintf("%v", desc)
+		doc["id"] = idStr
+		result[idStr] = template.Render(doc, format)
 	}
 
 	return result, nil
 }
 
-// resolveEntityReferences calls FindReferences on the RepositoryRegistry for each entry
-// in entityIDs and returns the merged EntityRefererenceGroup.
-func resolveEntityReferences(ctx context.Context, di sdk.EndorDIContainerInterface, entityIDs map[string][]string) (sdk.EntityRefererenceGroup, error) {
-	references := make(sdk.EntityRefererenceGroup)
-	for entityName, ids := range entityIDs {
-		_, e, err := sdk.ParseEntityID(entityName)
+// descriptionProjection reads the fields of a description template ("id" is stored as _id),
+// leaving out the fields nested in another one, which Mongo rejects as path collisions.
+func descriptionProjection(fields []string) bson.M {
+	projection := bson.M{"_id": 1}
+	sorted := slices.Clone(fields)
+	slices.Sort(sorted)
+	included := []string{}
+	for _, field := range sorted {
+		if field == "id" || slices.ContainsFunc(included, func(parent string) bool {
+			return strings.HasPrefix(field, parent+".")
+		}) {
+			continue
+		}
+		included = append(included, field)
+		projection[field] = 1
+	}
+	return projection
+}
+
+// idsFilter selects the documents, not soft-deleted, with the given ids. String IDs are
+// converted to the storage format (primitive.ObjectID or string) via the ID strategy.
+func (r *mongoBaseRepository[T]) idsFilter(ids []string) (bson.M, error) {
+	storageIDs := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		storageID, err := r.idStrategy.ToStorageFormat(id)
 		if err != nil {
-			return references, err
+			return nil, sdk.NewBadRequestError(fmt.Errorf("invalid id %s: %w", id, err))
 		}
-		repo, found := di.GetRepositories()[e]
-		if !found {
-			continue
+		storageIDs = append(storageIDs, storageID)
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": storageIDs}}
+	if r.softDelete {
+		filter[sdk.DeletedAtField] = nil
+	}
+	return filter, nil
+}
+
+// FindDocuments retrieves the documents with the given ids, keyed by id, restricted to
+// dto.Fields if any.
+func (r *mongoBaseRepository[T]) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]bson.M, error) {
+	if len(dto.Ids) == 0 {
+		return map[string]bson.M{}, nil
+	}
+	filter, err := r.idsFilter(dto.Ids)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find()
+	if len(dto.Fields) > 0 {
+		projection := bson.M{"_id": 1}
+		for _, field := range dto.Fields {
+			projection[field] = 1
 		}
-		descriptions, err := repo.FindReferences(ctx, sdk.ReadInstancesDTO{Ids: ids})
+		opts.SetProjection(projection)
+	}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find documents: %w", err))
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode documents: %w", err))
+	}
+	result := make(map[string]bson.M, len(docs))
+	for _, doc := range docs {
+		id, err := r.idStrategy.FromStorageFormat(doc["_id"])
 		if err != nil {
-			return nil, err
+			id = fmt.Sprintf("%v", doc["_id"])
+		}
+		result[id] = doc
+	}
+	return result, nil
+}
+
+// toJSONDocument converts a model to its JSON representation, as returned by the API.
+func toJSONDocument(model any) (map[string]interface{}, error) {
+	jsonBytes, err := json.Marshal(model)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &document); err != nil {
+		return nil, sdk.NewInternalServerError(err)
+	}
+	return document, nil
+}
+
+// resolveEntityReferences resolves the references of entityIDs with the repositories of
+// di, or with the ReferenceResolver for the entities of other modules.
+func resolveEntityReferences(ctx context.Context, di sdk.EndorDIContainerInterface, entityIDs map[string][]string) (sdk.EntityRefererenceGroup, error) {
+	return sdk.ResolveEntityReferences(ctx, di, entityIDs)
+}
+
+// referenceIDSet collects the referenced ids of a stream without duplicates, so that its
+// size depends on the distinct references rather than on the streamed documents.
+type referenceIDSet map[string]map[string]struct{}
+
+func (s referenceIDSet) add(entityIDs map[string][]string) {
+	for entityName, ids := range entityIDs {
+		if s[entityName] == nil {
+			s[entityName] = map[string]struct{}{}
+		}
+		for _, id := range ids {
+			s[entityName][id] = struct{}{}
 		}
-		references[entityName] = descriptions
 	}
-	return references, nil
+}
+
+func (s referenceIDSet) ids() map[string][]string {
+	entityIDs := make(map[string][]string, len(s))
+	for entityName, ids := range s {
+		for id := range ids {
+			entityIDs[entityName] = append(entityIDs[entityName], id)
+		}
+	}
+	return entityIDs
 }
 
 // extractEntityReferenceIDsFromDoc inspects schema properties and, for each property with a
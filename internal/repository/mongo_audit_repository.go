@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	auditCollection = "audit"
+	auditListLimit  = 100
+)
+
+// MongoAuditRepository persists the audit trail in the "audit" collection of the module
+// database.
+type MongoAuditRepository struct{}
+
+func NewMongoAuditRepository() *MongoAuditRepository {
+	return &MongoAuditRepository{}
+}
+
+func (r *MongoAuditRepository) Add(ctx context.Context, entry sdk.AuditEntry) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	if _, err := collection.InsertOne(ctx, entry); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to record audit entry: %w", err))
+	}
+	return nil
+}
+
+func (r *MongoAuditRepository) Instance(ctx context.Context, id string) (*sdk.AuditEntry, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	var entry sdk.AuditEntry
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, sdk.NewNotFoundError(fmt.Errorf("audit entry %s not found", id)).WithTranslation("sdk.audit.messages.not_found", map[string]any{"id": id})
+		}
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find audit entry: %w", err))
+	}
+	return &entry, nil
+}
+
+// List returns the last entries, most recent first.
+func (r *MongoAuditRepository) List(ctx context.Context, dto sdk.ReadAuditEntriesDTO) ([]sdk.AuditEntry, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{}
+	if dto.UserId != "" {
+		filter["userId"] = dto.UserId
+	}
+	if dto.Entity != "" {
+		filter["entity"] = dto.Entity
+	}
+	if dto.From != nil || dto.To != nil {
+		occurredAt := bson.M{}
+		if dto.From != nil {
+			occurredAt["$gte"] = *dto.From
+		}
+		if dto.To != nil {
+			occurredAt["$lte"] = *dto.To
+		}
+		filter["occurredAt"] = occurredAt
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "occurredAt", Value: -1}}).SetLimit(auditListLimit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to list audit entries: %w", err))
+	}
+	entries := []sdk.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode audit entries: %w", err))
+	}
+	return entries, nil
+}
+
+func (r *MongoAuditRepository) getCollection() (*mongo.Collection, error) {
+	client, err := sdk.GetMongoClient()
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	// redacted payloads are decoded as maps, as in JSON
+	opts := options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
+	return client.Database(sdk_configuration.GetConfig().ModuleDBName).Collection(auditCollection, opts), nil
+}
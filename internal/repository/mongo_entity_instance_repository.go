@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
@@ -30,6 +31,7 @@ type MongoEntityInstanceRepository[T sdk.EntityInstanceInterface] struct {
 	entityId string
 	schema   sdk.RootSchema
 	di       sdk.EndorDIContainerInterface
+	events   entityEventPublisher
 }
 
 // NewMongoEntityInstanceRepository creates a new repository for the given collection.
@@ -46,8 +48,10 @@ func NewMongoEntityInstanceRepository[T sdk.EntityInstanceInterface](
 			base:   nil,
 			schema: schema,
 			di:     di,
+			events: entityEventPublisher{entityId: entityId, session: session, di: di},
 		}
 	}
+	history := newMongoHistory(entityId, &schema, session, options.History)
 	dbName := sdk_configuration.GetConfig().ModuleDBName
 	if session.Development == true && session.Username != "" {
 		dbName = session.Username + "-" + dbName
@@ -55,10 +59,11 @@ func NewMongoEntityInstanceRepository[T sdk.EntityInstanceInterface](
 	collection := client.Database(dbName).Collection(entityId)
 
 	return &MongoEntityInstanceRepository[T]{
-		base:     newMongoBaseRepository[T](collection, *options.AutoGenerateID),
+		base:     newMongoBaseRepository[T](collection, *options.AutoGenerateID, options.Versioned, options.SoftDelete),
 		entityId: entityId,
 		schema:   schema,
 		di:       di,
+		events:   entityEventPublisher{entityId: entityId, session: session, di: di, history: history},
 	}
 }
 
@@ -72,7 +77,7 @@ func (r *MongoEntityInstanceRepository[T]) GetSchema() *sdk.RootSchema {
 
 // Instance retrieves a single entity by ID.
 func (r *MongoEntityInstanceRepository[T]) Instance(ctx context.Context, dto sdk.ReadInstanceDTO) (*sdk.EntityInstance[T], error) {
-	rawDoc, err := r.base.FindByID(ctx, dto.Id)
+	rawDoc, err := r.base.FindByID(ctx, dto.Id, dto.IncludeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -105,17 +110,7 @@ func (r *MongoEntityInstanceRepository[T]) RawList(ctx context.Context, dto sdk.
 
 // List retrieves entities matching the filter.
 func (r *MongoEntityInstanceRepository[T]) List(ctx context.Context, dto sdk.ReadDTO) ([]sdk.EntityInstance[T], error) {
-	var filter bson.M
-	if dto.Filter != nil {
-		filter = cloneBsonM(dto.Filter)
-	}
-
-	var projection bson.M
-	if dto.Projection != nil {
-		projection = cloneBsonM(dto.Projection)
-	}
-
-	rawDocs, err := r.base.Find(ctx, filter, projection)
+	rawDocs, _, err := r.base.Find(ctx, dto)
 	if err != nil {
 		return nil, err
 	}
@@ -148,12 +143,21 @@ func (r *MongoEntityInstanceRepository[T]) Create(ctx context.Context, dto sdk.C
 		providedID = idToString(idPtr)
 	}
 
-	idStr, err := r.base.Insert(ctx, doc, providedID)
+	var created *sdk.EntityInstance[T]
+	err = r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		idStr, err := r.base.Insert(ctx, doc, providedID)
+		if err != nil {
+			return entityChange{}, err
+		}
+		if created, err = r.Instance(ctx, sdk.ReadInstanceDTO{Id: idStr}); err != nil {
+			return entityChange{}, err
+		}
+		return entityChange{eventType: sdk.EntityEventCreated, instanceId: idStr, category: categoryOf(created.This), after: created}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return r.Instance(ctx, sdk.ReadInstanceDTO{Id: idStr})
+	return created, nil
 }
 
 // Update modifies an existing entity by ID.
@@ -171,29 +175,226 @@ func (r *MongoEntityInstanceRepository[T]) Update(ctx context.Context, dto sdk.U
 		setDoc[k] = v
 	}
 
-	if err := r.base.Update(ctx, dto.Id, setDoc); err != nil {
+	var updated *sdk.EntityInstance[T]
+	err := r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: dto.Id}
+		if observed {
+			before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+			if err != nil {
+				return change, err
+			}
+			change.before = before
+		}
+		if err := r.base.Update(ctx, dto.Id, setDoc, dto.Version); err != nil {
+			return change, err
+		}
+		var err error
+		if updated, err = r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id}); err != nil {
+			return change, err
+		}
+		change.category = categoryOf(updated.This)
+		change.after = updated
+		return change, nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	return r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+	return updated, nil
 }
 
-// Delete removes an entity by ID.
+// Delete removes an entity by ID, after applying the delete policies of the references to
+// it: the instances deleted by cascade are deleted with Delete, so they publish their
+// events and record their history too.
 func (r *MongoEntityInstanceRepository[T]) Delete(ctx context.Context, dto sdk.ReadInstanceDTO) error {
-	return r.base.Delete(ctx, dto.Id)
+	return deleteWithReferences(ctx, r.di, r.entityId, dto.Id, func(ctx context.Context) error {
+		return r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+			change := entityChange{eventType: sdk.EntityEventDeleted, instanceId: dto.Id}
+			if observed {
+				before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+				if err != nil {
+					return change, err
+				}
+				change.category = categoryOf(before.This)
+				change.before = before
+			}
+			return change, r.base.Delete(ctx, dto.Id, dto.Version)
+		})
+	})
+}
+
+// FindReferencing returns the ids of the instances whose reference field holds id.
+func (r *MongoEntityInstanceRepository[T]) FindReferencing(ctx context.Context, reference sdk.EntityReference, id string) ([]string, error) {
+	return r.base.FindReferencing(ctx, reference.Field, id)
+}
+
+// ClearReference sets to null the reference field of the instances holding id. Each of
+// them is a tracked write, like Update: it publishes an updated event and, with history,
+// records a new version.
+func (r *MongoEntityInstanceRepository[T]) ClearReference(ctx context.Context, reference sdk.EntityReference, id string) error {
+	ids, err := r.base.FindReferencing(ctx, reference.Field, id)
+	if err != nil {
+		return err
+	}
+	for _, instanceId := range ids {
+		err := r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+			change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: instanceId}
+			if observed {
+				before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: instanceId})
+				if err != nil {
+					return change, err
+				}
+				change.before = before
+			}
+			if err := r.base.ClearReference(ctx, instanceId, reference, id); err != nil {
+				return change, err
+			}
+			updated, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: instanceId})
+			if err != nil {
+				return change, err
+			}
+			change.category = categoryOf(updated.This)
+			change.after = updated
+			return change, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore brings back a soft-deleted entity by ID.
+func (r *MongoEntityInstanceRepository[T]) Restore(ctx context.Context, dto sdk.ReadInstanceDTO) (*sdk.EntityInstance[T], error) {
+	var restored *sdk.EntityInstance[T]
+	err := r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: dto.Id}
+		if observed {
+			before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id, IncludeDeleted: true})
+			if err != nil {
+				return change, err
+			}
+			change.before = before
+		}
+		if err := r.base.Restore(ctx, dto.Id); err != nil {
+			return change, err
+		}
+		instance, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+		if err != nil {
+			return change, err
+		}
+		restored = instance
+		change.category = categoryOf(instance.This)
+		change.after = instance
+		return change, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// History returns the versions of an entity, oldest first.
+func (r *MongoEntityInstanceRepository[T]) History(ctx context.Context, dto sdk.ReadInstanceDTO) ([]sdk.HistoryEntry, error) {
+	if r.events.history == nil {
+		return nil, historyNotEnabled(r.entityId)
+	}
+	return r.events.history.list(ctx, dto.Id)
+}
+
+// HistoryVersion returns a version of an entity with its snapshot.
+func (r *MongoEntityInstanceRepository[T]) HistoryVersion(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.HistoryEntry, error) {
+	if r.events.history == nil {
+		return nil, historyNotEnabled(r.entityId)
+	}
+	return r.events.history.version(ctx, dto)
+}
+
+// Revert writes a version of an entity back, recreating the entity if deleted.
+func (r *MongoEntityInstanceRepository[T]) Revert(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.EntityInstance[T], error) {
+	if r.events.history == nil {
+		return nil, historyNotEnabled(r.entityId)
+	}
+	var current any
+	instance, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id, IncludeDeleted: true})
+	var endorErr *sdk.EndorError
+	if err != nil && (!errors.As(err, &endorErr) || endorErr.StatusCode != 404) {
+		return nil, err
+	}
+	if err == nil {
+		current = instance
+	}
+	var snapshot sdk.EntityInstance[T]
+	if err := r.events.history.snapshot(ctx, dto, current, &snapshot); err != nil {
+		return nil, err
+	}
+	doc, err := r.base.GetDocumentMapper().ToDocument(snapshot.This, snapshot.Metadata, r.base.GetIDStrategy())
+	if err != nil {
+		return nil, sdk.NewInternalServerError(err)
+	}
+
+	var reverted *sdk.EntityInstance[T]
+	err = r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: dto.Id}
+		if observed {
+			before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id, IncludeDeleted: true})
+			var endorErr *sdk.EndorError
+			if err != nil && (!errors.As(err, &endorErr) || endorErr.StatusCode != 404) {
+				return change, err
+			}
+			if err == nil {
+				change.before = before
+			}
+		}
+		created, err := r.base.Replace(ctx, dto.Id, doc)
+		if err != nil {
+			return change, err
+		}
+		if created {
+			change.eventType = sdk.EntityEventCreated
+		}
+		if reverted, err = r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id}); err != nil {
+			return change, err
+		}
+		change.category = categoryOf(reverted.This)
+		change.after = reverted
+		return change, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
 }
 
 // FindReferences retrieves id->description pairs for the given entity IDs.
 func (r *MongoEntityInstanceRepository[T]) FindReferences(ctx context.Context, dto sdk.ReadInstancesDTO) (sdk.EntityReferenceGroupDescriptions, error) {
-	descriptionAttributeKey := r.schema.UISchema.EntityDescriptionKey
-	if descriptionAttributeKey == nil {
+	template := r.schema.DescriptionTemplate()
+	if template == nil {
 		return make(sdk.EntityReferenceGroupDescriptions), nil
 	}
-	return r.base.FindReferences(ctx, dto, *descriptionAttributeKey)
+	return r.base.FindReferences(ctx, dto, template, sdk.DescriptionFormatOf(ctx, r.di))
+}
+
+// FindDocuments retrieves the entities with the given ids as JSON documents keyed by id.
+func (r *MongoEntityInstanceRepository[T]) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	rawDocs, err := r.base.FindDocuments(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+	documents := make(map[string]map[string]interface{}, len(rawDocs))
+	for id, rawDoc := range rawDocs {
+		instance, err := r.toEntityInstance(rawDoc)
+		if err != nil {
+			return nil, err
+		}
+		if documents[id], err = toJSONDocument(instance); err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
 }
 
 func (r *MongoEntityInstanceRepository[T]) InstanceWithReferences(ctx context.Context, dto sdk.ReadInstanceDTO) (*sdk.EntityInstance[T], sdk.EntityRefererenceGroup, error) {
-	rawDoc, err := r.base.FindByID(ctx, dto.Id)
+	rawDoc, err := r.base.FindByID(ctx, dto.Id, dto.IncludeDeleted)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -213,19 +414,15 @@ func (r *MongoEntityInstanceRepository[T]) InstanceWithReferences(ctx context.Co
 }
 
 func (r *MongoEntityInstanceRepository[T]) ListWithReferences(ctx context.Context, dto sdk.ReadDTO) ([]sdk.EntityInstance[T], sdk.EntityRefererenceGroup, error) {
-	var filter bson.M
-	if dto.Filter != nil {
-		filter = cloneBsonM(dto.Filter)
-	}
-
-	var projection bson.M
-	if dto.Projection != nil {
-		projection = cloneBsonM(dto.Projection)
-	}
+	instances, references, _, err := r.ListPage(ctx, dto)
+	return instances, references, err
+}
 
-	rawDocs, err := r.base.Find(ctx, filter, projection)
+// ListPage retrieves the entities selected by dto with their references and pagination.
+func (r *MongoEntityInstanceRepository[T]) ListPage(ctx context.Context, dto sdk.ReadDTO) ([]sdk.EntityInstance[T], sdk.EntityRefererenceGroup, *sdk.Pagination, error) {
+	rawDocs, pagination, err := r.base.Find(ctx, dto)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	allEntityIDs := make(map[string][]string)
@@ -237,19 +434,38 @@ func (r *MongoEntityInstanceRepository[T]) ListWithReferences(ctx context.Contex
 
 	references, err := resolveEntityReferences(ctx, r.di, allEntityIDs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	instances := make([]sdk.EntityInstance[T], 0, len(rawDocs))
 	for _, rawDoc := range rawDocs {
 		instance, err := r.toEntityInstance(rawDoc)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		instances = append(instances, *instance)
 	}
 
-	return instances, references, nil
+	return instances, references, pagination, nil
+}
+
+// StreamWithReferences calls yield for every entity matching the filter, reading the cursor
+// one document at a time, and returns the references of all of them.
+func (r *MongoEntityInstanceRepository[T]) StreamWithReferences(ctx context.Context, dto sdk.ReadDTO, yield func(instance sdk.EntityInstance[T]) error) (sdk.EntityRefererenceGroup, error) {
+	entityIDs := referenceIDSet{}
+	err := r.base.FindEach(ctx, dto, func(rawDoc bson.M) error {
+		entityIDs.add(extractEntityReferenceIDsFromDoc(&r.schema, rawDoc))
+		instance, err := r.toEntityInstance(rawDoc)
+		if err != nil {
+			return err
+		}
+		return yield(*instance)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveEntityReferences(ctx, r.di, entityIDs.ids())
 }
 
 // toEntityInstance converts a raw MongoDB document to EntityInstance[T].
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoHistory stores the history of the instances of an entity in the <entity>__history
+// collection of the database of the repository.
+type mongoHistory struct {
+	entityId string
+	// schema describes the instances: its writeOnly and password fields are redacted
+	schema  *sdk.RootSchema
+	session sdk.Session
+}
+
+// newMongoHistory returns the history of entityId, nil if disabled.
+func newMongoHistory(entityId string, schema *sdk.RootSchema, session sdk.Session, enabled bool) *mongoHistory {
+	if !enabled {
+		return nil
+	}
+	return &mongoHistory{entityId: entityId, schema: schema, session: session}
+}
+
+// errHistoryVersionTaken is returned by record when a concurrent write numbered its entry
+// with the same version: the write has to be run again.
+var errHistoryVersionTaken = errors.New("history version taken by a concurrent write")
+
+// historyIndexes are the history collections whose unique (instanceId, version) index has
+// been created, by database and collection name.
+var historyIndexes sync.Map
+
+// record adds the entry of event, numbered after the last entry of the instance. It runs in
+// the transaction of the write: the unique index on (instanceId, version) makes concurrent
+// writes of the same instance fail instead of sharing a version.
+func (h *mongoHistory) record(ctx context.Context, event sdk.EntityEvent) error {
+	collection, err := h.getCollection()
+	if err != nil {
+		return err
+	}
+	if err := ensureHistoryIndex(collection); err != nil {
+		return err
+	}
+	var last sdk.HistoryEntry
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"version": 1})
+	if err := collection.FindOne(ctx, bson.M{"instanceId": event.InstanceId}, opts).Decode(&last); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to read history of %s: %w", event.InstanceId, err))
+	}
+	if _, err := collection.InsertOne(ctx, sdk.NewHistoryEntry(event, last.Version+1).Redact(h.schema)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return sdk.NewConflictError(fmt.Errorf("failed to record history of %s: %w", event.InstanceId, errHistoryVersionTaken)).WithTranslation("sdk.history.version_conflict", map[string]any{"id": event.InstanceId})
+		}
+		return sdk.NewInternalServerError(fmt.Errorf("failed to record history of %s: %w", event.InstanceId, err))
+	}
+	return nil
+}
+
+// ensureHistoryIndex creates the unique (instanceId, version) index of collection once per
+// process. Indexes cannot be created in transactions, so it does not use the context of the
+// write.
+func ensureHistoryIndex(collection *mongo.Collection) error {
+	key := collection.Database().Name() + "." + collection.Name()
+	if _, done := historyIndexes.Load(key); done {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "instanceId", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to create the history index of %s: %w", collection.Name(), err))
+	}
+	historyIndexes.Store(key, struct{}{})
+	return nil
+}
+
+// list returns the entries of the instance without snapshots, oldest first.
+func (h *mongoHistory) list(ctx context.Context, instanceId string) ([]sdk.HistoryEntry, error) {
+	collection, err := h.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}}).SetProjection(bson.M{"snapshot": 0})
+	cursor, err := collection.Find(ctx, bson.M{"instanceId": instanceId}, opts)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to list history of %s: %w", instanceId, err))
+	}
+	entries := []sdk.HistoryEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode history of %s: %w", instanceId, err))
+	}
+	return entries, nil
+}
+
+// version returns an entry of the instance with its snapshot.
+func (h *mongoHistory) version(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.HistoryEntry, error) {
+	collection, err := h.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	var entry sdk.HistoryEntry
+	if err := collection.FindOne(ctx, bson.M{"instanceId": dto.Id, "version": dto.Version}).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, sdk.NewNotFoundError(fmt.Errorf("version %d of %s not found", dto.Version, dto.Id)).WithTranslation("sdk.history.version_not_found", map[string]any{"id": dto.Id, "version": dto.Version})
+		}
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find version %d of %s: %w", dto.Version, dto.Id, err))
+	}
+	return &entry, nil
+}
+
+// snapshot decodes the snapshot of a version in target, as a JSON payload of the actions.
+// The redacted values of the snapshot are taken from current, the instance as it is now
+// (nil if missing).
+func (h *mongoHistory) snapshot(ctx context.Context, dto sdk.ReadHistoryVersionDTO, current any, target any) error {
+	entry, err := h.version(ctx, dto)
+	if err != nil {
+		return err
+	}
+	if entry.Snapshot == nil {
+		return sdk.NewBadRequestError(fmt.Errorf("version %d of %s has no snapshot", dto.Version, dto.Id)).WithTranslation("sdk.history.no_snapshot", map[string]any{"id": dto.Id, "version": dto.Version})
+	}
+	currentDoc, err := toEventDocument(current)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(sdk.RestoreRedacted(entry.Snapshot, currentDoc))
+	if err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to encode snapshot: %w", err))
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to decode snapshot: %w", err))
+	}
+	return nil
+}
+
+// historyNotEnabled is returned by the history methods of repositories without history.
+func historyNotEnabled(entityId string) error {
+	return sdk.NewBadRequestError(fmt.Errorf("history is not enabled for entity %s", entityId)).WithTranslation("sdk.history.not_enabled", map[string]any{"entity": entityId})
+}
+
+func (h *mongoHistory) getCollection() (*mongo.Collection, error) {
+	client, err := sdk.GetMongoClient()
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	dbName := sdk_configuration.GetConfig().ModuleDBName
+	if h.session.Development && h.session.Username != "" {
+		dbName = h.session.Username + "-" + dbName
+	}
+	// nested objects of the snapshots are decoded as maps, as in JSON
+	opts := options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
+	return client.Database(dbName).Collection(sdk.HistoryCollection(h.entityId), opts), nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	jobCollection = "job"
+	jobListLimit  = 100
+)
+
+// MongoJobRepository persists the jobs of the async actions in the "job" collection
+// of the module database.
+type MongoJobRepository struct{}
+
+func NewMongoJobRepository() *MongoJobRepository {
+	return &MongoJobRepository{}
+}
+
+func (r *MongoJobRepository) Create(ctx context.Context, job sdk.Job) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	if _, err := collection.InsertOne(ctx, job); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to create job: %w", err))
+	}
+	return nil
+}
+
+func (r *MongoJobRepository) Update(ctx context.Context, job sdk.Job) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": job.Id}, job)
+	if err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to update job: %w", err))
+	}
+	if result.MatchedCount == 0 {
+		return sdk.NewNotFoundError(fmt.Errorf("job %s not found", job.Id)).WithTranslation("sdk.job.messages.not_found", map[string]any{"id": job.Id})
+	}
+	return nil
+}
+
+func (r *MongoJobRepository) Instance(ctx context.Context, id string) (*sdk.Job, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	var job sdk.Job
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, sdk.NewNotFoundError(fmt.Errorf("job %s not found", id)).WithTranslation("sdk.job.messages.not_found", map[string]any{"id": id})
+		}
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find job: %w", err))
+	}
+	return &job, nil
+}
+
+// List returns the last jobs of userId, most recent first.
+func (r *MongoJobRepository) List(ctx context.Context, userId string) ([]sdk.Job, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(jobListLimit)
+	cursor, err := collection.Find(ctx, bson.M{"userId": userId}, opts)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to list jobs: %w", err))
+	}
+	jobs := []sdk.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode jobs: %w", err))
+	}
+	return jobs, nil
+}
+
+func (r *MongoJobRepository) getCollection() (*mongo.Collection, error) {
+	client, err := sdk.GetMongoClient()
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	return client.Database(sdk_configuration.GetConfig().ModuleDBName).Collection(jobCollection), nil
+}
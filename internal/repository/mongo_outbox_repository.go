@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const outboxCollection = "outbox"
+
+// MongoOutboxRepository persists the outbox entries in the "outbox" collection of the
+// module database.
+type MongoOutboxRepository struct{}
+
+func NewMongoOutboxRepository() *MongoOutboxRepository {
+	return &MongoOutboxRepository{}
+}
+
+func (r *MongoOutboxRepository) Add(ctx context.Context, entry sdk.OutboxEntry) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	if _, err := collection.InsertOne(ctx, entry); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to add outbox entry: %w", err))
+	}
+	return nil
+}
+
+// Claim leases the entries one at a time, so that concurrent relays never get the same one.
+func (r *MongoOutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]sdk.OutboxEntry, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{
+		"status":        sdk.OutboxEntryStatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetReturnDocument(options.After)
+	entries := []sdk.OutboxEntry{}
+	for len(entries) < limit {
+		var entry sdk.OutboxEntry
+		if err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			}
+			return entries, sdk.NewInternalServerError(fmt.Errorf("failed to claim outbox entries: %w", err))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *MongoOutboxRepository) Update(ctx context.Context, entry sdk.OutboxEntry) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": entry.Id}, entry); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to update outbox entry: %w", err))
+	}
+	return nil
+}
+
+func (r *MongoOutboxRepository) getCollection() (*mongo.Collection, error) {
+	client, err := sdk.GetMongoClient()
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	return client.Database(sdk_configuration.GetConfig().ModuleDBName).Collection(outboxCollection), nil
+}
+
+// inTransaction runs fn in a Mongo transaction, or in the one ctx already belongs to.
+// The write operations of fn must use the ctx it receives.
+func inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return sdk.WithTransaction(ctx, fn)
+}
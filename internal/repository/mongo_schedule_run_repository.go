@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	scheduleRunCollection = "schedule-run"
+	scheduleRunListLimit  = 100
+)
+
+// MongoScheduleRunRepository persists the runs of the scheduled actions in the
+// "schedule-run" collection of the module database. The run id is unique per tick, so
+// the insert of Acquire succeeds on a single replica.
+type MongoScheduleRunRepository struct{}
+
+func NewMongoScheduleRunRepository() *MongoScheduleRunRepository {
+	return &MongoScheduleRunRepository{}
+}
+
+func (r *MongoScheduleRunRepository) Acquire(ctx context.Context, run sdk.ScheduleRun) (bool, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return false, err
+	}
+	if _, err := collection.InsertOne(ctx, run); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, sdk.NewInternalServerError(fmt.Errorf("failed to acquire schedule run: %w", err))
+	}
+	return true, nil
+}
+
+func (r *MongoScheduleRunRepository) Update(ctx context.Context, run sdk.ScheduleRun) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": run.Id}, run); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to update schedule run: %w", err))
+	}
+	return nil
+}
+
+// List returns the last runs, most recent tick first.
+func (r *MongoScheduleRunRepository) List(ctx context.Context, dto sdk.ReadScheduleRunsDTO) ([]sdk.ScheduleRun, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{}
+	if dto.ScheduleId != "" {
+		filter["scheduleId"] = dto.ScheduleId
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "tick", Value: -1}}).SetLimit(scheduleRunListLimit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to list schedule runs: %w", err))
+	}
+	runs := []sdk.ScheduleRun{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode schedule runs: %w", err))
+	}
+	return runs, nil
+}
+
+func (r *MongoScheduleRunRepository) getCollection() (*mongo.Collection, error) {
+	client, err := sdk.GetMongoClient()
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	return client.Database(sdk_configuration.GetConfig().ModuleDBName).Collection(scheduleRunCollection), nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
@@ -29,6 +30,7 @@ type MongoStaticEntityInstanceRepository[T sdk.EntityInstanceInterface] struct {
 	entityId string
 	session  sdk.Session
 	di       sdk.EndorDIContainerInterface
+	events   entityEventPublisher
 	_base    *mongoBaseRepository[T]
 }
 
@@ -39,11 +41,13 @@ func NewMongoStaticEntityInstanceRepository[T sdk.EntityInstanceInterface](
 	session sdk.Session,
 	di sdk.EndorDIContainerInterface,
 ) *MongoStaticEntityInstanceRepository[T] {
+	var zero T
 	return &MongoStaticEntityInstanceRepository[T]{
 		options:  options,
 		entityId: entityId,
 		session:  session,
 		di:       di,
+		events:   entityEventPublisher{entityId: entityId, session: session, di: di, history: newMongoHistory(entityId, sdk.NewSchema(zero), session, options.History)},
 	}
 }
 
@@ -60,7 +64,7 @@ func (r *MongoStaticEntityInstanceRepository[T]) GetSchema() *sdk.RootSchema {
 func (r *MongoStaticEntityInstanceRepository[T]) Instance(ctx context.Context, dto sdk.ReadInstanceDTO) (T, error) {
 	var zero T
 
-	rawDoc, err := r.getBaseRepository().FindByID(ctx, dto.Id)
+	rawDoc, err := r.getBaseRepository().FindByID(ctx, dto.Id, dto.IncludeDeleted)
 	if err != nil {
 		return zero, err
 	}
@@ -80,17 +84,7 @@ func (r *MongoStaticEntityInstanceRepository[T]) Instance(ctx context.Context, d
 
 // List retrieves entities matching the filter.
 func (r *MongoStaticEntityInstanceRepository[T]) List(ctx context.Context, dto sdk.ReadDTO) ([]T, error) {
-	var filter bson.M
-	if dto.Filter != nil {
-		filter = cloneBsonM(dto.Filter)
-	}
-
-	var projection bson.M
-	if dto.Projection != nil {
-		projection = cloneBsonM(dto.Projection)
-	}
-
-	rawDocs, err := r.getBaseRepository().Find(ctx, filter, projection)
+	rawDocs, _, err := r.getBaseRepository().Find(ctx, dto)
 	if err != nil {
 		return nil, err
 	}
@@ -127,43 +121,255 @@ func (r *MongoStaticEntityInstanceRepository[T]) Create(ctx context.Context, dto
 	// Get provided ID
 	providedID := dto.Data.GetID()
 
-	idStr, err := r.getBaseRepository().Insert(ctx, doc, providedID)
+	var created T
+	err = r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		idStr, err := r.getBaseRepository().Insert(ctx, doc, providedID)
+		if err != nil {
+			return entityChange{}, err
+		}
+		if created, err = r.Instance(ctx, sdk.ReadInstanceDTO{Id: idStr}); err != nil {
+			return entityChange{}, err
+		}
+		return entityChange{eventType: sdk.EntityEventCreated, instanceId: idStr, category: categoryOf(created), after: created}, nil
+	})
 	if err != nil {
 		return zero, err
 	}
-
-	return r.Instance(ctx, sdk.ReadInstanceDTO{Id: idStr})
+	return created, nil
 }
 
 // Update modifies an existing entity by ID.
 func (r *MongoStaticEntityInstanceRepository[T]) Update(ctx context.Context, dto sdk.UpdateByIdDTO[map[string]interface{}]) (T, error) {
-	var zero T
+	var updated T
+	err := r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: dto.Id}
+		if observed {
+			before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+			if err != nil {
+				return change, err
+			}
+			change.before = before
+		}
+		if err := r.getBaseRepository().Update(ctx, dto.Id, dto.Data, dto.Version); err != nil {
+			return change, err
+		}
+		var err error
+		if updated, err = r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id}); err != nil {
+			return change, err
+		}
+		change.category = categoryOf(updated)
+		change.after = updated
+		return change, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return updated, nil
+}
 
-	if err := r.getBaseRepository().Update(ctx, dto.Id, dto.Data); err != nil {
+// Delete removes an entity by ID, after applying the delete policies of the references to
+// it: the instances deleted by cascade are deleted with Delete, so they publish their
+// events and record their history too.
+func (r *MongoStaticEntityInstanceRepository[T]) Delete(ctx context.Context, dto sdk.ReadInstanceDTO) error {
+	return deleteWithReferences(ctx, r.di, r.entityId, dto.Id, func(ctx context.Context) error {
+		return r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+			change := entityChange{eventType: sdk.EntityEventDeleted, instanceId: dto.Id}
+			if observed {
+				before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+				if err != nil {
+					return change, err
+				}
+				change.category = categoryOf(before)
+				change.before = before
+			}
+			return change, r.getBaseRepository().Delete(ctx, dto.Id, dto.Version)
+		})
+	})
+}
+
+// FindReferencing returns the ids of the instances whose reference field holds id.
+func (r *MongoStaticEntityInstanceRepository[T]) FindReferencing(ctx context.Context, reference sdk.EntityReference, id string) ([]string, error) {
+	return r.getBaseRepository().FindReferencing(ctx, reference.Field, id)
+}
+
+// ClearReference sets to null the reference field of the instances holding id. Each of
+// them is a tracked write, like Update: it publishes an updated event and, with history,
+// records a new version.
+func (r *MongoStaticEntityInstanceRepository[T]) ClearReference(ctx context.Context, reference sdk.EntityReference, id string) error {
+	ids, err := r.getBaseRepository().FindReferencing(ctx, reference.Field, id)
+	if err != nil {
+		return err
+	}
+	for _, instanceId := range ids {
+		err := r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+			change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: instanceId}
+			if observed {
+				before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: instanceId})
+				if err != nil {
+					return change, err
+				}
+				change.before = before
+			}
+			if err := r.getBaseRepository().ClearReference(ctx, instanceId, reference, id); err != nil {
+				return change, err
+			}
+			updated, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: instanceId})
+			if err != nil {
+				return change, err
+			}
+			change.category = categoryOf(updated)
+			change.after = updated
+			return change, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore brings back a soft-deleted entity by ID.
+func (r *MongoStaticEntityInstanceRepository[T]) Restore(ctx context.Context, dto sdk.ReadInstanceDTO) (T, error) {
+	var restored T
+	err := r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: dto.Id}
+		if observed {
+			before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id, IncludeDeleted: true})
+			if err != nil {
+				return change, err
+			}
+			change.before = before
+		}
+		if err := r.getBaseRepository().Restore(ctx, dto.Id); err != nil {
+			return change, err
+		}
+		instance, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+		if err != nil {
+			return change, err
+		}
+		restored = instance
+		change.category = categoryOf(instance)
+		change.after = instance
+		return change, nil
+	})
+	if err != nil {
+		var zero T
 		return zero, err
 	}
+	return restored, nil
+}
 
-	return r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id})
+// History returns the versions of an entity, oldest first.
+func (r *MongoStaticEntityInstanceRepository[T]) History(ctx context.Context, dto sdk.ReadInstanceDTO) ([]sdk.HistoryEntry, error) {
+	if r.events.history == nil {
+		return nil, historyNotEnabled(r.entityId)
+	}
+	return r.events.history.list(ctx, dto.Id)
 }
 
-// Delete removes an entity by ID.
-func (r *MongoStaticEntityInstanceRepository[T]) Delete(ctx context.Context, dto sdk.ReadInstanceDTO) error {
-	return r.getBaseRepository().Delete(ctx, dto.Id)
+// HistoryVersion returns a version of an entity with its snapshot.
+func (r *MongoStaticEntityInstanceRepository[T]) HistoryVersion(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.HistoryEntry, error) {
+	if r.events.history == nil {
+		return nil, historyNotEnabled(r.entityId)
+	}
+	return r.events.history.version(ctx, dto)
+}
+
+// Revert writes a version of an entity back, recreating the entity if deleted.
+func (r *MongoStaticEntityInstanceRepository[T]) Revert(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (T, error) {
+	var zero T
+	if r.events.history == nil {
+		return zero, historyNotEnabled(r.entityId)
+	}
+	var current any
+	instance, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id, IncludeDeleted: true})
+	var endorErr *sdk.EndorError
+	if err != nil && (!errors.As(err, &endorErr) || endorErr.StatusCode != 404) {
+		return zero, err
+	}
+	if err == nil {
+		current = instance
+	}
+	var snapshot T
+	if err := r.events.history.snapshot(ctx, dto, current, &snapshot); err != nil {
+		return zero, err
+	}
+	doc, err := r.getBaseRepository().GetDocumentMapper().ToDocumentWithoutMetadata(snapshot, r.getBaseRepository().GetIDStrategy())
+	if err != nil {
+		return zero, sdk.NewInternalServerError(err)
+	}
+
+	var reverted T
+	err = r.events.track(ctx, func(ctx context.Context, observed bool) (entityChange, error) {
+		change := entityChange{eventType: sdk.EntityEventUpdated, instanceId: dto.Id}
+		if observed {
+			before, err := r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id, IncludeDeleted: true})
+			var endorErr *sdk.EndorError
+			if err != nil && (!errors.As(err, &endorErr) || endorErr.StatusCode != 404) {
+				return change, err
+			}
+			if err == nil {
+				change.before = before
+			}
+		}
+		created, err := r.getBaseRepository().Replace(ctx, dto.Id, doc)
+		if err != nil {
+			return change, err
+		}
+		if created {
+			change.eventType = sdk.EntityEventCreated
+		}
+		if reverted, err = r.Instance(ctx, sdk.ReadInstanceDTO{Id: dto.Id}); err != nil {
+			return change, err
+		}
+		change.category = categoryOf(reverted)
+		change.after = reverted
+		return change, nil
+	})
+	if err != nil {
+		return zero, err
+	}
+	return reverted, nil
 }
 
 func (r *MongoStaticEntityInstanceRepository[T]) FindReferences(ctx context.Context, dto sdk.ReadInstancesDTO) (sdk.EntityReferenceGroupDescriptions, error) {
 	var zero T
-	descriptionAttributeKey := sdk.NewSchema(zero).UISchema.EntityDescriptionKey
-	if descriptionAttributeKey == nil {
+	template := sdk.NewSchema(zero).DescriptionTemplate()
+	if template == nil {
 		return make(sdk.EntityReferenceGroupDescriptions), nil
 	}
-	return r.getBaseRepository().FindReferences(ctx, dto, *descriptionAttributeKey)
+	return r.getBaseRepository().FindReferences(ctx, dto, template, sdk.DescriptionFormatOf(ctx, r.di))
+}
+
+// FindDocuments retrieves the entities with the given ids as JSON documents keyed by id.
+func (r *MongoStaticEntityInstanceRepository[T]) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	rawDocs, err := r.getBaseRepository().FindDocuments(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+	documents := make(map[string]map[string]interface{}, len(rawDocs))
+	for id, rawDoc := range rawDocs {
+		instance, err := r.toModel(rawDoc)
+		if err != nil {
+			return nil, err
+		}
+		if r.options.Hooks.AfterFind != nil {
+			if err := r.options.Hooks.AfterFind(instance); err != nil {
+				return nil, err
+			}
+		}
+		if documents[id], err = toJSONDocument(instance); err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
 }
 
 func (r *MongoStaticEntityInstanceRepository[T]) InstanceWithReferences(ctx context.Context, dto sdk.ReadInstanceDTO) (T, sdk.EntityRefererenceGroup, error) {
 	var zero T
 
-	rawDoc, err := r.getBaseRepository().FindByID(ctx, dto.Id)
+	rawDoc, err := r.getBaseRepository().FindByID(ctx, dto.Id, dto.IncludeDeleted)
 	if err != nil {
 		return zero, nil, err
 	}
@@ -189,19 +395,15 @@ func (r *MongoStaticEntityInstanceRepository[T]) InstanceWithReferences(ctx cont
 }
 
 func (r *MongoStaticEntityInstanceRepository[T]) ListWithReferences(ctx context.Context, dto sdk.ReadDTO) ([]T, sdk.EntityRefererenceGroup, error) {
-	var filter bson.M
-	if dto.Filter != nil {
-		filter = cloneBsonM(dto.Filter)
-	}
-
-	var projection bson.M
-	if dto.Projection != nil {
-		projection = cloneBsonM(dto.Projection)
-	}
+	instances, references, _, err := r.ListPage(ctx, dto)
+	return instances, references, err
+}
 
-	rawDocs, err := r.getBaseRepository().Find(ctx, filter, projection)
+// ListPage retrieves the entities selected by dto with their references and pagination.
+func (r *MongoStaticEntityInstanceRepository[T]) ListPage(ctx context.Context, dto sdk.ReadDTO) ([]T, sdk.EntityRefererenceGroup, *sdk.Pagination, error) {
+	rawDocs, pagination, err := r.getBaseRepository().Find(ctx, dto)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	var zero T
@@ -215,26 +417,53 @@ func (r *MongoStaticEntityInstanceRepository[T]) ListWithReferences(ctx context.
 
 	references, err := resolveEntityReferences(ctx, r.di, allEntityIDs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	instances := make([]T, 0, len(rawDocs))
 	for _, rawDoc := range rawDocs {
 		instance, err := r.toModel(rawDoc)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		// call hook
 		if r.options.Hooks.AfterFind != nil {
 			err := r.options.Hooks.AfterFind(instance)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		}
 		instances = append(instances, instance)
 	}
 
-	return instances, references, nil
+	return instances, references, pagination, nil
+}
+
+// StreamWithReferences calls yield for every entity matching the filter, reading the cursor
+// one document at a time, and returns the references of all of them.
+func (r *MongoStaticEntityInstanceRepository[T]) StreamWithReferences(ctx context.Context, dto sdk.ReadDTO, yield func(instance T) error) (sdk.EntityRefererenceGroup, error) {
+	var zero T
+	schema := sdk.NewSchema(zero)
+	entityIDs := referenceIDSet{}
+	err := r.getBaseRepository().FindEach(ctx, dto, func(rawDoc bson.M) error {
+		entityIDs.add(extractEntityReferenceIDsFromDoc(schema, rawDoc))
+		instance, err := r.toModel(rawDoc)
+		if err != nil {
+			return err
+		}
+		// call hook
+		if r.options.Hooks.AfterFind != nil {
+			if err := r.options.Hooks.AfterFind(instance); err != nil {
+				return err
+			}
+		}
+		return yield(instance)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveEntityReferences(ctx, r.di, entityIDs.ids())
 }
 
 func (r *MongoStaticEntityInstanceRepository[T]) RawList(ctx context.Context, dto sdk.ReadDTO) ([]map[string]interface{}, error) {
@@ -287,5 +516,5 @@ func (r *MongoStaticEntityInstanceRepository[T]) getBaseRepository() *mongoBaseR
 		dbName = r.session.Username + "-" + dbName
 	}
 	collection := client.Database(dbName).Collection(r.entityId)
-	return newMongoBaseRepository[T](collection, *r.options.AutoGenerateID)
+	return newMongoBaseRepository[T](collection, *r.options.AutoGenerateID, r.options.Versioned, r.options.SoftDelete)
 }
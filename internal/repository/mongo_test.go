@@ -985,3 +985,20 @@ func TestExtractEntityReferenceIDsFromDoc_NestedArrayReferences(t *testing.T) {
 	assert.Equal(t, []string{"cust-xyz"}, result["customer"])
 	assert.ElementsMatch(t, []string{"prod-1", "prod-2"}, result["product"])
 }
+
+func TestVersionCondition(t *testing.T) {
+	assert.Equal(t, int64(3), versionCondition(3))
+	// documents written before versioning was enabled have no version field
+	assert.Equal(t, bson.M{"$in": bson.A{0, nil}}, versionCondition(0))
+}
+
+func TestNotDeleted(t *testing.T) {
+	assert.Equal(t, bson.M{sdk.DeletedAtField: nil}, notDeleted(bson.M{}))
+	filter := bson.M{"status": "open"}
+	assert.Equal(t, bson.M{"$and": bson.A{filter, bson.M{sdk.DeletedAtField: nil}}}, notDeleted(filter))
+}
+
+func TestDescriptionProjection(t *testing.T) {
+	assert.Equal(t, bson.M{"_id": 1, "name": 1, "company": 1}, descriptionProjection([]string{"id", "company.name", "name", "company"}),
+		"nested fields of a projected field are left out")
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	webhookDeliveryCollection = "webhook-delivery"
+	webhookDeliveryListLimit  = 100
+)
+
+// MongoWebhookDeliveryRepository persists the delivery log of the webhooks in the
+// "webhook-delivery" collection of the module database.
+type MongoWebhookDeliveryRepository struct{}
+
+func NewMongoWebhookDeliveryRepository() *MongoWebhookDeliveryRepository {
+	return &MongoWebhookDeliveryRepository{}
+}
+
+func (r *MongoWebhookDeliveryRepository) Save(ctx context.Context, delivery sdk.WebhookDelivery) error {
+	collection, err := r.getCollection()
+	if err != nil {
+		return err
+	}
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": delivery.Id}, delivery, options.Replace().SetUpsert(true)); err != nil {
+		return sdk.NewInternalServerError(fmt.Errorf("failed to save webhook delivery: %w", err))
+	}
+	return nil
+}
+
+func (r *MongoWebhookDeliveryRepository) Instance(ctx context.Context, id string) (*sdk.WebhookDelivery, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	var delivery sdk.WebhookDelivery
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&delivery); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, sdk.NewNotFoundError(fmt.Errorf("webhook delivery %s not found", id)).WithTranslation("sdk.webhook_delivery.messages.not_found", map[string]any{"id": id})
+		}
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to find webhook delivery: %w", err))
+	}
+	return &delivery, nil
+}
+
+// List returns the last deliveries, most recent first.
+func (r *MongoWebhookDeliveryRepository) List(ctx context.Context, dto sdk.ReadWebhookDeliveriesDTO) ([]sdk.WebhookDelivery, error) {
+	collection, err := r.getCollection()
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{}
+	if dto.EntityId != "" {
+		filter["entityId"] = dto.EntityId
+	}
+	if dto.WebhookId != "" {
+		filter["webhookId"] = dto.WebhookId
+	}
+	if dto.InstanceId != "" {
+		filter["instanceId"] = dto.InstanceId
+	}
+	if dto.Status != "" {
+		filter["status"] = dto.Status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(webhookDeliveryListLimit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to list webhook deliveries: %w", err))
+	}
+	deliveries := []sdk.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("failed to decode webhook deliveries: %w", err))
+	}
+	return deliveries, nil
+}
+
+func (r *MongoWebhookDeliveryRepository) getCollection() (*mongo.Collection, error) {
+	client, err := sdk.GetMongoClient()
+	if err != nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	return client.Database(sdk_configuration.GetConfig().ModuleDBName).Collection(webhookDeliveryCollection), nil
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ============================================================================
+// Pagination
+// ============================================================================
+// A ReadDTO is translated in a findQuery: the storage filter and the find options of its
+// sort and page. The After cursor is a keyset: the sort values of the last document of
+// the previous page, encoded in BSON so that dates and ObjectIDs keep their type. The sort
+// fields are always read, even when the projection leaves them out, and hidden afterwards.
+
+// findQuery is a ReadDTO ready to be run against a collection.
+type findQuery struct {
+	// filter selects the documents of the list, it is used to count them
+	filter bson.M
+	// page also skips the documents up to the After cursor
+	page bson.M
+	opts *options.FindOptions
+	// sort is the effective sort, including the _id tie-breaker, nil if unsorted
+	sort []sortKey
+	// hidden are the fields read for the cursor only, removed by hide
+	hidden []string
+}
+
+type sortKey struct {
+	field     string
+	direction int
+}
+
+// pageCursor is the content of a Pagination.Next cursor.
+type pageCursor struct {
+	Sort   string `bson:"s"`
+	Values bson.A `bson:"v"`
+}
+
+// newFindQuery builds the query of dto; filter must already be in storage format.
+func newFindQuery(dto sdk.ReadDTO, filter bson.M) (*findQuery, error) {
+	if dto.Skip < 0 || dto.Limit < 0 {
+		return nil, sdk.NewBadRequestError(fmt.Errorf("skip and limit must not be negative")).WithTranslation("sdk.pagination.invalid_range", nil)
+	}
+	sort, err := effectiveSort(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &findQuery{filter: filter, page: filter, opts: options.Find(), sort: sort}
+	if dto.Projection != nil {
+		projection := cloneBsonM(dto.Projection)
+		query.hidden = projectSortFields(projection, sort)
+		query.opts.SetProjection(projection)
+	}
+	if len(sort) > 0 {
+		sortDoc := make(bson.D, 0, len(sort))
+		for _, key := range sort {
+			sortDoc = append(sortDoc, bson.E{Key: key.field, Value: key.direction})
+		}
+		query.opts.SetSort(sortDoc)
+	}
+	if dto.Skip > 0 {
+		query.opts.SetSkip(dto.Skip)
+	}
+	if dto.Limit > 0 {
+		query.opts.SetLimit(dto.Limit)
+	}
+	if dto.After != "" {
+		values, err := decodePageCursor(dto.After, sort)
+		if err != nil {
+			return nil, err
+		}
+		keyset := keysetFilter(sort, values)
+		if len(filter) == 0 {
+			query.page = keyset
+		} else {
+			query.page = bson.M{"$and": bson.A{filter, keyset}}
+		}
+	}
+	return query, nil
+}
+
+// effectiveSort returns the sort of dto followed by _id, so that documents with the same
+// sort values are always returned in the same order. Lists without sort and page are left
+// in natural order.
+func effectiveSort(dto sdk.ReadDTO) ([]sortKey, error) {
+	if len(dto.Sort) == 0 && dto.Limit == 0 && dto.Skip == 0 && dto.After == "" {
+		return nil, nil
+	}
+	sort := make([]sortKey, 0, len(dto.Sort)+1)
+	hasID := false
+	for _, field := range dto.Sort {
+		direction := int(field.Direction)
+		if direction == 0 {
+			direction = int(sdk.SortAscending)
+		}
+		if field.Field == "" || strings.HasPrefix(field.Field, "$") || (direction != int(sdk.SortAscending) && direction != int(sdk.SortDescending)) {
+			return nil, sdk.NewBadRequestError(fmt.Errorf("invalid sort on field %q with direction %d", field.Field, field.Direction)).WithTranslation("sdk.pagination.invalid_sort", map[string]any{"field": field.Field})
+		}
+		hasID = hasID || field.Field == "_id"
+		sort = append(sort, sortKey{field: field.Field, direction: direction})
+	}
+	if !hasID {
+		sort = append(sort, sortKey{field: "_id", direction: int(sdk.SortAscending)})
+	}
+	return sort, nil
+}
+
+// projectSortFields changes projection so that it returns the sort fields, and returns the
+// fields (or the excluded parents of the fields) that the caller did not ask for.
+func projectSortFields(projection bson.M, sort []sortKey) []string {
+	inclusive := false
+	for field, value := range projection {
+		if field != "_id" && !excludedByProjection(value) {
+			inclusive = true
+		}
+	}
+	var hidden []string
+	for _, key := range sort {
+		if value, ok := projection[key.field]; ok && !excludedByProjection(value) {
+			continue
+		}
+		parent, excluded := excludingParent(projection, key.field)
+		switch {
+		case excluded:
+			// {"customer": 0} also hides the sort on customer.name
+			delete(projection, parent)
+			if !slices.Contains(hidden, parent) {
+				hidden = append(hidden, parent)
+			}
+		case !inclusive || key.field == "_id":
+			// an exclusion projection returns the other fields, and _id is returned unless excluded
+		case parent != "":
+			// included through {"customer": 1}
+		default:
+			projection[key.field] = 1
+			hidden = append(hidden, key.field)
+		}
+	}
+	return hidden
+}
+
+// excludingParent returns the projected field that is field or one of its parents, and
+// whether it is excluded.
+func excludingParent(projection bson.M, field string) (string, bool) {
+	parts := strings.Split(field, ".")
+	for i := 1; i <= len(parts); i++ {
+		path := strings.Join(parts[:i], ".")
+		if value, ok := projection[path]; ok {
+			return path, excludedByProjection(value)
+		}
+	}
+	return "", false
+}
+
+// excludedByProjection reports whether a projection value excludes the field (0 or false).
+func excludedByProjection(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return !v
+	case int:
+		return v == 0
+	case int32:
+		return v == 0
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// hide removes the fields read for the cursor only from the documents.
+func (q *findQuery) hide(docs ...bson.M) {
+	for _, doc := range docs {
+		for _, field := range q.hidden {
+			removeDocumentPath(doc, strings.Split(field, "."))
+		}
+	}
+}
+
+// removeDocumentPath deletes path from doc, with the parents it leaves empty.
+func removeDocumentPath(doc bson.M, path []string) {
+	if len(path) == 1 {
+		delete(doc, path[0])
+		return
+	}
+	child, ok := doc[path[0]].(bson.M)
+	if !ok {
+		return
+	}
+	removeDocumentPath(child, path[1:])
+	if len(child) == 0 {
+		delete(doc, path[0])
+	}
+}
+
+// keysetFilter selects the documents that follow values in the given sort:
+// (f1 > v1) OR (f1 = v1 AND f2 > v2) OR ... with $lt for descending fields. Null and
+// missing values sort before any other value: they follow every value in descending
+// order and precede every value in ascending order.
+func keysetFilter(sort []sortKey, values bson.A) bson.M {
+	or := make(bson.A, 0, len(sort))
+	for i, key := range sort {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[sort[j].field] = values[j]
+		}
+		switch {
+		case values[i] == nil && key.direction < 0:
+			// nothing sorts before null
+			continue
+		case values[i] == nil:
+			clause[key.field] = bson.M{"$ne": nil}
+		case key.direction < 0:
+			clause["$or"] = bson.A{
+				bson.M{key.field: bson.M{"$lt": values[i]}},
+				bson.M{key.field: nil},
+			}
+		default:
+			clause[key.field] = bson.M{"$gt": values[i]}
+		}
+		or = append(or, clause)
+	}
+	return bson.M{"$or": or}
+}
+
+// nextPageCursor returns the cursor of the page following the document last.
+func nextPageCursor(sort []sortKey, last bson.M) (string, error) {
+	values := make(bson.A, 0, len(sort))
+	for _, key := range sort {
+		value, _ := lookupDocumentPath(last, key.field)
+		values = append(values, value)
+	}
+	raw, err := bson.Marshal(pageCursor{Sort: sortSignature(sort), Values: values})
+	if err != nil {
+		return "", sdk.NewInternalServerError(fmt.Errorf("failed to encode page cursor: %w", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodePageCursor(cursor string, sort []sortKey) (bson.A, error) {
+	invalid := func(err error) error {
+		return sdk.NewBadRequestError(fmt.Errorf("invalid page cursor: %w", err)).WithTranslation("sdk.pagination.invalid_cursor", nil)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	var decoded pageCursor
+	if err := bson.Unmarshal(raw, &decoded); err != nil {
+		return nil, invalid(err)
+	}
+	if decoded.Sort != sortSignature(sort) || len(decoded.Values) != len(sort) {
+		return nil, invalid(fmt.Errorf("cursor sorted by %q, list sorted by %q", decoded.Sort, sortSignature(sort)))
+	}
+	return decoded.Values, nil
+}
+
+func sortSignature(sort []sortKey) string {
+	parts := make([]string, 0, len(sort))
+	for _, key := range sort {
+		parts = append(parts, fmt.Sprintf("%s:%d", key.field, key.direction))
+	}
+	return strings.Join(parts, ",")
+}
+
+// lookupDocumentPath resolves a dot-notation path in a decoded document.
+func lookupDocumentPath(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		switch m := current.(type) {
+		case bson.M:
+			current = m[part]
+		case map[string]interface{}:
+			current = m[part]
+		case bson.D:
+			var found interface{}
+			for _, e := range m {
+				if e.Key == part {
+					found = e.Value
+					break
+				}
+			}
+			current = found
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEffectiveSort_AddsIDTieBreaker(t *testing.T) {
+	sort, err := effectiveSort(sdk.ReadDTO{Sort: []sdk.SortField{{Field: "createdAt", Direction: sdk.SortDescending}, {Field: "name"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []sortKey{{"createdAt", -1}, {"name", 1}, {"_id", 1}}, sort)
+
+	sort, err = effectiveSort(sdk.ReadDTO{Sort: []sdk.SortField{{Field: "_id", Direction: sdk.SortDescending}}})
+	require.NoError(t, err)
+	assert.Equal(t, []sortKey{{"_id", -1}}, sort)
+
+	sort, err = effectiveSort(sdk.ReadDTO{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []sortKey{{"_id", 1}}, sort)
+
+	sort, err = effectiveSort(sdk.ReadDTO{})
+	require.NoError(t, err)
+	assert.Nil(t, sort)
+}
+
+func TestNewFindQuery_RejectsInvalidSortAndRange(t *testing.T) {
+	for _, dto := range []sdk.ReadDTO{
+		{Sort: []sdk.SortField{{Field: "name", Direction: 2}}},
+		{Sort: []sdk.SortField{{Field: "$where"}}},
+		{Sort: []sdk.SortField{{Field: ""}}},
+		{Limit: -1},
+		{Skip: -5},
+	} {
+		_, err := newFindQuery(dto, bson.M{})
+		var endorError *sdk.EndorError
+		require.True(t, errors.As(err, &endorError), "%+v", dto)
+		assert.Equal(t, http.StatusBadRequest, endorError.StatusCode)
+	}
+}
+
+func TestPageCursor_RoundTripKeepsTypes(t *testing.T) {
+	sort := []sortKey{{"createdAt", -1}, {"customer.name", 1}, {"_id", 1}}
+	id := primitive.NewObjectID()
+	createdAt := primitive.NewDateTimeFromTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	last := bson.M{"_id": id, "createdAt": createdAt, "customer": bson.M{"name": "Acme"}}
+
+	cursor, err := nextPageCursor(sort, last)
+	require.NoError(t, err)
+	values, err := decodePageCursor(cursor, sort)
+	require.NoError(t, err)
+	assert.Equal(t, bson.A{createdAt, "Acme", id}, values)
+}
+
+func TestPageCursor_RejectsOtherSortAndGarbage(t *testing.T) {
+	cursor, err := nextPageCursor([]sortKey{{"name", 1}, {"_id", 1}}, bson.M{"_id": "a", "name": "x"})
+	require.NoError(t, err)
+
+	_, err = decodePageCursor(cursor, []sortKey{{"name", -1}, {"_id", 1}})
+	assert.Error(t, err)
+	_, err = decodePageCursor("not a cursor!", []sortKey{{"_id", 1}})
+	assert.Error(t, err)
+}
+
+func TestNewFindQuery_AfterAddsKeysetToFilter(t *testing.T) {
+	dto := sdk.ReadDTO{Sort: []sdk.SortField{{Field: "total", Direction: sdk.SortDescending}}, Limit: 2}
+	sort, err := effectiveSort(dto)
+	require.NoError(t, err)
+	dto.After, err = nextPageCursor(sort, bson.M{"_id": "o2", "total": int32(50)})
+	require.NoError(t, err)
+
+	filter := bson.M{"status": "paid"}
+	query, err := newFindQuery(dto, filter)
+	require.NoError(t, err)
+	assert.Equal(t, filter, query.filter)
+	assert.Equal(t, bson.M{"$and": bson.A{
+		filter,
+		bson.M{"$or": bson.A{
+			bson.M{"$or": bson.A{bson.M{"total": bson.M{"$lt": int32(50)}}, bson.M{"total": nil}}},
+			bson.M{"total": int32(50), "_id": bson.M{"$gt": "o2"}},
+		}},
+	}}, query.page)
+	assert.Equal(t, bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}, query.opts.Sort)
+	assert.Equal(t, int64(2), *query.opts.Limit)
+}
+
+func TestKeysetFilter_NullValues(t *testing.T) {
+	sort := []sortKey{{"dueDate", 1}, {"priority", -1}, {"_id", 1}}
+
+	// null sorts first: after a null due date come the other nulls and every dated document
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"dueDate": bson.M{"$ne": nil}},
+		bson.M{"dueDate": nil, "$or": bson.A{bson.M{"priority": bson.M{"$lt": int32(2)}}, bson.M{"priority": nil}}},
+		bson.M{"dueDate": nil, "priority": int32(2), "_id": bson.M{"$gt": "t1"}},
+	}}, keysetFilter(sort, bson.A{nil, int32(2), "t1"}))
+
+	// nothing follows a null priority in descending order but the ties on _id
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"dueDate": bson.M{"$gt": "2026-01-01"}},
+		bson.M{"dueDate": "2026-01-01", "priority": nil, "_id": bson.M{"$gt": "t2"}},
+	}}, keysetFilter(sort, bson.A{"2026-01-01", nil, "t2"}))
+}
+
+func TestNewFindQuery_ProjectsSortFields(t *testing.T) {
+	dto := sdk.ReadDTO{
+		Sort:       []sdk.SortField{{Field: "total", Direction: sdk.SortDescending}, {Field: "customer.name"}},
+		Projection: map[string]interface{}{"code": 1, "_id": 0},
+		Limit:      2,
+	}
+	query, err := newFindQuery(dto, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"code": 1, "total": 1, "customer.name": 1}, query.opts.Projection)
+	assert.Equal(t, []string{"total", "customer.name", "_id"}, query.hidden)
+	assert.Equal(t, map[string]interface{}{"code": 1, "_id": 0}, dto.Projection, "the projection of the dto is not changed")
+
+	// the cursor is built from the fields read for it, which are then hidden
+	last := bson.M{"_id": "o2", "code": "B", "total": int32(50), "customer": bson.M{"name": "Acme"}}
+	cursor, err := nextPageCursor(query.sort, last)
+	require.NoError(t, err)
+	values, err := decodePageCursor(cursor, query.sort)
+	require.NoError(t, err)
+	assert.Equal(t, bson.A{int32(50), "Acme", "o2"}, values)
+	query.hide(last)
+	assert.Equal(t, bson.M{"code": "B"}, last)
+
+	// excluded sort fields, or excluded parents of them, are read and hidden too
+	dto.Projection = map[string]interface{}{"total": 0, "customer": false, "notes": 0}
+	query, err = newFindQuery(dto, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"notes": 0}, query.opts.Projection)
+	assert.Equal(t, []string{"total", "customer"}, query.hidden)
+
+	// fields already projected are not hidden
+	dto.Projection = map[string]interface{}{"total": 1, "customer": 1}
+	query, err = newFindQuery(dto, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"total": 1, "customer": 1}, query.opts.Projection)
+	assert.Empty(t, query.hidden)
+}
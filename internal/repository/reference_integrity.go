@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+// deleteWithReferences runs remove after applying the delete policies of the references to
+// the instance id of entityId (see sdk.EnforceReferencesOnDelete), in a transaction when
+// they write other instances.
+func deleteWithReferences(ctx context.Context, di sdk.EndorDIContainerInterface, entityId, id string, remove func(ctx context.Context) error) error {
+	if di == nil {
+		return remove(ctx)
+	}
+	enforce := func(ctx context.Context) error {
+		if err := sdk.EnforceReferencesOnDelete(ctx, di, entityId, id); err != nil {
+			return err
+		}
+		return remove(ctx)
+	}
+	if !sdk.HasWritingReferences(di, entityId) {
+		return enforce(ctx)
+	}
+	return inTransaction(ctx, enforce)
+}
@@ -0,0 +1,266 @@
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// #region Entries
+
+// AuditRedacted replaces the sensitive values in the payloads of the audit trail.
+const AuditRedacted = "[REDACTED]"
+
+// AuditReadPermission is required to read the audit trail.
+const AuditReadPermission = "audit:read"
+
+// readActionNames are the default actions that never change state.
+var readActionNames = map[string]struct{}{
+	"schema":   {},
+	"instance": {},
+	"list":     {},
+	"trash":    {},
+	"history":  {},
+	"version":  {},
+}
+
+// IsReadAction reports whether the action named name (the last segment of the action id)
+// only reads: the default read actions and the ones declared ReadOnly are not audited.
+func IsReadAction(name string, options EndorHandlerActionOptions) bool {
+	_, read := readActionNames[path.Base(name)]
+	return read || options.ReadOnly
+}
+
+// AuditEntry records an invocation of a non-read action.
+type AuditEntry struct {
+	Id       string `json:"id" bson:"_id" schema:"title=${t.sdk.audit.fields.id},readOnly=true"`
+	ActionId string `json:"actionId" bson:"actionId" schema:"title=${t.sdk.audit.fields.action_id},readOnly=true"`
+	Entity   string `json:"entity" bson:"entity" schema:"title=${t.sdk.audit.fields.entity},readOnly=true"`
+	Category string `json:"category,omitempty" bson:"category,omitempty" schema:"title=${t.sdk.audit.fields.category},readOnly=true"`
+	Action   string `json:"action" bson:"action" schema:"title=${t.sdk.audit.fields.action},readOnly=true"`
+	UserId   string `json:"userId,omitempty" bson:"userId,omitempty" schema:"title=${t.sdk.audit.fields.user_id},readOnly=true"`
+	Username string `json:"username,omitempty" bson:"username,omitempty" schema:"title=${t.sdk.audit.fields.username},readOnly=true"`
+	// Payload is the payload of the action with the writeOnly and password fields of its
+	// input schema redacted.
+	Payload map[string]any `json:"payload,omitempty" bson:"payload,omitempty" schema:"title=${t.sdk.audit.fields.payload},readOnly=true"`
+	// PayloadHash is the hex SHA-256 of the JSON of Payload, after redaction.
+	PayloadHash string `json:"payloadHash,omitempty" bson:"payloadHash,omitempty" schema:"title=${t.sdk.audit.fields.payload_hash},readOnly=true"`
+	// StatusCode is the HTTP status of the outcome: 200 or the status of the error.
+	StatusCode int       `json:"statusCode" bson:"statusCode" schema:"title=${t.sdk.audit.fields.status_code},readOnly=true"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty" schema:"title=${t.sdk.audit.fields.error},readOnly=true"`
+	DurationMs int64     `json:"durationMs" bson:"durationMs" schema:"title=${t.sdk.audit.fields.duration_ms},readOnly=true"`
+	OccurredAt time.Time `json:"occurredAt" bson:"occurredAt" schema:"title=${t.sdk.audit.fields.occurred_at},readOnly=true"`
+}
+
+func (e *AuditEntry) GetID() any {
+	return e.Id
+}
+
+// ReadAuditEntriesDTO selects the audit entries; empty fields match every entry. Entity is
+// the entity name (e.g. "order"), From and To bound OccurredAt (inclusive).
+type ReadAuditEntriesDTO struct {
+	UserId string     `json:"userId,omitempty"`
+	Entity string     `json:"entity,omitempty"`
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+}
+
+func (dto ReadAuditEntriesDTO) matches(entry AuditEntry) bool {
+	return (dto.UserId == "" || dto.UserId == entry.UserId) &&
+		(dto.Entity == "" || dto.Entity == entry.Entity) &&
+		(dto.From == nil || !entry.OccurredAt.Before(*dto.From)) &&
+		(dto.To == nil || !entry.OccurredAt.After(*dto.To))
+}
+
+// AuditRepositoryInterface stores the audit trail.
+type AuditRepositoryInterface interface {
+	Add(ctx context.Context, entry AuditEntry) error
+	Instance(ctx context.Context, id string) (*AuditEntry, error)
+	// List returns the last entries, most recent first.
+	List(ctx context.Context, dto ReadAuditEntriesDTO) ([]AuditEntry, error)
+}
+
+func auditEntryNotFoundError(id string) error {
+	return NewNotFoundError(fmt.Errorf("audit entry %s not found", id)).WithTranslation("sdk.audit.messages.not_found", map[string]any{"id": id})
+}
+
+// InMemoryAuditRepository keeps the audit trail in memory: it is meant for tests and local
+// development.
+type InMemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries map[string]AuditEntry
+}
+
+func NewInMemoryAuditRepository() *InMemoryAuditRepository {
+	return &InMemoryAuditRepository{entries: map[string]AuditEntry{}}
+}
+
+func (r *InMemoryAuditRepository) Add(ctx context.Context, entry AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[entry.Id] = entry
+	return nil
+}
+
+func (r *InMemoryAuditRepository) Instance(ctx context.Context, id string) (*AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, exists := r.entries[id]
+	if !exists {
+		return nil, auditEntryNotFoundError(id)
+	}
+	return &entry, nil
+}
+
+func (r *InMemoryAuditRepository) List(ctx context.Context, dto ReadAuditEntriesDTO) ([]AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries := []AuditEntry{}
+	for _, entry := range r.entries {
+		if dto.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b AuditEntry) int {
+		return b.OccurredAt.Compare(a.OccurredAt)
+	})
+	return entries, nil
+}
+
+// #endregion
+
+// #region Trail
+
+// AuditTrail records the invocations of the non-read actions of the service.
+type AuditTrail struct {
+	repository AuditRepositoryInterface
+	logger     *Logger
+	now        func() time.Time
+}
+
+func NewAuditTrail(repository AuditRepositoryInterface, logger *Logger) *AuditTrail {
+	return &AuditTrail{repository: repository, logger: logger, now: time.Now}
+}
+
+// Repository returns the storage of the trail.
+func (t *AuditTrail) Repository() AuditRepositoryInterface {
+	return t.repository
+}
+
+// Middleware records the invocations of an action whose payloads are described by
+// inputSchema. The entry is written once the action returns; failing to write it is
+// logged and does not change the outcome of the action.
+func (t *AuditTrail) Middleware(inputSchema *RootSchema) EndorActionMiddleware {
+	return func(ctx EndorContextInterface, next EndorActionNext) (EndorResponseInterface, error) {
+		started := t.now()
+		response, err := next()
+		entry := t.entry(ctx, inputSchema, started, err)
+		if recordErr := t.repository.Add(context.WithoutCancel(ctx.Context()), entry); recordErr != nil && t.logger != nil {
+			t.logger.Error(fmt.Sprintf("failed to record audit entry of %s: %s", entry.ActionId, recordErr.Error()))
+		}
+		return response, err
+	}
+}
+
+func (t *AuditTrail) entry(ctx EndorContextInterface, inputSchema *RootSchema, started time.Time, err error) AuditEntry {
+	session := ctx.GetSession()
+	entry := AuditEntry{
+		Id:         primitive.NewObjectID().Hex(),
+		ActionId:   ctx.GetActionId(),
+		UserId:     session.UserId,
+		Username:   session.Username,
+		StatusCode: http.StatusOK,
+		DurationMs: t.now().Sub(started).Milliseconds(),
+		OccurredAt: started.UTC(),
+	}
+	if _, entity, category, action, parseErr := ParseEntityActionID(entry.ActionId); parseErr == nil {
+		entry.Entity, entry.Category, entry.Action = entity, category, action
+	}
+	if err != nil {
+		entry.StatusCode = http.StatusInternalServerError
+		var endorError *EndorError
+		if errors.As(err, &endorError) {
+			entry.StatusCode = endorError.StatusCode
+		}
+		entry.Error = err.Error()
+	}
+	if body, marshalErr := json.Marshal(ctx.GetPayload()); marshalErr == nil {
+		var payload map[string]any
+		if json.Unmarshal(body, &payload) == nil && len(payload) > 0 {
+			entry.Payload = RedactPayload(payload, inputSchema)
+			// the hash of the secrets would allow to guess them offline
+			if redacted, err := json.Marshal(entry.Payload); err == nil {
+				body = redacted
+			}
+		}
+		hash := sha256.Sum256(body)
+		entry.PayloadHash = hex.EncodeToString(hash[:])
+	}
+	return entry
+}
+
+// RedactPayload returns a copy of payload with the values of the writeOnly and password
+// fields of schema replaced by AuditRedacted.
+func RedactPayload(payload map[string]any, schema *RootSchema) map[string]any {
+	if schema == nil {
+		return payload
+	}
+	redacted, _ := schema.redactValue(&schema.Schema, payload, 0).(map[string]any)
+	return redacted
+}
+
+// maxRedactionDepth stops the redaction of recursive schemas.
+const maxRedactionDepth = 32
+
+func (rs *RootSchema) redactValue(s *Schema, value any, depth int) any {
+	s = rs.resolveReference(s)
+	if value == nil || depth > maxRedactionDepth {
+		return value
+	}
+	if (s.WriteOnly != nil && *s.WriteOnly) || (s.Format != nil && *s.Format == SchemaFormatPassword) {
+		return AuditRedacted
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, item := range v {
+			switch {
+			case s.Properties != nil && hasProperty(*s.Properties, key):
+				property := (*s.Properties)[key]
+				redacted[key] = rs.redactValue(&property, item, depth+1)
+			case s.AdditionalProperties != nil:
+				redacted[key] = rs.redactValue(s.AdditionalProperties, item, depth+1)
+			default:
+				redacted[key] = item
+			}
+		}
+		return redacted
+	case []any:
+		if s.Items == nil {
+			return v
+		}
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = rs.redactValue(s.Items, item, depth+1)
+		}
+		return redacted
+	}
+	return value
+}
+
+func hasProperty(properties map[string]Schema, key string) bool {
+	_, exists := properties[key]
+	return exists
+}
+
+// #endregion
@@ -0,0 +1,114 @@
+package sdk_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditCredentials struct {
+	Password string `json:"password" schema:"format=password"`
+}
+
+type auditPayload struct {
+	Name        string             `json:"name"`
+	Token       string             `json:"token" schema:"writeOnly=true"`
+	Credentials auditCredentials   `json:"credentials"`
+	Accounts    []auditCredentials `json:"accounts"`
+}
+
+func TestRedactPayload(t *testing.T) {
+	schema := sdk.NewSchema(auditPayload{})
+	payload := map[string]any{
+		"name":        "mario",
+		"token":       "secret",
+		"credentials": map[string]any{"password": "p1"},
+		"accounts":    []any{map[string]any{"password": "p2"}},
+		"extra":       "kept",
+	}
+
+	redacted := sdk.RedactPayload(payload, schema)
+
+	assert.Equal(t, map[string]any{
+		"name":        "mario",
+		"token":       sdk.AuditRedacted,
+		"credentials": map[string]any{"password": sdk.AuditRedacted},
+		"accounts":    []any{map[string]any{"password": sdk.AuditRedacted}},
+		"extra":       "kept",
+	}, redacted)
+	assert.Equal(t, "secret", payload["token"], "the payload must not be modified")
+}
+
+func TestIsReadAction(t *testing.T) {
+	assert.True(t, sdk.IsReadAction("list", sdk.EndorHandlerActionOptions{}))
+	assert.True(t, sdk.IsReadAction("premium/instance", sdk.EndorHandlerActionOptions{}))
+	assert.True(t, sdk.IsReadAction("execute", sdk.EndorHandlerActionOptions{ReadOnly: true}))
+	assert.False(t, sdk.IsReadAction("create", sdk.EndorHandlerActionOptions{}))
+	assert.False(t, sdk.IsReadAction("premium/restore", sdk.EndorHandlerActionOptions{}))
+}
+
+func TestAuditTrail_Middleware(t *testing.T) {
+	repository := sdk.NewInMemoryAuditRepository()
+	trail := sdk.NewAuditTrail(repository, nil)
+	fail := false
+	action := sdk.NewAction(
+		func(c *sdk.EndorContext[auditPayload]) (*sdk.Response[string], error) {
+			if fail {
+				return nil, sdk.NewConflictError(errors.New("conflict"))
+			}
+			return sdk.NewResponseBuilder[string]().AddData(&c.Payload.Name).Build(), nil
+		},
+		"test",
+	)
+	action = action.WithMiddlewares(trail.Middleware(action.GetOptions().InputSchema))
+	newContext := func() *sdk.EndorContext[auditPayload] {
+		return &sdk.EndorContext[auditPayload]{
+			ActionId: "endor-test-service/user/create",
+			Session:  sdk.Session{UserId: "u1", Username: "mario"},
+			Payload:  auditPayload{Name: "mario", Token: "secret"},
+		}
+	}
+
+	_, err := action.Invoke(newContext())
+	require.NoError(t, err)
+	fail = true
+	_, err = action.Invoke(newContext())
+	require.Error(t, err)
+
+	entries, err := repository.List(context.Background(), sdk.ReadAuditEntriesDTO{UserId: "u1", Entity: "user"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	statuses := []int{entries[0].StatusCode, entries[1].StatusCode}
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusConflict}, statuses)
+	for _, entry := range entries {
+		assert.Equal(t, "endor-test-service/user/create", entry.ActionId)
+		assert.Equal(t, "user", entry.Entity)
+		assert.Equal(t, "create", entry.Action)
+		assert.Equal(t, "mario", entry.Username)
+		assert.Equal(t, sdk.AuditRedacted, entry.Payload["token"])
+		assert.Equal(t, "mario", entry.Payload["name"])
+		redacted, err := json.Marshal(entry.Payload)
+		require.NoError(t, err)
+		hash := sha256.Sum256(redacted)
+		assert.Equal(t, hex.EncodeToString(hash[:]), entry.PayloadHash, "the secrets are not hashed")
+	}
+
+	future := time.Now().Add(time.Hour)
+	entries, err = repository.List(context.Background(), sdk.ReadAuditEntriesDTO{From: &future})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = repository.Instance(context.Background(), "missing")
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusNotFound, endorError.StatusCode)
+}
@@ -1,6 +1,9 @@
 package sdk
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,20 +14,72 @@ type Session struct {
 	Development bool   `json:"development"`
 	Locale      string `json:"locale"`
 	AccessToken string `json:"accessToken"`
+	// Roles and Permissions are provided by the gateway (X-Endor-Roles, X-Endor-Permissions);
+	// Permissions also include the ones granted by the roles.
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
 }
 
 type EndorContext[T any] struct {
 	MicroServiceId string
-	Session        Session
-	Payload        T
-	CategoryType   string
+	// ActionId is the full action identifier (<module>/<entity>/[<category>/]<action>).
+	ActionId     string
+	Session      Session
+	Payload      T
+	CategoryType string
 
 	// DIContainer gives handler code access to all registered handlers and repositories
 	// for the current session (production or per-user development overlay).
 	DIContainer EndorDIContainerInterface
 
+	// GinContext is nil for actions with a timeout (gin recycles it once the 504 is
+	// written): read the request headers with Header.
 	GinContext *gin.Context
 	Logger     Logger
+
+	// ctx overrides the request context (e.g. with the action timeout)
+	ctx context.Context
+	// headers is the snapshot of the request headers taken when GinContext is dropped
+	headers http.Header
+}
+
+// Header returns the request header name, empty for programmatic invocations.
+func (ec *EndorContext[T]) Header(name string) string {
+	if ec.headers != nil {
+		return ec.headers.Get(name)
+	}
+	if ec.GinContext == nil || ec.GinContext.Request == nil {
+		return ""
+	}
+	return ec.GinContext.GetHeader(name)
+}
+
+// Context returns the context of the current action: it is derived from the HTTP request
+// context, so it is cancelled when the client disconnects or the action times out.
+// It carries the session (see SessionFromContext). Pass it to every repository call.
+func (ec *EndorContext[T]) Context() context.Context {
+	ctx := ec.ctx
+	if ctx == nil && ec.GinContext != nil && ec.GinContext.Request != nil {
+		ctx = ec.GinContext.Request.Context()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ContextWithSession(ctx, ec.Session)
+}
+
+type sessionContextKey struct{}
+
+// ContextWithSession returns a copy of ctx carrying session.
+func ContextWithSession(ctx context.Context, session Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session of the action ctx belongs to. Repositories use
+// it to know who performs a write.
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(Session)
+	return session, ok
 }
 
 // T translates the given key using named placeholder interpolation {{key}}.
@@ -37,4 +92,51 @@ func (ec *EndorContext[T]) ResolveTExpr(value string) string {
 	return ec.DIContainer.GetTranslator().ResolveTExpr(ec.Session.Locale, value)
 }
 
+// EndorContextInterface is the untyped view of an EndorContext[T], used by code that
+// handles actions generically (e.g. middlewares).
+type EndorContextInterface interface {
+	GetMicroServiceId() string
+	GetActionId() string
+	Context() context.Context
+	GetSession() Session
+	GetPayload() any
+	GetCategoryType() string
+	GetDIContainer() EndorDIContainerInterface
+	GetGinContext() *gin.Context
+	GetLogger() *Logger
+	T(key string, args map[string]any) string
+}
+
+func (ec *EndorContext[T]) GetMicroServiceId() string {
+	return ec.MicroServiceId
+}
+
+func (ec *EndorContext[T]) GetActionId() string {
+	return ec.ActionId
+}
+
+func (ec *EndorContext[T]) GetSession() Session {
+	return ec.Session
+}
+
+func (ec *EndorContext[T]) GetPayload() any {
+	return ec.Payload
+}
+
+func (ec *EndorContext[T]) GetCategoryType() string {
+	return ec.CategoryType
+}
+
+func (ec *EndorContext[T]) GetDIContainer() EndorDIContainerInterface {
+	return ec.DIContainer
+}
+
+func (ec *EndorContext[T]) GetGinContext() *gin.Context {
+	return ec.GinContext
+}
+
+func (ec *EndorContext[T]) GetLogger() *Logger {
+	return &ec.Logger
+}
+
 type NoPayload struct{}
@@ -0,0 +1,117 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndorContext_ContextFromRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	requestCtx, cancel := context.WithCancel(context.Background())
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil).WithContext(requestCtx)
+
+	ec := &sdk.EndorContext[sdk.NoPayload]{GinContext: c}
+	require.NoError(t, ec.Context().Err())
+	cancel()
+	assert.ErrorIs(t, ec.Context().Err(), context.Canceled)
+
+	assert.NotNil(t, (&sdk.EndorContext[sdk.NoPayload]{}).Context())
+}
+
+func TestTimeout_CancelsHandlerAndAnswers504(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handlerCtxDone := make(chan error, 1)
+	action := sdk.NewConfigurableAction(
+		sdk.EndorHandlerActionOptions{Timeout: 20 * time.Millisecond},
+		func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+			<-c.Context().Done()
+			handlerCtxDone <- c.Context().Err()
+			return nil, c.Context().Err()
+		},
+	)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/report/build", nil)
+	action.CreateHTTPCallback("sdk", "report", "build", "", sdk.Session{Locale: "en"}, testDIContainer{})(c)
+
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+	var response sdk.Response[any]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Messages, 1)
+	assert.Equal(t, "sdk/report/build did not complete within 20ms", response.Messages[0].Value)
+	assert.ErrorIs(t, <-handlerCtxDone, context.DeadlineExceeded)
+}
+
+func TestTimeout_FastHandlerIsUnaffected(t *testing.T) {
+	action := sdk.NewConfigurableAction(
+		sdk.EndorHandlerActionOptions{Timeout: time.Second},
+		func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[string], error) {
+			_, hasDeadline := c.Context().Deadline()
+			value := "done"
+			if !hasDeadline {
+				value = "no deadline"
+			}
+			return sdk.NewResponseBuilder[string]().AddData(&value).Build(), nil
+		},
+	)
+	result, err := action.Invoke(&sdk.EndorContext[sdk.NoPayload]{})
+	require.NoError(t, err)
+	assert.Equal(t, "done", *result.(*sdk.Response[string]).Data)
+}
+
+func TestTimeout_HandlerReadsHeaderSnapshot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	action := sdk.NewConfigurableAction(
+		sdk.EndorHandlerActionOptions{Timeout: time.Second},
+		func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[string], error) {
+			if c.GinContext != nil {
+				return nil, sdk.NewInternalServerError(errors.New("the gin context outlives the request after a timeout"))
+			}
+			version, err := c.IfMatchVersion()
+			if err != nil {
+				return nil, err
+			}
+			value := fmt.Sprintf("%d %t", *version, c.StreamRequested())
+			return sdk.NewResponseBuilder[string]().AddData(&value).Build(), nil
+		},
+	)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/report/build", nil)
+	c.Request.Header.Set("If-Match", `"3"`)
+	action.CreateHTTPCallback("sdk", "report", "build", "", sdk.Session{Locale: "en"}, testDIContainer{})(c)
+
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var response sdk.Response[string]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "3 false", *response.Data)
+}
+
+func TestExecute_RecoversPanics(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Second} {
+		action := sdk.NewConfigurableAction(
+			sdk.EndorHandlerActionOptions{Timeout: timeout},
+			func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+				panic("boom")
+			},
+		)
+		_, err := action.Invoke(&sdk.EndorContext[sdk.NoPayload]{ActionId: "sdk/report/build"})
+		var endorError *sdk.EndorError
+		require.ErrorAs(t, err, &endorError, timeout)
+		assert.Equal(t, http.StatusInternalServerError, endorError.StatusCode, timeout)
+	}
+}
@@ -0,0 +1,186 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// emptyDescriptionGroup matches the brackets left empty by missing fields.
+var emptyDescriptionGroup = regexp.MustCompile(`\(\s*\)|\[\s*\]`)
+
+// descriptionPlaceholder matches the {{field}} placeholders of a description template,
+// with dot notation for nested fields.
+var descriptionPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// DescriptionTemplate describes the instances of an entity in the references, from one or
+// more of their fields (UISchema.EntityDescriptionTemplate, e.g.
+// "{{name}} {{surname}} ({{company}})").
+type DescriptionTemplate struct {
+	template string
+	fields   []string
+}
+
+// NewDescriptionTemplate parses template.
+func NewDescriptionTemplate(template string) *DescriptionTemplate {
+	t := &DescriptionTemplate{template: template}
+	for _, match := range descriptionPlaceholder.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(t.fields, match[1]) {
+			t.fields = append(t.fields, match[1])
+		}
+	}
+	return t
+}
+
+// DescriptionTemplate returns the template describing the instances of the schema: its
+// EntityDescriptionTemplate, or the EntityDescriptionKey field; nil if it has neither.
+func (rs *RootSchema) DescriptionTemplate() *DescriptionTemplate {
+	if rs == nil || rs.UISchema == nil {
+		return nil
+	}
+	if rs.UISchema.EntityDescriptionTemplate != nil {
+		return NewDescriptionTemplate(*rs.UISchema.EntityDescriptionTemplate)
+	}
+	if rs.UISchema.EntityDescriptionKey != nil {
+		return NewDescriptionTemplate("{{" + *rs.UISchema.EntityDescriptionKey + "}}")
+	}
+	return nil
+}
+
+// Fields returns the fields used by the template, the projection needed to render it.
+func (t *DescriptionTemplate) Fields() []string {
+	return t.fields
+}
+
+// Render describes document (a stored or JSON document) with format. Missing fields are
+// rendered empty: the brackets left empty are dropped, together with the separators left
+// at the ends, and the spaces collapsed ("{{surname}}, {{name}} ({{company}})" renders
+// "Rossi" when only the surname is set).
+func (t *DescriptionTemplate) Render(document map[string]interface{}, format DescriptionFormat) string {
+	rendered := descriptionPlaceholder.ReplaceAllStringFunc(t.template, func(match string) string {
+		field := descriptionPlaceholder.FindStringSubmatch(match)[1]
+		return format.value(valueAtPath(document, strings.Split(field, ".")))
+	})
+	rendered = emptyDescriptionGroup.ReplaceAllString(rendered, "")
+	return strings.Trim(strings.Join(strings.Fields(rendered), " "), " ,;:-/")
+}
+
+func valueAtPath(value any, path []string) any {
+	if len(path) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return valueAtPath(v[path[0]], path[1:])
+	case primitive.M:
+		return valueAtPath(map[string]interface{}(v), path)
+	case primitive.D:
+		return valueAtPath(map[string]interface{}(v.Map()), path)
+	}
+	return nil
+}
+
+// DescriptionFormat formats the values of the description templates for a locale.
+type DescriptionFormat struct {
+	// Date and DateTime are the time layouts of dates (times at midnight UTC) and of the
+	// other times.
+	Date     string
+	DateTime string
+	// DecimalSeparator replaces the "." of the numbers with a fractional part.
+	DecimalSeparator string
+	True             string
+	False            string
+}
+
+// defaultDescriptionFormat is used without a translator.
+var defaultDescriptionFormat = DescriptionFormat{
+	Date:             time.DateOnly,
+	DateTime:         "2006-01-02 15:04",
+	DecimalSeparator: ".",
+	True:             "true",
+	False:            "false",
+}
+
+// NewDescriptionFormat returns the format of locale, from the sdk.formats translations.
+func NewDescriptionFormat(translator *sdk_i18n.Translator, locale string) DescriptionFormat {
+	if translator == nil {
+		return defaultDescriptionFormat
+	}
+	return DescriptionFormat{
+		Date:             translator.T(locale, "sdk.formats.date", nil),
+		DateTime:         translator.T(locale, "sdk.formats.date_time", nil),
+		DecimalSeparator: translator.T(locale, "sdk.formats.decimal_separator", nil),
+		True:             translator.T(locale, "sdk.formats.boolean_true", nil),
+		False:            translator.T(locale, "sdk.formats.boolean_false", nil),
+	}
+}
+
+// DescriptionFormatOf returns the format of the locale of the session of ctx, with the
+// translations of container.
+func DescriptionFormatOf(ctx context.Context, container EndorDIContainerInterface) DescriptionFormat {
+	session, _ := SessionFromContext(ctx)
+	if container == nil {
+		return defaultDescriptionFormat
+	}
+	return NewDescriptionFormat(container.GetTranslator(), session.Locale)
+}
+
+func (f DescriptionFormat) value(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return f.True
+		}
+		return f.False
+	case float64:
+		return f.number(v)
+	case float32:
+		return f.number(float64(v))
+	case primitive.Decimal128:
+		return strings.Replace(v.String(), ".", f.DecimalSeparator, 1)
+	case time.Time:
+		return f.time(v)
+	case primitive.DateTime:
+		return f.time(v.Time())
+	case primitive.ObjectID:
+		return v.Hex()
+	case []interface{}:
+		return f.values(v)
+	case primitive.A:
+		return f.values(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (f DescriptionFormat) number(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", f.DecimalSeparator, 1)
+}
+
+func (f DescriptionFormat) time(v time.Time) string {
+	v = v.UTC()
+	if v.Equal(v.Truncate(24 * time.Hour)) {
+		return v.Format(f.Date)
+	}
+	return v.Format(f.DateTime)
+}
+
+func (f DescriptionFormat) values(values []interface{}) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		if s := f.value(value); s != "" {
+			formatted = append(formatted, s)
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,93 @@
+package sdk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type describedContact struct {
+	Id      string `json:"id" ui-schema:"entityDescriptionTemplate={{surname}}, {{name}} ({{company.name}})"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	Company struct {
+		Name string `json:"name"`
+	} `json:"company"`
+}
+
+type describedProduct struct {
+	Id   string `json:"id"`
+	Name string `json:"name" ui-schema:"entityDescriptionKey=true"`
+}
+
+func TestRootSchema_DescriptionTemplate(t *testing.T) {
+	template := sdk.NewSchema(describedContact{}).DescriptionTemplate()
+	require.NotNil(t, template)
+	assert.Equal(t, []string{"surname", "name", "company.name"}, template.Fields())
+
+	template = sdk.NewSchema(describedProduct{}).DescriptionTemplate()
+	require.NotNil(t, template, "the description key is a template of a single field")
+	assert.Equal(t, []string{"name"}, template.Fields())
+
+	assert.Nil(t, sdk.NewSchema(referenceInvoice{}).DescriptionTemplate())
+}
+
+func TestDescriptionTemplate_Render(t *testing.T) {
+	template := sdk.NewDescriptionTemplate("{{name}} {{surname}} ({{ company.name }})")
+
+	assert.Equal(t, "Mario Rossi (ACME)", template.Render(bson.M{
+		"name":    "Mario",
+		"surname": "Rossi",
+		"company": bson.M{"name": "ACME"},
+	}, sdk.NewDescriptionFormat(nil, "")))
+	assert.Equal(t, "Mario", template.Render(map[string]interface{}{"name": "Mario"}, sdk.NewDescriptionFormat(nil, "")),
+		"the brackets of missing fields are dropped")
+
+	template = sdk.NewDescriptionTemplate("{{surname}}, {{name}} [{{code}}]")
+	assert.Equal(t, "Rossi", template.Render(bson.M{"surname": "Rossi"}, sdk.NewDescriptionFormat(nil, "")),
+		"the separators left at the ends are dropped")
+	assert.Equal(t, "Rossi, Mario [R1]", template.Render(bson.M{"surname": "Rossi", "name": "Mario", "code": "R1"}, sdk.NewDescriptionFormat(nil, "")))
+}
+
+func TestRootSchema_DescriptionTemplateTag(t *testing.T) {
+	type keysFirst struct {
+		Id   string `json:"id" ui-schema:"entityIdKey=true,entityDescriptionTemplate={{code}}, hidden {{name}}"`
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	schema := sdk.NewSchema(keysFirst{})
+	require.NotNil(t, schema.UISchema.EntityDescriptionTemplate)
+	assert.Equal(t, "{{code}}, hidden {{name}}", *schema.UISchema.EntityDescriptionTemplate)
+	assert.Equal(t, "id", *schema.UISchema.EntityIdKey)
+
+	type keysAfter struct {
+		Id string `json:"id" ui-schema:"entityDescriptionTemplate={{code}} {{name}},hidden=true"`
+	}
+	assert.PanicsWithValue(t, `ui-schema: entityDescriptionTemplate must be the last key of the tag "entityDescriptionTemplate={{code}} {{name}},hidden=true"`, func() {
+		sdk.NewSchema(keysAfter{})
+	})
+}
+
+func TestDescriptionTemplate_RenderLocale(t *testing.T) {
+	template := sdk.NewDescriptionTemplate("{{code}} {{date}} {{total}} {{paid}} {{tags}}")
+	document := bson.M{
+		"code":  int32(1200),
+		"date":  primitive.NewDateTimeFromTime(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)),
+		"total": 1234.5,
+		"paid":  true,
+		"tags":  primitive.A{"a", "b"},
+	}
+	translator := sdk_i18n.NewTranslator(nil)
+
+	assert.Equal(t, "1200 17/10/2026 1234,5 sì a, b", template.Render(document, sdk.NewDescriptionFormat(translator, "it")))
+	assert.Equal(t, "1200 10/17/2026 1234.5 yes a, b", template.Render(document, sdk.NewDescriptionFormat(translator, "en")))
+
+	document["date"] = time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, "1200 17/10/2026 09:30 1234,5 sì a, b", template.Render(document, sdk.NewDescriptionFormat(translator, "it")))
+}
@@ -8,7 +8,28 @@ import (
 
 type EndorDIContainerInterface interface {
 	GetRepositories() map[string]EndorRepositoryInterface
+	// GetModule returns the module of the service, the prefix of its entity and action ids.
+	GetModule() string
 	GetTranslator() *sdk_i18n.Translator
+	// InvokeAction runs another action (<module>/<entity>/[<category>/]<action>) in-process,
+	// with the session and context of ctx. The result is the *Response[R] of the action.
+	InvokeAction(ctx EndorContextInterface, actionId string, payload any) (any, error)
+	// AuthorizeAction returns nil if the session may run the action actionId, a 403 error if
+	// it lacks the required permissions and a 404 error if the action does not exist.
+	AuthorizeAction(session Session, actionId string) error
+	// GetEventBus returns the bus the repositories publish their entity events on.
+	GetEventBus() *EventBus
+	// GetJobManager returns the manager running the async actions.
+	GetJobManager() *JobManager
+	// GetOutbox returns the transactional outbox, nil when it is disabled.
+	GetOutbox() *Outbox
+	// GetWebhookDispatcher returns the dispatcher of the webhooks, nil when they are disabled.
+	GetWebhookDispatcher() *WebhookDispatcher
+	// GetAuditTrail returns the audit trail, nil when it is disabled.
+	GetAuditTrail() *AuditTrail
+	// GetReferenceResolver returns the resolver of the references to the entities of other
+	// modules, nil when every reference is resolved with the local repositories.
+	GetReferenceResolver() ReferenceResolver
 }
 
 type RepositoryFactory func(session Session, container EndorDIContainerInterface) EndorRepositoryInterface
@@ -28,3 +49,18 @@ func GetDynamicRepository[T EntityInstanceInterface](diContainer EndorDIContaine
 	}
 	return repo, nil
 }
+
+// InvokeAction runs another action in-process through the DI container of ctx and returns
+// its typed response. payload may be the action payload type itself or any value with the
+// same JSON representation (e.g. a map).
+func InvokeAction[R any](ctx EndorContextInterface, actionId string, payload any) (*Response[R], error) {
+	result, err := ctx.GetDIContainer().InvokeAction(ctx, actionId, payload)
+	if err != nil {
+		return nil, err
+	}
+	response, ok := result.(*Response[R])
+	if !ok {
+		return nil, NewInternalServerError(fmt.Errorf("action %s returns %T, not %T", actionId, result, response))
+	}
+	return response, nil
+}
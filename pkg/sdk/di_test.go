@@ -0,0 +1,47 @@
+package sdk_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderCreatePayload struct {
+	Code     string `json:"code" schema:"minLength=3"`
+	Quantity int    `json:"quantity"`
+}
+
+func TestInvokeAction_TypedPayloadAndResponse(t *testing.T) {
+	var received sdk.EndorContext[orderCreatePayload]
+	container := testDIContainer{actions: map[string]sdk.EndorHandlerActionInterface{
+		"sdk/order/create": sdk.NewAction(func(c *sdk.EndorContext[orderCreatePayload]) (*sdk.Response[orderCreatePayload], error) {
+			received = *c
+			return sdk.NewResponseBuilder[orderCreatePayload]().AddData(&c.Payload).Build(), nil
+		}, "create"),
+	}}
+	caller := &sdk.EndorContext[sdk.NoPayload]{Session: sdk.Session{Username: "mario"}, DIContainer: container}
+
+	response, err := sdk.InvokeAction[orderCreatePayload](caller, "sdk/order/create", map[string]any{"code": "ORD-1", "quantity": 2})
+	require.NoError(t, err)
+	assert.Equal(t, orderCreatePayload{Code: "ORD-1", Quantity: 2}, *response.Data)
+	assert.Equal(t, "mario", received.Session.Username)
+	assert.Equal(t, "sdk/order/create", received.ActionId)
+	assert.Equal(t, "sdk", received.MicroServiceId)
+
+	response, err = sdk.InvokeAction[orderCreatePayload](caller, "sdk/order/create", orderCreatePayload{Code: "ORD-2"})
+	require.NoError(t, err)
+	assert.Equal(t, "ORD-2", response.Data.Code)
+
+	_, err = sdk.InvokeAction[orderCreatePayload](caller, "sdk/order/create", map[string]any{"code": "X"})
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusBadRequest, endorError.StatusCode)
+	assert.Equal(t, "sdk.validation.min_length", endorError.TranslationKey)
+
+	_, err = sdk.InvokeAction[string](caller, "sdk/order/create", orderCreatePayload{Code: "ORD-3"})
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusInternalServerError, endorError.StatusCode)
+}
@@ -1,12 +1,17 @@
 package sdk
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"path"
 	"reflect"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
 )
 
@@ -16,6 +21,12 @@ type EndorHandlerActionInterface interface {
 	CreateHTTPCallback(microserviceId string, entity string, action string, category string, session Session, container EndorDIContainerInterface) func(c *gin.Context)
 	GetOptions() EndorHandlerActionOptions
 	Invoke(ctx any) (any, error)
+	// InvokeWithPayload runs the action on behalf of parent, reusing its session, context and
+	// logger. A payload of the action payload type is used as is; any other value is converted
+	// through JSON and validated against the input schema.
+	InvokeWithPayload(parent EndorContextInterface, actionId string, payload any, container EndorDIContainerInterface) (any, error)
+	WithMiddlewares(middlewares ...EndorActionMiddleware) EndorHandlerActionInterface
+	WithRequiredPermissions(permissions ...string) EndorHandlerActionInterface
 }
 
 type EndorHandlerActionOptions struct {
@@ -23,6 +34,17 @@ type EndorHandlerActionOptions struct {
 	Public                bool
 	SkipPayloadValidation bool
 	InputSchema           *RootSchema
+	Middlewares           []EndorActionMiddleware
+	// Timeout, if set, cancels the context of the action (and of its repository calls)
+	// once elapsed and answers with 504. It bounds the writing of streamed responses too,
+	// which then end with a Fatal message.
+	Timeout time.Duration
+	// RequiredPermissions must all be granted by the session (e.g. "order:write"),
+	// otherwise the action is rejected with 403 before the handler runs.
+	RequiredPermissions []string
+	// ReadOnly declares that the action does not change state: it is not recorded in the
+	// audit trail.
+	ReadOnly bool
 }
 
 type EndorHandler struct {
@@ -33,6 +55,10 @@ type EndorHandler struct {
 	Priority            *int
 	EntitySchema        RootSchema
 	RepositoryFactories map[string]RepositoryFactory
+	Schedules           []Schedule
+	EventSubscriptions  []EventSubscription
+	// Webhooks are declared by the webhooks section of the DSL entities.
+	Webhooks []Webhook
 }
 
 func (h EndorHandler) GetEntity() string {
@@ -70,9 +96,48 @@ func NewConfigurableAction[T any, R any](options EndorHandlerActionOptions, hand
 	return &endorHandlerActionImpl[T, R]{handler: handler, options: options}
 }
 
+// EndorAsyncHandlerFunc is the handler of an async action. It runs in the job worker pool
+// after the request has been answered, so c has no GinContext; c.Context() is cancelled
+// when the job is cancelled.
+type EndorAsyncHandlerFunc[T any, R any] func(c *EndorContext[T], job *JobHandle) (*Response[R], error)
+
+// NewAsyncAction creates an action that answers at once with the pending Job and runs the
+// handler in background; its response becomes the job result, polled through the job entity.
+func NewAsyncAction[T any, R any](handler EndorAsyncHandlerFunc[T, R], description string) EndorHandlerActionInterface {
+	return NewAction(func(c *EndorContext[T]) (*Response[Job], error) {
+		var manager *JobManager
+		if c.DIContainer != nil {
+			manager = c.DIContainer.GetJobManager()
+		}
+		if manager == nil {
+			return nil, NewInternalServerError(fmt.Errorf("no job manager to run action %s", c.ActionId))
+		}
+		job, err := manager.Submit(c, func(ctx context.Context, handle *JobHandle) (any, error) {
+			detached := *c
+			detached.GinContext = nil
+			detached.ctx = ctx
+			response, err := handler(&detached, handle)
+			if err == nil && response != nil && detached.DIContainer != nil {
+				response.ResolveTranslations(detached.ResolveTExpr)
+			}
+			return response, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewResponseBuilder[Job]().
+			AddData(job).
+			AddMessage(NewMessage(ResponseMessageGravityInfo, c.T("sdk.job.messages.queued", map[string]any{"id": job.Id}))).
+			Build(), nil
+	}, description)
+}
+
 type endorHandlerActionImpl[T any, R any] struct {
-	handler EndorHandlerFunc[T, R]
-	options EndorHandlerActionOptions
+	handler     EndorHandlerFunc[T, R]
+	options     EndorHandlerActionOptions
+	middlewares []EndorActionMiddleware
+	// permissions inherited from the entity or category the action belongs to
+	permissions []string
 }
 
 func (m *endorHandlerActionImpl[T, R]) CreateHTTPCallback(microserviceId string, entity string, action string, categoryType string, session Session, container EndorDIContainerInterface) func(c *gin.Context) {
@@ -93,46 +158,152 @@ func (m *endorHandlerActionImpl[T, R]) CreateHTTPCallback(microserviceId string,
 
 		ec := &EndorContext[T]{
 			MicroServiceId: microserviceId,
+			ActionId:       path.Join(microserviceId, entity, categoryType, action),
 			Session:        session,
 			GinContext:     c,
 			Logger:         *logger,
 			CategoryType:   categoryType,
 			DIContainer:    container,
 		}
+		if err := m.authorize(ec); err != nil {
+			writeErrorResponse(c, ec, logger, err)
+			return
+		}
 		var t T
 		if !m.options.SkipPayloadValidation && reflect.TypeOf(t) != reflect.TypeOf(NoPayload{}) {
-			if err := c.ShouldBindJSON(&ec.Payload); err != nil {
-				//TODO: implements JSON Schema validation
+			body, err := c.GetRawData()
+			if err != nil {
 				logger.ErrorWithStackTrace(err)
 				c.AbortWithStatusJSON(http.StatusBadRequest, NewDefaultResponseBuilder().AddMessage(NewMessage(ResponseMessageGravityFatal, err.Error())).Build())
 				return
 			}
-		}
-		// call method
-		response, err := m.handler(ec)
-		if err != nil {
-			var endorError *EndorError
-			if errors.As(err, &endorError) {
-				logger.ErrorWithStackTrace(endorError)
-				message := endorError.Error()
-				if endorError.TranslationKey != "" {
-					message = ec.T(endorError.TranslationKey, endorError.TranslationArgs)
+			if violations := m.options.InputSchema.ValidateJSON(body); len(violations) > 0 {
+				response := NewDefaultResponseBuilder()
+				for _, violation := range violations {
+					response.AddMessage(NewMessage(ResponseMessageGravityError, ec.T(violation.TranslationKey, violation.TranslationArgs)))
 				}
-				c.JSON(endorError.StatusCode, NewDefaultResponseBuilder().AddMessage(NewMessage(ResponseMessageGravityFatal, message)).Build())
-			} else {
+				logger.WarnWithFields("Payload validation failed", map[string]interface{}{"violations": len(violations)})
+				c.AbortWithStatusJSON(http.StatusBadRequest, response.Build())
+				return
+			}
+			if err := binding.JSON.BindBody(body, &ec.Payload); err != nil {
 				logger.ErrorWithStackTrace(err)
-				c.JSON(http.StatusInternalServerError, NewDefaultResponseBuilder().AddMessage(NewMessage(ResponseMessageGravityFatal, err.Error())).Build())
+				c.AbortWithStatusJSON(http.StatusBadRequest, NewDefaultResponseBuilder().AddMessage(NewMessage(ResponseMessageGravityFatal, err.Error())).Build())
+				return
 			}
+		}
+		// the timeout bounds the whole action, the writing of a streamed response included
+		streamCtx := ec.Context()
+		var streamTimeoutErr error
+		if m.options.Timeout > 0 {
+			var cancel context.CancelFunc
+			streamCtx, cancel = context.WithTimeout(streamCtx, m.options.Timeout)
+			defer cancel()
+			streamTimeoutErr = m.timeoutError(ec)
+		}
+		// call method
+		response, err := m.execute(ec)
+		if err != nil {
+			writeErrorResponse(c, ec, logger, err)
+			return
+		}
+		for key, value := range response.Headers() {
+			c.Header(key, value)
+		}
+		response.ResolveTranslations(ec.ResolveTExpr)
+		if response.IsStream() {
+			writeStreamResponse(c, ec, streamCtx, logger, microserviceId, response, streamTimeoutErr)
 		} else {
-			response.ResolveTranslations(ec.ResolveTExpr)
 			c.Header("X-Endor-Microservice", microserviceId)
 			c.JSON(http.StatusOK, response)
 		}
 	}
 }
 
+func writeErrorResponse[T any](c *gin.Context, ec *EndorContext[T], logger *Logger, err error) {
+	var endorError *EndorError
+	if errors.As(err, &endorError) {
+		logger.ErrorWithStackTrace(endorError)
+		message := endorError.Error()
+		if endorError.TranslationKey != "" {
+			message = ec.T(endorError.TranslationKey, endorError.TranslationArgs)
+		}
+		c.JSON(endorError.StatusCode, NewDefaultResponseBuilder().AddMessage(NewMessage(ResponseMessageGravityFatal, message)).Build())
+	} else {
+		logger.ErrorWithStackTrace(err)
+		c.JSON(http.StatusInternalServerError, NewDefaultResponseBuilder().AddMessage(NewMessage(ResponseMessageGravityFatal, err.Error())).Build())
+	}
+}
+
+// execute runs the action within its timeout, if any.
+func (m *endorHandlerActionImpl[T, R]) execute(ec *EndorContext[T]) (*Response[R], error) {
+	if m.options.Timeout <= 0 {
+		return m.runRecovered(ec)
+	}
+	ctx, cancel := context.WithTimeout(ec.Context(), m.options.Timeout)
+	defer cancel()
+	// the handler keeps running on its own copy of the context after a timeout, when gin
+	// has already recycled its context: the copy only keeps a snapshot of the headers
+	timed := *ec
+	timed.ctx = ctx
+	if ec.GinContext != nil && ec.GinContext.Request != nil {
+		timed.headers = ec.GinContext.Request.Header.Clone()
+	}
+	timed.GinContext = nil
+
+	type result struct {
+		response *Response[R]
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := m.runRecovered(&timed)
+		done <- result{response, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && errors.Is(res.err, context.DeadlineExceeded) {
+			return nil, m.timeoutError(ec)
+		}
+		return res.response, res.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, m.timeoutError(ec)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// runRecovered runs the chain, turning a panic of the handler into an internal error.
+func (m *endorHandlerActionImpl[T, R]) runRecovered(ec *EndorContext[T]) (response *Response[R], err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			response, err = nil, NewInternalServerError(fmt.Errorf("action %s panicked: %v", ec.ActionId, r))
+		}
+	}()
+	return m.runChain(ec)
+}
+
+func (m *endorHandlerActionImpl[T, R]) timeoutError(ec *EndorContext[T]) error {
+	return NewGatewayTimeoutError(fmt.Errorf("action %s timed out after %s", ec.ActionId, m.options.Timeout)).WithTranslation("sdk.commons.timeout", map[string]any{
+		"action":  ec.ActionId,
+		"timeout": m.options.Timeout.String(),
+	})
+}
+
+// authorize checks the session against the permissions required by the action.
+func (m *endorHandlerActionImpl[T, R]) authorize(ec *EndorContext[T]) error {
+	return ec.Session.AuthorizeAction(ec.ActionId, m.GetOptions().RequiredPermissions)
+}
+
+// GetOptions returns the action options; RequiredPermissions include the inherited ones.
 func (m *endorHandlerActionImpl[T, R]) GetOptions() EndorHandlerActionOptions {
-	return m.options
+	options := m.options
+	if len(m.permissions) > 0 {
+		options.RequiredPermissions = mergePermissions(m.options.RequiredPermissions, m.permissions)
+	}
+	return options
 }
 
 func (m *endorHandlerActionImpl[T, R]) Invoke(ctx any) (any, error) {
@@ -140,7 +311,60 @@ func (m *endorHandlerActionImpl[T, R]) Invoke(ctx any) (any, error) {
 	if !ok {
 		return nil, fmt.Errorf("invoke: incompatible context type %T", ctx)
 	}
-	return m.handler(ec)
+	if err := m.authorize(ec); err != nil {
+		return nil, err
+	}
+	return m.execute(ec)
+}
+
+func (m *endorHandlerActionImpl[T, R]) InvokeWithPayload(parent EndorContextInterface, actionId string, payload any, container EndorDIContainerInterface) (any, error) {
+	microServiceId, _, categoryType, _, err := ParseEntityActionID(actionId)
+	if err != nil {
+		return nil, NewBadRequestError(err)
+	}
+	ec := &EndorContext[T]{
+		MicroServiceId: microServiceId,
+		ActionId:       actionId,
+		Session:        parent.GetSession(),
+		CategoryType:   categoryType,
+		DIContainer:    container,
+		GinContext:     parent.GetGinContext(),
+		Logger:         *parent.GetLogger(),
+		ctx:            parent.Context(),
+	}
+	if typed, ok := payload.(T); ok {
+		ec.Payload = typed
+	} else if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, NewBadRequestError(err)
+		}
+		if !m.options.SkipPayloadValidation {
+			if violations := m.options.InputSchema.ValidateJSON(body); len(violations) > 0 {
+				first := violations[0]
+				return nil, NewBadRequestError(fmt.Errorf("invalid payload for action %s: %s %s", actionId, first.Field(), first.TranslationKey)).WithTranslation(first.TranslationKey, first.TranslationArgs)
+			}
+		}
+		if err := json.Unmarshal(body, &ec.Payload); err != nil {
+			return nil, NewBadRequestError(err)
+		}
+	}
+	if err := m.authorize(ec); err != nil {
+		return nil, err
+	}
+	return m.execute(ec)
+}
+
+func (m *endorHandlerActionImpl[T, R]) WithMiddlewares(middlewares ...EndorActionMiddleware) EndorHandlerActionInterface {
+	wrapped := *m
+	wrapped.middlewares = append(append([]EndorActionMiddleware{}, middlewares...), m.middlewares...)
+	return &wrapped
+}
+
+func (m *endorHandlerActionImpl[T, R]) WithRequiredPermissions(permissions ...string) EndorHandlerActionInterface {
+	wrapped := *m
+	wrapped.permissions = append(append([]string{}, m.permissions...), permissions...)
+	return &wrapped
 }
 
 // generic
@@ -157,6 +381,11 @@ type EndorBaseHandlerInterface interface {
 	EndorHandlerInterface
 	WithExtendedDescription(description string) EndorBaseHandlerInterface
 	WithPriority(priority int) EndorBaseHandlerInterface
+	WithMiddlewares(middlewares ...EndorActionMiddleware) EndorBaseHandlerInterface
+	// WithSchedules invokes actions of the handler periodically with the system session.
+	WithSchedules(schedules ...Schedule) EndorBaseHandlerInterface
+	// WithEventSubscriptions reacts to the entity events published by the repositories.
+	WithEventSubscriptions(subscriptions ...EventSubscription) EndorBaseHandlerInterface
 	WithActions(actions map[string]EndorHandlerActionInterface) EndorBaseHandlerInterface
 	WithRepository(fn RepositoryFactory) EndorBaseHandlerInterface
 	ToEndorHandler() EndorHandler
@@ -167,6 +396,11 @@ type EndorBaseSpecializedHandlerInterface interface {
 	EndorHandlerInterface
 	WithExtendedDescription(description string) EndorBaseSpecializedHandlerInterface
 	WithPriority(priority int) EndorBaseSpecializedHandlerInterface
+	WithMiddlewares(middlewares ...EndorActionMiddleware) EndorBaseSpecializedHandlerInterface
+	// WithSchedules invokes actions of the handler periodically with the system session.
+	WithSchedules(schedules ...Schedule) EndorBaseSpecializedHandlerInterface
+	// WithEventSubscriptions reacts to the entity events published by the repositories.
+	WithEventSubscriptions(subscriptions ...EventSubscription) EndorBaseSpecializedHandlerInterface
 	WithActions(actions map[string]EndorHandlerActionInterface) EndorBaseSpecializedHandlerInterface
 	WithCategories(categories []EndorBaseSpecializedHandlerCategoryInterface) EndorBaseSpecializedHandlerInterface
 	WithRepository(fn RepositoryFactory) EndorBaseSpecializedHandlerInterface
@@ -181,7 +415,9 @@ type EndorBaseSpecializedHandlerCategoryInterface interface {
 	GetSchema() string
 	GetActions() map[string]EndorHandlerActionInterface
 	GetRepository() RepositoryFactory
+	GetRequiredPermissions() []string
 	WithExtendedDescription(description string) EndorBaseSpecializedHandlerCategoryInterface
+	WithRequiredPermissions(permissions ...string) EndorBaseSpecializedHandlerCategoryInterface
 	WithActions(actions map[string]EndorHandlerActionInterface) EndorBaseSpecializedHandlerCategoryInterface
 	WithRepository(fn RepositoryFactory) EndorBaseSpecializedHandlerCategoryInterface
 }
@@ -191,6 +427,20 @@ type EndorHybridHandlerInterface interface {
 	EndorHandlerInterface
 	WithExtendedDescription(description string) EndorHybridHandlerInterface
 	WithPriority(priority int) EndorHybridHandlerInterface
+	WithMiddlewares(middlewares ...EndorActionMiddleware) EndorHybridHandlerInterface
+	// WithSchedules invokes actions of the handler periodically with the system session.
+	WithSchedules(schedules ...Schedule) EndorHybridHandlerInterface
+	// WithEventSubscriptions reacts to the entity events published by the repositories.
+	WithEventSubscriptions(subscriptions ...EventSubscription) EndorHybridHandlerInterface
+	// WithVersioning enables optimistic concurrency on the instances of the entity (see
+	// EntityInstanceRepositoryOptions.Versioned).
+	WithVersioning() EndorHybridHandlerInterface
+	// WithSoftDelete keeps the deleted instances, hidden from reads, and adds the trash and
+	// restore actions (see EntityInstanceRepositoryOptions.SoftDelete).
+	WithSoftDelete() EndorHybridHandlerInterface
+	// WithHistory records the versions of the instances and adds the history, version and
+	// revert actions (see EntityInstanceRepositoryOptions.History).
+	WithHistory() EndorHybridHandlerInterface
 	WithActions(fn func(getSchema func() RootSchema) map[string]EndorHandlerActionInterface) EndorHybridHandlerInterface
 	ToEndorHandler(metadataSchema RootSchema) EndorHandler
 }
@@ -200,6 +450,20 @@ type EndorHybridSpecializedHandlerInterface interface {
 	EndorHandlerInterface
 	WithExtendedDescription(description string) EndorHybridSpecializedHandlerInterface
 	WithPriority(priority int) EndorHybridSpecializedHandlerInterface
+	WithMiddlewares(middlewares ...EndorActionMiddleware) EndorHybridSpecializedHandlerInterface
+	// WithSchedules invokes actions of the handler periodically with the system session.
+	WithSchedules(schedules ...Schedule) EndorHybridSpecializedHandlerInterface
+	// WithEventSubscriptions reacts to the entity events published by the repositories.
+	WithEventSubscriptions(subscriptions ...EventSubscription) EndorHybridSpecializedHandlerInterface
+	// WithVersioning enables optimistic concurrency on the instances of the entity (see
+	// EntityInstanceRepositoryOptions.Versioned).
+	WithVersioning() EndorHybridSpecializedHandlerInterface
+	// WithSoftDelete keeps the deleted instances, hidden from reads, and adds the trash and
+	// restore actions (see EntityInstanceRepositoryOptions.SoftDelete).
+	WithSoftDelete() EndorHybridSpecializedHandlerInterface
+	// WithHistory records the versions of the instances and adds the history, version and
+	// revert actions (see EntityInstanceRepositoryOptions.History).
+	WithHistory() EndorHybridSpecializedHandlerInterface
 	WithActions(fn func(getSchema func() RootSchema) map[string]EndorHandlerActionInterface) EndorHybridSpecializedHandlerInterface
 	WithHybridCategories(categories []EndorHybridSpecializedHandlerCategoryInterface) EndorHybridSpecializedHandlerInterface
 	GetHybridCategories() []Category
@@ -213,7 +477,9 @@ type EndorHybridSpecializedHandlerCategoryInterface interface {
 	GetSchema() string
 	GetActions() func(getSchema func() RootSchema) map[string]EndorHandlerActionInterface
 	GetRepository() RepositoryFactory
+	GetRequiredPermissions() []string
 	WithExtendedDescription(description string) EndorHybridSpecializedHandlerCategoryInterface
+	WithRequiredPermissions(permissions ...string) EndorHybridSpecializedHandlerCategoryInterface
 	WithActions(actionFn func(getSchema func() RootSchema) map[string]EndorHandlerActionInterface) EndorHybridSpecializedHandlerCategoryInterface
 	CreateDefaultActions(entity string, entityDescription string, metadataSchema RootSchema, categoryMetadataSchema RootSchema) map[string]EndorHandlerActionInterface
 }
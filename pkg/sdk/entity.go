@@ -11,6 +11,8 @@ type Category struct {
 	Title       string `json:"title" schema:"title=${t.sdk.entity.fields.category.title},readOnly=true"`
 	Description string `json:"description" schema:"title=${t.sdk.entity.fields.category.description},readOnly=true"`
 	Schema      string `json:"schema" schema:"title=${t.sdk.entity.fields.category.schema},format=yaml,readOnly=true"`
+	// RequiredPermissions are required by every action of the category.
+	RequiredPermissions []string `json:"requiredPermissions,omitempty" schema:"title=${t.sdk.entity.fields.category.required_permissions},readOnly=true"`
 }
 
 type EntityType string
@@ -34,6 +36,8 @@ type Entity struct {
 	Module      string     `json:"module" schema:"title=${t.sdk.entity.fields.module},readOnly=true" ui-schema:"entity=core/module"`
 	Schema      string     `json:"schema" schema:"title=${t.sdk.entity.fields.schema},format=yaml,readOnly=true"`
 	Categories  []Category `json:"categories,omitempty" bson:"categories,omitempty" schema:"title=${t.sdk.entity.fields.categories},readOnly=true"`
+	// RequiredPermissions are required by every action of the entity.
+	RequiredPermissions []string `json:"requiredPermissions,omitempty" bson:"requiredPermissions,omitempty" schema:"title=${t.sdk.entity.fields.required_permissions},readOnly=true"`
 }
 
 func (h *Entity) GetID() any {
@@ -44,10 +48,11 @@ func (h *Entity) GetID() any {
 
 type EntityAction struct {
 	// module/version/entity/action
-	ID          string `json:"id" schema:"title=${t.sdk.entity_action.fields.id}"`
-	Entity      string `json:"entity" schema:"title=${t.sdk.entity_action.fields.entity}" ui-schema:"core/entity"`
-	Description string `json:"description" schema:"title=${t.sdk.entity_action.fields.description}"`
-	InputSchema string `json:"inputSchema" schema:"title=${t.sdk.entity_action.fields.input_schema},format=yaml"`
+	ID                  string   `json:"id" schema:"title=${t.sdk.entity_action.fields.id}"`
+	Entity              string   `json:"entity" schema:"title=${t.sdk.entity_action.fields.entity}" ui-schema:"core/entity"`
+	Description         string   `json:"description" schema:"title=${t.sdk.entity_action.fields.description}"`
+	InputSchema         string   `json:"inputSchema" schema:"title=${t.sdk.entity_action.fields.input_schema},format=yaml"`
+	RequiredPermissions []string `json:"requiredPermissions,omitempty" schema:"title=${t.sdk.entity_action.fields.required_permissions}"`
 }
 
 func (h *EntityAction) GetID() any {
@@ -83,6 +88,7 @@ type EntityRepositoryInterface interface {
 	List() ([]Entity, error)
 	Instance(dto ReadInstanceDTO) (*Entity, error)
 	FindReferences(ctx context.Context, ids ReadInstancesDTO) (EntityReferenceGroupDescriptions, error)
+	FindDocuments(ctx context.Context, dto ReadInstancesDTO) (map[string]map[string]interface{}, error)
 	GetEntity() string
 	RawList(ctx context.Context, dto ReadDTO) ([]map[string]interface{}, error)
 	GetSchema() *RootSchema
@@ -53,6 +53,10 @@ func NewUnauthorizedError(err error) *EndorError {
 	return &EndorError{StatusCode: http.StatusUnauthorized, InternalErr: err}
 }
 
+func NewGatewayTimeoutError(err error) *EndorError {
+	return &EndorError{StatusCode: http.StatusGatewayTimeout, InternalErr: err}
+}
+
 func NewGenericError(status int, err error) *EndorError {
 	return &EndorError{StatusCode: status, InternalErr: err}
 }
@@ -0,0 +1,234 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EntityEventType string
+
+const (
+	EntityEventCreated EntityEventType = "created"
+	EntityEventUpdated EntityEventType = "updated"
+	EntityEventDeleted EntityEventType = "deleted"
+)
+
+// EntityEvent describes a successful write of an entity instance. Before is nil for
+// created instances, After is nil for deleted ones.
+type EntityEvent struct {
+	Id         string          `json:"id"`
+	Type       EntityEventType `json:"type"`
+	EntityId   string          `json:"entityId"`
+	Category   string          `json:"category,omitempty"`
+	InstanceId string          `json:"instanceId"`
+	Before     map[string]any  `json:"before,omitempty"`
+	After      map[string]any  `json:"after,omitempty"`
+	// Session is the session of the write.
+	Session    Session   `json:"session"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// NewEntityEvent builds an event for the write of instanceId happened now. The access token
+// is removed from the session, since events leave the service (e.g. through the outbox).
+func NewEntityEvent(eventType EntityEventType, entityId string, category string, instanceId string, before map[string]any, after map[string]any, session Session) EntityEvent {
+	session.AccessToken = ""
+	return EntityEvent{
+		Id:         primitive.NewObjectID().Hex(),
+		Type:       eventType,
+		EntityId:   entityId,
+		Category:   category,
+		InstanceId: instanceId,
+		Before:     before,
+		After:      after,
+		Session:    session,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// EntityEventHandler handles an event; c.Payload is the event and c.Session the session
+// of the write.
+type EntityEventHandler func(c *EndorContext[EntityEvent]) error
+
+const (
+	defaultEventMaxAttempts = 3
+	defaultEventRetryDelay  = 200 * time.Millisecond
+)
+
+// EventSubscription subscribes to the events of an entity. Handlers declare it with
+// WithEventSubscriptions and DSL entities in the events section.
+type EventSubscription struct {
+	// Entity is the observed entity, the declaring one if empty.
+	Entity string `json:"entity,omitempty" yaml:"entity,omitempty"`
+	// Events are the observed event types, every type if empty.
+	Events []EntityEventType `json:"events,omitempty" yaml:"events,omitempty"`
+	// Action is the action of the declaring entity invoked with the event as payload,
+	// used when Handler is nil (e.g. by DSL entities).
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Async delivers the event after the write returns, retrying failures.
+	Async bool `json:"async,omitempty" yaml:"async,omitempty"`
+	// MaxAttempts bounds the deliveries of an async event (default 3).
+	MaxAttempts int `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+	// RetryDelay is the wait before the second attempt, doubled at every further one
+	// (default 200ms).
+	RetryDelay time.Duration      `json:"retryDelay,omitempty" yaml:"retryDelay,omitempty"`
+	Handler    EntityEventHandler `json:"-" yaml:"-"`
+}
+
+func (s EventSubscription) matches(event EntityEvent) bool {
+	return s.Entity == event.EntityId && (len(s.Events) == 0 || slices.Contains(s.Events, event.Type))
+}
+
+// EventBus dispatches the entity events published by the repositories of a DI container.
+// See docs/EVENTS.md for the delivery guarantees.
+type EventBus struct {
+	mu             sync.RWMutex
+	subscriptions  map[int]EventSubscription
+	nextId         int
+	microServiceId string
+	container      EndorDIContainerInterface
+	logger         *Logger
+	pending        sync.WaitGroup
+}
+
+// NewEventBus creates the bus of container: the handlers receive it in their context.
+func NewEventBus(microServiceId string, container EndorDIContainerInterface, logger *Logger) *EventBus {
+	if logger == nil {
+		logger = NewLogger(LogConfig{}, LogContext{})
+	}
+	return &EventBus{
+		subscriptions:  map[int]EventSubscription{},
+		microServiceId: microServiceId,
+		container:      container,
+		logger:         logger,
+	}
+}
+
+// Subscribe adds the subscription and returns the function that removes it.
+func (b *EventBus) Subscribe(subscription EventSubscription) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextId
+	b.nextId++
+	b.subscriptions[id] = subscription
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscriptions, id)
+	}
+}
+
+// HasSubscribers reports whether any subscription observes entityId, so that repositories
+// can skip building the events nobody receives.
+func (b *EventBus) HasSubscribers(entityId string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, subscription := range b.subscriptions {
+		if subscription.Entity == entityId {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish runs the synchronous subscribers in subscription order and returns their errors;
+// the asynchronous ones are started in background and never fail the publisher.
+func (b *EventBus) Publish(ctx context.Context, event EntityEvent) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	ids := make([]int, 0, len(b.subscriptions))
+	for id, subscription := range b.subscriptions {
+		if subscription.matches(event) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	subscriptions := make([]EventSubscription, 0, len(ids))
+	for _, id := range ids {
+		subscriptions = append(subscriptions, b.subscriptions[id])
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, subscription := range subscriptions {
+		if subscription.Async {
+			b.pending.Add(1)
+			go func() {
+				defer b.pending.Done()
+				b.deliverWithRetry(context.WithoutCancel(ctx), subscription, event)
+			}()
+			continue
+		}
+		if err := b.deliver(ctx, subscription, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PublishCommitted publishes the event of a write already saved, as the repositories do:
+// the write can no longer be undone, so the errors of the synchronous subscribers are
+// logged instead of being returned to the writer. Checks that must be able to reject a
+// write belong to the action, before the write.
+func (b *EventBus) PublishCommitted(ctx context.Context, event EntityEvent) {
+	if err := b.Publish(ctx, event); err != nil {
+		b.logger.Error(fmt.Sprintf("event %s (%s %s/%s): subscribers failed after the write: %s", event.Id, event.Type, event.EntityId, event.InstanceId, err.Error()))
+	}
+}
+
+// Wait blocks until the asynchronous deliveries in progress are completed.
+func (b *EventBus) Wait() {
+	b.pending.Wait()
+}
+
+func (b *EventBus) deliverWithRetry(ctx context.Context, subscription EventSubscription, event EntityEvent) {
+	attempts := subscription.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultEventMaxAttempts
+	}
+	delay := subscription.RetryDelay
+	if delay <= 0 {
+		delay = defaultEventRetryDelay
+	}
+	for attempt := 1; ; attempt++ {
+		err := b.deliver(ctx, subscription, event)
+		if err == nil {
+			return
+		}
+		if attempt == attempts {
+			b.logger.Error(fmt.Sprintf("event %s (%s %s/%s) dropped after %d attempts: %s", event.Id, event.Type, event.EntityId, event.InstanceId, attempts, err.Error()))
+			return
+		}
+		b.logger.Warn(fmt.Sprintf("event %s (%s %s/%s) delivery failed, retrying: %s", event.Id, event.Type, event.EntityId, event.InstanceId, err.Error()))
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// deliver runs the subscription turning a panic into an error.
+func (b *EventBus) deliver(ctx context.Context, subscription EventSubscription, event EntityEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewInternalServerError(fmt.Errorf("event subscriber panicked: %v", r))
+		}
+	}()
+	ec := &EndorContext[EntityEvent]{
+		MicroServiceId: b.microServiceId,
+		Session:        event.Session,
+		Payload:        event,
+		DIContainer:    b.container,
+		Logger:         *b.logger,
+		ctx:            ctx,
+	}
+	return subscription.Handler(ec)
+}
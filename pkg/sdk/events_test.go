@@ -0,0 +1,121 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOrderEvent(eventType sdk.EntityEventType) sdk.EntityEvent {
+	return sdk.NewEntityEvent(eventType, "order", "", "order-1", nil, map[string]any{"code": "A"}, sdk.Session{UserId: "user-1"})
+}
+
+func TestEventBus_SyncSubscribersInOrder(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	var calls []string
+	bus.Subscribe(sdk.EventSubscription{Entity: "order", Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		calls = append(calls, "first:"+string(c.Payload.Type)+":"+c.Session.UserId)
+		return nil
+	}})
+	bus.Subscribe(sdk.EventSubscription{Entity: "order", Events: []sdk.EntityEventType{sdk.EntityEventDeleted}, Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		calls = append(calls, "deleted")
+		return errors.New("not allowed")
+	}})
+	bus.Subscribe(sdk.EventSubscription{Entity: "customer", Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		calls = append(calls, "customer")
+		return nil
+	}})
+
+	assert.True(t, bus.HasSubscribers("order"))
+	assert.False(t, bus.HasSubscribers("invoice"))
+
+	require.NoError(t, bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	assert.Equal(t, []string{"first:created:user-1"}, calls)
+
+	err := bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventDeleted))
+	assert.EqualError(t, err, "not allowed")
+	assert.Equal(t, []string{"first:created:user-1", "first:deleted:user-1", "deleted"}, calls)
+}
+
+func TestEventBus_PublishCommittedRunsEverySubscriber(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	var calls []string
+	bus.Subscribe(sdk.EventSubscription{Entity: "order", Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		calls = append(calls, "failing")
+		return errors.New("not allowed")
+	}})
+	bus.Subscribe(sdk.EventSubscription{Entity: "order", Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		calls = append(calls, "second")
+		return nil
+	}})
+
+	// the errors are logged: there is nothing to return them to
+	bus.PublishCommitted(context.Background(), newOrderEvent(sdk.EntityEventCreated))
+	assert.Equal(t, []string{"failing", "second"}, calls)
+}
+
+func TestEventBus_AsyncRetries(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	var attempts atomic.Int32
+	bus.Subscribe(sdk.EventSubscription{Entity: "order", Async: true, RetryDelay: time.Millisecond, Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("temporarily unavailable")
+		}
+		return nil
+	}})
+	var dropped atomic.Int32
+	bus.Subscribe(sdk.EventSubscription{Entity: "order", Async: true, MaxAttempts: 2, RetryDelay: time.Millisecond, Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		dropped.Add(1)
+		panic("broken subscriber")
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, bus.Publish(ctx, newOrderEvent(sdk.EntityEventUpdated)))
+	// the async delivery outlives the context of the write
+	cancel()
+	bus.Wait()
+
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, int32(2), dropped.Load())
+}
+
+func TestEventBus_Unsubscribe(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	var mu sync.Mutex
+	received := 0
+	unsubscribe := bus.Subscribe(sdk.EventSubscription{Entity: "order", Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received++
+		return nil
+	}})
+
+	require.NoError(t, bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	unsubscribe()
+	require.NoError(t, bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	assert.Equal(t, 1, received)
+	assert.False(t, bus.HasSubscribers("order"))
+}
+
+func TestEventBus_NilBusIsNoop(t *testing.T) {
+	var bus *sdk.EventBus
+	assert.False(t, bus.HasSubscribers("order"))
+	assert.NoError(t, bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+}
+
+func TestEndorContext_ContextCarriesSession(t *testing.T) {
+	ec := &sdk.EndorContext[sdk.NoPayload]{Session: sdk.Session{UserId: "user-1"}}
+	session, ok := sdk.SessionFromContext(ec.Context())
+	require.True(t, ok)
+	assert.Equal(t, "user-1", session.UserId)
+
+	_, ok = sdk.SessionFromContext(context.Background())
+	assert.False(t, ok)
+}
@@ -0,0 +1,266 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// MaxExpandDepth is the maximum ReadDTO.ExpandDepth.
+const MaxExpandDepth = 3
+
+// EntityExpansionGroup contains for each referenced entity (as declared by UISchema.Entity)
+// the expanded documents by id.
+type EntityExpansionGroup map[string]map[string]map[string]interface{}
+
+// ExpandOptions selects the references to expand (see ReadDTO.Expand).
+type ExpandOptions struct {
+	// Fields are the paths of the reference fields to expand, in dot notation.
+	Fields []string
+	// Depth is the number of levels to expand, 1 when 0.
+	Depth int
+	// Projection restricts the documents of an entity to the given top-level fields.
+	Projection map[string][]string
+}
+
+// expandReference is a reference field found in a schema.
+type expandReference struct {
+	entity string
+	path   []string
+}
+
+// ExpandReferences returns the documents referenced by the fields of data (an instance or
+// a list of instances, as returned in Response.Data) selected by options. The documents
+// are looked up with one FindDocuments call per entity and level; with a Depth above 1
+// every reference of the expanded documents is expanded too. References to entities
+// without a repository in container, or whose instance action the session of ctx may not
+// run, and ids not found, are left out. The writeOnly and password fields of the
+// documents are redacted, as in the audit trail.
+func ExpandReferences(ctx context.Context, container EndorDIContainerInterface, schema *RootSchema, data any, options ExpandOptions) (EntityExpansionGroup, error) {
+	if len(options.Fields) == 0 || schema == nil {
+		return nil, nil
+	}
+	depth := options.Depth
+	if depth == 0 {
+		depth = 1
+	}
+	if depth < 0 || depth > MaxExpandDepth {
+		return nil, NewBadRequestError(fmt.Errorf("expand depth %d out of range 1-%d", options.Depth, MaxExpandDepth)).WithTranslation("sdk.entity.messages.invalid_expand_depth", map[string]any{
+			"max": MaxExpandDepth,
+		})
+	}
+	references := make([]expandReference, 0, len(options.Fields))
+	for _, field := range options.Fields {
+		path := strings.Split(field, ".")
+		entity, ok := schema.referenceAt(path)
+		if !ok {
+			return nil, NewBadRequestError(fmt.Errorf("%s is not a reference field", field)).WithTranslation("sdk.entity.messages.invalid_expand", map[string]any{
+				"field": field,
+			})
+		}
+		references = append(references, expandReference{entity: entity, path: path})
+	}
+
+	root, err := toJSONDocuments(data)
+	if err != nil {
+		return nil, NewInternalServerError(fmt.Errorf("failed to read the instances to expand: %w", err))
+	}
+	repositories := container.GetRepositories()
+	session, _ := SessionFromContext(ctx)
+	// readable tells, by entity, whether the session may read its instances
+	readable := map[string]bool{}
+	expanded := EntityExpansionGroup{}
+	// level holds the documents whose references are expanded next, with their references
+	level := []expandLevel{{documents: root, references: references}}
+	for i := 0; i < depth && len(level) > 0; i++ {
+		ids := map[string][]string{}
+		for _, l := range level {
+			for _, reference := range l.references {
+				for _, id := range referencedIDs(l.documents, reference.path) {
+					if _, done := expanded[reference.entity][id]; !done && !slices.Contains(ids[reference.entity], id) {
+						ids[reference.entity] = append(ids[reference.entity], id)
+					}
+				}
+			}
+		}
+		next := []expandLevel{}
+		for entity, entityIds := range ids {
+			local, repository, found := repositoryOf(repositories, container.GetModule(), entity)
+			if !found {
+				continue
+			}
+			allowed, checked := readable[local]
+			if !checked {
+				var err error
+				if allowed, err = canReadInstances(container, session, local); err != nil {
+					return nil, err
+				}
+				readable[local] = allowed
+			}
+			if !allowed {
+				continue
+			}
+			fields := options.Projection[entity]
+			documents, err := repository.FindDocuments(ctx, ReadInstancesDTO{Ids: entityIds, Fields: fields})
+			if err != nil {
+				return nil, err
+			}
+			if expanded[entity] == nil {
+				expanded[entity] = map[string]map[string]interface{}{}
+			}
+			entityDocuments := make([]any, 0, len(documents))
+			for id, document := range documents {
+				document = RedactPayload(projectDocument(document, fields), repository.GetSchema())
+				expanded[entity][id] = document
+				entityDocuments = append(entityDocuments, document)
+			}
+			if i+1 < depth {
+				next = append(next, expandLevel{documents: entityDocuments, references: repository.GetSchema().references()})
+			}
+		}
+		level = next
+	}
+	return expanded, nil
+}
+
+// expandLevel is a set of documents of the same schema to expand.
+type expandLevel struct {
+	documents  any
+	references []expandReference
+}
+
+// repositoryOf returns the local name and the repository of entity, qualified
+// ("module/entity") or not. Entities of other modules have no repository.
+func repositoryOf(repositories map[string]EndorRepositoryInterface, module, entity string) (string, EndorRepositoryInterface, bool) {
+	if entityModule, parsed, err := ParseEntityID(entity); err == nil {
+		if entityModule != module {
+			return "", nil, false
+		}
+		entity = parsed
+	}
+	repository, found := repositories[entity]
+	return entity, repository, found
+}
+
+// canReadInstances reports whether session may run the instance action of entity; an
+// entity without it cannot be read either.
+func canReadInstances(container EndorDIContainerInterface, session Session, entity string) (bool, error) {
+	err := container.AuthorizeAction(session, path.Join(container.GetModule(), entity, "instance"))
+	var endorError *EndorError
+	if errors.As(err, &endorError) && (endorError.StatusCode == http.StatusForbidden || endorError.StatusCode == http.StatusNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// referenceAt returns the entity referenced by the field at path, walking nested objects
+// and arrays of objects.
+func (rs *RootSchema) referenceAt(path []string) (string, bool) {
+	s := rs.resolveReference(&rs.Schema)
+	for i, name := range path {
+		if s.Items != nil {
+			s = rs.resolveReference(s.Items)
+		}
+		if s.Properties == nil {
+			return "", false
+		}
+		property, ok := (*s.Properties)[name]
+		if !ok {
+			return "", false
+		}
+		s = rs.resolveReference(&property)
+		if s.UISchema != nil && s.UISchema.Entity != nil {
+			return *s.UISchema.Entity, i == len(path)-1
+		}
+	}
+	return "", false
+}
+
+// references returns every reference field of the schema, nil for a nil schema.
+func (rs *RootSchema) references() []expandReference {
+	if rs == nil {
+		return nil
+	}
+	references := []expandReference{}
+	rs.collectExpandReferences(&rs.Schema, nil, &references, 0)
+	return references
+}
+
+func (rs *RootSchema) collectExpandReferences(s *Schema, prefix []string, references *[]expandReference, depth int) {
+	s = rs.resolveReference(s)
+	if s.Items != nil {
+		s = rs.resolveReference(s.Items)
+	}
+	if s.Properties == nil || depth > maxReferenceDepth {
+		return
+	}
+	for name, property := range *s.Properties {
+		property := rs.resolveReference(&property)
+		path := append(slices.Clone(prefix), name)
+		if property.UISchema != nil && property.UISchema.Entity != nil {
+			*references = append(*references, expandReference{entity: *property.UISchema.Entity, path: path})
+			continue
+		}
+		rs.collectExpandReferences(property, path, references, depth+1)
+	}
+}
+
+// referencedIDs returns the ids held at path by document, a JSON value: arrays are walked
+// element by element, both along the path and at its end (arrays of ids).
+func referencedIDs(document any, path []string) []string {
+	switch value := document.(type) {
+	case nil:
+		return nil
+	case []any:
+		ids := []string{}
+		for _, element := range value {
+			ids = append(ids, referencedIDs(element, path)...)
+		}
+		return ids
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return nil
+		}
+		return referencedIDs(value[path[0]], path[1:])
+	}
+	if len(path) > 0 {
+		return nil
+	}
+	if id := fmt.Sprintf("%v", document); id != "" {
+		return []string{id}
+	}
+	return nil
+}
+
+// projectDocument keeps only the id and the given top-level fields of document, all of
+// them if fields is empty.
+func projectDocument(document map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return document
+	}
+	projected := map[string]interface{}{}
+	for key, value := range document {
+		if key == "id" || slices.Contains(fields, key) {
+			projected[key] = value
+		}
+	}
+	return projected
+}
+
+// toJSONDocuments converts data to its JSON representation (maps and slices).
+func toJSONDocuments(data any) (any, error) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var documents any
+	if err := json.Unmarshal(bytes, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
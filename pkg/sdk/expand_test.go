@@ -0,0 +1,179 @@
+package sdk_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type expandLine struct {
+	ProductId string `json:"productId" ui-schema:"entity=product"`
+	Quantity  int    `json:"quantity"`
+}
+
+type expandOrder struct {
+	Id         string       `json:"id"`
+	CustomerId string       `json:"customerId" ui-schema:"entity=test/customer"`
+	Lines      []expandLine `json:"lines"`
+}
+
+type expandCustomer struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	CompanyId string `json:"companyId" ui-schema:"entity=company"`
+	ApiKey    string `json:"apiKey" schema:"writeOnly=true"`
+}
+
+// expandRepository serves documents by id and records the ids of every lookup.
+type expandRepository struct {
+	referenceRepository
+	documents map[string]map[string]interface{}
+	lookups   [][]string
+}
+
+func (r *expandRepository) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	r.lookups = append(r.lookups, dto.Ids)
+	result := map[string]map[string]interface{}{}
+	for _, id := range dto.Ids {
+		if document, ok := r.documents[id]; ok {
+			result[id] = document
+		}
+	}
+	return result, nil
+}
+
+func newExpandContainer() (referenceContainer, map[string]*expandRepository) {
+	repositories := map[string]*expandRepository{
+		"customer": {
+			referenceRepository: referenceRepository{schema: sdk.NewSchema(expandCustomer{})},
+			documents: map[string]map[string]interface{}{
+				"c1": {"id": "c1", "name": "Mario", "companyId": "k1"},
+				"c2": {"id": "c2", "name": "Anna", "companyId": "k1", "apiKey": "secret"},
+			},
+		},
+		"product": {
+			documents: map[string]map[string]interface{}{
+				"p1": {"id": "p1", "name": "Desk"},
+				"p2": {"id": "p2", "name": "Chair"},
+			},
+		},
+		"company": {
+			documents: map[string]map[string]interface{}{"k1": {"id": "k1", "name": "Acme"}},
+		},
+	}
+	container := referenceContainer{repositories: map[string]sdk.EndorRepositoryInterface{}}
+	for entity, repository := range repositories {
+		container.repositories[entity] = repository
+	}
+	return container, repositories
+}
+
+func TestExpandReferences(t *testing.T) {
+	container, repositories := newExpandContainer()
+	orders := []expandOrder{
+		{Id: "o1", CustomerId: "c1", Lines: []expandLine{{ProductId: "p1"}, {ProductId: "p2"}}},
+		{Id: "o2", CustomerId: "c1", Lines: []expandLine{{ProductId: "p1"}, {ProductId: "p3"}}},
+	}
+
+	expanded, err := sdk.ExpandReferences(context.Background(), container, sdk.NewSchema(expandOrder{}), orders, sdk.ExpandOptions{
+		Fields: []string{"customerId", "lines.productId"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, sdk.EntityExpansionGroup{
+		"test/customer": {"c1": {"id": "c1", "name": "Mario", "companyId": "k1"}},
+		"product": {
+			"p1": {"id": "p1", "name": "Desk"},
+			"p2": {"id": "p2", "name": "Chair"},
+		},
+	}, expanded, "ids not found are left out")
+	assert.Equal(t, [][]string{{"c1"}}, repositories["customer"].lookups)
+	assert.Equal(t, [][]string{{"p1", "p2", "p3"}}, repositories["product"].lookups, "one lookup per entity")
+	assert.Empty(t, repositories["company"].lookups, "the references of the expanded documents need a greater depth")
+}
+
+func TestExpandReferences_Depth(t *testing.T) {
+	container, repositories := newExpandContainer()
+	order := &expandOrder{Id: "o1", CustomerId: "c2"}
+
+	expanded, err := sdk.ExpandReferences(context.Background(), container, sdk.NewSchema(expandOrder{}), order, sdk.ExpandOptions{
+		Fields:     []string{"customerId"},
+		Depth:      2,
+		Projection: map[string][]string{"test/customer": {"name"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, sdk.EntityExpansionGroup{
+		"test/customer": {"c2": {"id": "c2", "name": "Anna"}},
+	}, expanded, "the projection drops the reference to the company")
+	assert.Empty(t, repositories["company"].lookups)
+
+	expanded, err = sdk.ExpandReferences(context.Background(), container, sdk.NewSchema(expandOrder{}), order, sdk.ExpandOptions{
+		Fields: []string{"customerId"},
+		Depth:  2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "k1", "name": "Acme"}, expanded["company"]["k1"])
+}
+
+func TestExpandReferences_Invalid(t *testing.T) {
+	container, _ := newExpandContainer()
+	schema := sdk.NewSchema(expandOrder{})
+
+	for _, field := range []string{"lines", "lines.quantity", "customerId.name", "missing"} {
+		_, err := sdk.ExpandReferences(context.Background(), container, schema, expandOrder{}, sdk.ExpandOptions{Fields: []string{field}})
+		var endorError *sdk.EndorError
+		require.ErrorAs(t, err, &endorError, field)
+		assert.Equal(t, http.StatusBadRequest, endorError.StatusCode, field)
+	}
+
+	_, err := sdk.ExpandReferences(context.Background(), container, schema, expandOrder{}, sdk.ExpandOptions{
+		Fields: []string{"customerId"},
+		Depth:  sdk.MaxExpandDepth + 1,
+	})
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusBadRequest, endorError.StatusCode)
+}
+
+func TestExpandReferences_NoFields(t *testing.T) {
+	container, _ := newExpandContainer()
+
+	expanded, err := sdk.ExpandReferences(context.Background(), container, sdk.NewSchema(expandOrder{}), expandOrder{CustomerId: "c1"}, sdk.ExpandOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, expanded)
+}
+
+func TestExpandReferences_PermissionsAndRedaction(t *testing.T) {
+	container, repositories := newExpandContainer()
+	container.forbidden = []string{"test/product/instance"}
+	order := expandOrder{Id: "o1", CustomerId: "c2", Lines: []expandLine{{ProductId: "p1"}}}
+
+	expanded, err := sdk.ExpandReferences(context.Background(), container, sdk.NewSchema(expandOrder{}), order, sdk.ExpandOptions{
+		Fields: []string{"customerId", "lines.productId"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, sdk.EntityExpansionGroup{
+		"test/customer": {"c2": {"id": "c2", "name": "Anna", "companyId": "k1", "apiKey": sdk.AuditRedacted}},
+	}, expanded, "the products cannot be read and the secrets are redacted")
+	assert.Empty(t, repositories["product"].lookups)
+}
+
+func TestExpandReferences_OtherModule(t *testing.T) {
+	container, repositories := newExpandContainer()
+	type invoice struct {
+		CustomerId string `json:"customerId" ui-schema:"entity=billing/customer"`
+	}
+
+	expanded, err := sdk.ExpandReferences(context.Background(), container, sdk.NewSchema(invoice{}), invoice{CustomerId: "c1"}, sdk.ExpandOptions{
+		Fields: []string{"customerId"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, expanded)
+	assert.Empty(t, repositories["customer"].lookups, "the customers of another module are not in this service")
+}
@@ -0,0 +1,248 @@
+package sdk
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// MatchFilter evaluates a ReadDTO.Filter against a document in memory, e.g. the before or
+// after document of an EntityEvent. It supports the field conditions $eq, $ne, $gt, $gte,
+// $lt, $lte, $in, $nin, $exists and $regex, the logical operators $and, $or and $nor and
+// dot-notation paths; as in MongoDB a condition on an array field matches if any element
+// matches. Unknown operators never match.
+func MatchFilter(doc map[string]any, filter map[string]any) bool {
+	for field, condition := range filter {
+		switch field {
+		case "$and", "$or", "$nor":
+			clauses, ok := filterClauses(condition)
+			if !ok {
+				return false
+			}
+			matched := 0
+			for _, clause := range clauses {
+				if MatchFilter(doc, clause) {
+					matched++
+				}
+			}
+			switch {
+			case field == "$and" && matched != len(clauses),
+				field == "$or" && matched == 0,
+				field == "$nor" && matched > 0:
+				return false
+			}
+		default:
+			value, exists := filterFieldValue(doc, field)
+			if !matchFilterCondition(value, exists, condition) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchFilterCondition(value any, exists bool, condition any) bool {
+	operators, ok := toFilterMap(condition)
+	if !ok || !isOperatorMap(operators) {
+		return matchFilterValue(value, func(v any) bool { return filterEquals(v, condition) })
+	}
+	for op, operand := range operators {
+		var matched bool
+		switch op {
+		case "$eq":
+			matched = matchFilterValue(value, func(v any) bool { return filterEquals(v, operand) })
+		case "$ne":
+			matched = !matchFilterValue(value, func(v any) bool { return filterEquals(v, operand) })
+		case "$gt", "$gte", "$lt", "$lte":
+			matched = matchFilterValue(value, func(v any) bool {
+				c, ok := filterCompare(v, operand)
+				if !ok {
+					return false
+				}
+				switch op {
+				case "$gt":
+					return c > 0
+				case "$gte":
+					return c >= 0
+				case "$lt":
+					return c < 0
+				}
+				return c <= 0
+			})
+		case "$in", "$nin":
+			in := matchFilterValue(value, func(v any) bool {
+				for _, candidate := range toFilterSlice(operand) {
+					if filterEquals(v, candidate) {
+						return true
+					}
+				}
+				return false
+			})
+			matched = in == (op == "$in")
+		case "$exists":
+			want, _ := operand.(bool)
+			matched = (exists && value != nil) == want
+		case "$regex":
+			pattern, ok := operand.(string)
+			if !ok {
+				return false
+			}
+			if options, ok := operators["$options"].(string); ok && options != "" {
+				pattern = "(?" + options + ")" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return false
+			}
+			matched = matchFilterValue(value, func(v any) bool {
+				s, ok := v.(string)
+				return ok && re.MatchString(s)
+			})
+		case "$options":
+			matched = true
+		default:
+			return false
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchFilterValue applies match to value or, for arrays, to any of their elements.
+func matchFilterValue(value any, match func(v any) bool) bool {
+	if match(value) {
+		return true
+	}
+	for _, element := range toFilterSlice(value) {
+		if match(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFieldValue resolves a dot-notation path in doc.
+func filterFieldValue(doc map[string]any, path string) (any, bool) {
+	key, rest, nested := strings.Cut(path, ".")
+	value, exists := doc[key]
+	if !exists || !nested {
+		return value, exists
+	}
+	child, ok := toFilterMap(value)
+	if !ok {
+		return nil, false
+	}
+	return filterFieldValue(child, rest)
+}
+
+func isOperatorMap(m map[string]any) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func filterClauses(v any) ([]map[string]any, bool) {
+	items := toFilterSlice(v)
+	if items == nil {
+		return nil, false
+	}
+	clauses := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		clause, ok := toFilterMap(item)
+		if !ok {
+			return nil, false
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, true
+}
+
+func toFilterMap(v any) (map[string]any, bool) {
+	if m, ok := v.(map[string]any); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]any, rv.Len())
+	for _, key := range rv.MapKeys() {
+		m[key.String()] = rv.MapIndex(key).Interface()
+	}
+	return m, true
+}
+
+func toFilterSlice(v any) []any {
+	if s, ok := v.([]any); ok {
+		return s
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	s := make([]any, rv.Len())
+	for i := range s {
+		s[i] = rv.Index(i).Interface()
+	}
+	return s
+}
+
+func toFilterNumber(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func filterEquals(a, b any) bool {
+	if fa, ok := toFilterNumber(a); ok {
+		fb, ok := toFilterNumber(b)
+		return ok && fa == fb
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a).Comparable() && reflect.TypeOf(b).Comparable() && a == b {
+		return true
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// filterCompare orders numbers numerically and strings lexicographically (so ISO dates
+// compare chronologically); other values are not comparable.
+func filterCompare(a, b any) (int, bool) {
+	if fa, ok := toFilterNumber(a); ok {
+		fb, ok := toFilterNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if !okA || !okB {
+		return 0, false
+	}
+	return strings.Compare(sa, sb), true
+}
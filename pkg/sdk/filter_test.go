@@ -0,0 +1,51 @@
+package sdk_test
+
+import (
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchFilter(t *testing.T) {
+	doc := map[string]any{
+		"status": "paid",
+		"total":  float64(120),
+		"tags":   []any{"priority", "export"},
+		"customer": map[string]any{
+			"country": "IT",
+		},
+		"note": nil,
+	}
+	cases := []struct {
+		name   string
+		filter map[string]any
+		want   bool
+	}{
+		{"empty", map[string]any{}, true},
+		{"equality", map[string]any{"status": "paid"}, true},
+		{"equality mismatch", map[string]any{"status": "draft"}, false},
+		{"numbers of different types", map[string]any{"total": 120}, true},
+		{"comparison", map[string]any{"total": map[string]any{"$gte": 100, "$lt": 200}}, true},
+		{"comparison mismatch", map[string]any{"total": map[string]any{"$gt": 120}}, false},
+		{"in", map[string]any{"status": map[string]any{"$in": []any{"paid", "shipped"}}}, true},
+		{"nin", map[string]any{"status": map[string]any{"$nin": []any{"paid"}}}, false},
+		{"ne", map[string]any{"status": map[string]any{"$ne": "draft"}}, true},
+		{"dot path", map[string]any{"customer.country": "IT"}, true},
+		{"missing path", map[string]any{"customer.city": "Rome"}, false},
+		{"array contains", map[string]any{"tags": "export"}, true},
+		{"array ne", map[string]any{"tags": map[string]any{"$ne": "export"}}, false},
+		{"exists", map[string]any{"customer": map[string]any{"$exists": true}}, true},
+		{"exists on null", map[string]any{"note": map[string]any{"$exists": true}}, false},
+		{"regex", map[string]any{"status": map[string]any{"$regex": "^PA", "$options": "i"}}, true},
+		{"or", map[string]any{"$or": []any{map[string]any{"status": "draft"}, map[string]any{"total": 120}}}, true},
+		{"and", map[string]any{"$and": []any{map[string]any{"status": "paid"}, map[string]any{"total": 1}}}, false},
+		{"nor", map[string]any{"$nor": []any{map[string]any{"status": "draft"}}}, true},
+		{"unknown operator", map[string]any{"total": map[string]any{"$mod": []any{2, 0}}}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, sdk.MatchFilter(doc, c.filter))
+		})
+	}
+}
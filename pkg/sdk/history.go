@@ -0,0 +1,206 @@
+package sdk
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryCollectionSuffix is appended to the entity id to name the collection of the history
+// of the instances (see EntityInstanceRepositoryOptions.History).
+const HistoryCollectionSuffix = "__history"
+
+// HistoryCollection returns the name of the history collection of entityId.
+func HistoryCollection(entityId string) string {
+	return entityId + HistoryCollectionSuffix
+}
+
+// HistoryEntry is a version of an instance, recorded by every write of the repositories of
+// entities with history.
+type HistoryEntry struct {
+	// Id is the id of the event of the write.
+	Id         string `json:"id" bson:"_id"`
+	InstanceId string `json:"instanceId" bson:"instanceId"`
+	// Version numbers the entries of an instance, starting at 1.
+	Version  int64           `json:"version" bson:"version"`
+	Type     EntityEventType `json:"type" bson:"type"`
+	Category string          `json:"category,omitempty" bson:"category,omitempty"`
+	// Snapshot is the instance after the write, as returned by the actions; it is missing
+	// for deletions and in the entries returned by History.
+	Snapshot map[string]any `json:"snapshot,omitempty" bson:"snapshot,omitempty"`
+	// Changes are the fields changed by the write.
+	Changes    []FieldChange `json:"changes,omitempty" bson:"changes,omitempty"`
+	UserId     string        `json:"userId,omitempty" bson:"userId,omitempty"`
+	OccurredAt time.Time     `json:"occurredAt" bson:"occurredAt"`
+}
+
+// FieldChange is the change of a field (dot notation for nested objects); Before is missing
+// for added fields, After for removed ones.
+type FieldChange struct {
+	Path   string `json:"path" bson:"path"`
+	Before any    `json:"before,omitempty" bson:"before,omitempty"`
+	After  any    `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// ReadHistoryVersionDTO selects a version of the history of an instance.
+type ReadHistoryVersionDTO struct {
+	Id      string `json:"id" binding:"required"`
+	Version int64  `json:"version" binding:"required"`
+}
+
+// NewHistoryEntry returns the entry of the write of event, with the given version.
+func NewHistoryEntry(event EntityEvent, version int64) HistoryEntry {
+	entry := HistoryEntry{
+		Id:         event.Id,
+		InstanceId: event.InstanceId,
+		Version:    version,
+		Type:       event.Type,
+		Category:   event.Category,
+		UserId:     event.Session.UserId,
+		OccurredAt: event.OccurredAt,
+	}
+	if event.Type != EntityEventDeleted {
+		entry.Snapshot = event.After
+		entry.Changes = DiffDocuments(event.Before, event.After)
+	}
+	return entry
+}
+
+// Redact returns the entry with the values of the writeOnly and password fields of schema
+// replaced by AuditRedacted, in the snapshot and in the changes: the history shows that a
+// secret changed, never its value.
+func (e HistoryEntry) Redact(schema *RootSchema) HistoryEntry {
+	if schema == nil {
+		return e
+	}
+	if e.Snapshot != nil {
+		e.Snapshot = RedactPayload(e.Snapshot, schema)
+	}
+	changes := make([]FieldChange, 0, len(e.Changes))
+	for _, change := range e.Changes {
+		if field := schema.schemaAtPath(strings.Split(change.Path, ".")); field != nil {
+			change.Before = schema.redactValue(field, change.Before, 0)
+			change.After = schema.redactValue(field, change.After, 0)
+		}
+		changes = append(changes, change)
+	}
+	if e.Changes != nil {
+		e.Changes = changes
+	}
+	return e
+}
+
+// schemaAtPath returns the schema of the field at path, the sensitive parent of the field
+// if any, nil if the field is not described.
+func (rs *RootSchema) schemaAtPath(path []string) *Schema {
+	s := &rs.Schema
+	for _, key := range path {
+		s = rs.resolveReference(s)
+		if (s.WriteOnly != nil && *s.WriteOnly) || (s.Format != nil && *s.Format == SchemaFormatPassword) {
+			return s
+		}
+		switch {
+		case s.Properties != nil && hasProperty(*s.Properties, key):
+			property := (*s.Properties)[key]
+			s = &property
+		case s.AdditionalProperties != nil:
+			s = s.AdditionalProperties
+		default:
+			return nil
+		}
+	}
+	return s
+}
+
+// RestoreRedacted returns a copy of snapshot, a redacted version of an instance, with the
+// redacted values taken from current, the instance as it is now: reverting to a version
+// keeps the current secrets. Redacted values missing from current are removed, and arrays
+// with redacted items are taken from current as a whole.
+func RestoreRedacted(snapshot map[string]any, current map[string]any) map[string]any {
+	restored := make(map[string]any, len(snapshot))
+	for key, value := range snapshot {
+		currentValue, exists := current[key]
+		switch v := value.(type) {
+		case string:
+			if v != AuditRedacted {
+				restored[key] = v
+			} else if exists {
+				restored[key] = currentValue
+			}
+		case map[string]any:
+			currentObject, _ := currentValue.(map[string]any)
+			restored[key] = RestoreRedacted(v, currentObject)
+		case []any:
+			// items are not matched across versions: arrays with secrets keep the current value
+			if !containsRedacted(v) {
+				restored[key] = v
+			} else if exists {
+				restored[key] = currentValue
+			}
+		default:
+			restored[key] = value
+		}
+	}
+	return restored
+}
+
+func containsRedacted(value any) bool {
+	switch v := value.(type) {
+	case string:
+		return v == AuditRedacted
+	case map[string]any:
+		for _, item := range v {
+			if containsRedacted(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if containsRedacted(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DiffDocuments returns the changes from before to after, ordered by path. Nested objects
+// are compared field by field, arrays as a whole; the version of versioned entities is
+// ignored.
+func DiffDocuments(before, after map[string]any) []FieldChange {
+	changes := []FieldChange{}
+	diffDocuments("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func diffDocuments(prefix string, before, after map[string]any, changes *[]FieldChange) {
+	for key, beforeValue := range before {
+		path := prefix + key
+		if path == VersionField {
+			continue
+		}
+		afterValue, exists := after[key]
+		if !exists {
+			*changes = append(*changes, FieldChange{Path: path, Before: beforeValue})
+			continue
+		}
+		beforeObject, beforeIsObject := beforeValue.(map[string]any)
+		afterObject, afterIsObject := afterValue.(map[string]any)
+		if beforeIsObject && afterIsObject {
+			diffDocuments(path+".", beforeObject, afterObject, changes)
+			continue
+		}
+		if !reflect.DeepEqual(beforeValue, afterValue) {
+			*changes = append(*changes, FieldChange{Path: path, Before: beforeValue, After: afterValue})
+		}
+	}
+	for key, afterValue := range after {
+		path := prefix + key
+		if _, exists := before[key]; exists || path == VersionField {
+			continue
+		}
+		*changes = append(*changes, FieldChange{Path: path, After: afterValue})
+	}
+}
@@ -0,0 +1,100 @@
+package sdk_test
+
+import (
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	before := map[string]any{
+		"id":      "c1",
+		"amount":  100.0,
+		"notes":   "draft",
+		"version": 3.0,
+		"customer": map[string]any{
+			"name":    "ACME",
+			"country": "IT",
+		},
+		"tags": []any{"a"},
+	}
+	after := map[string]any{
+		"id":      "c1",
+		"amount":  120.0,
+		"status":  "open",
+		"version": 4.0,
+		"customer": map[string]any{
+			"name":    "ACME",
+			"country": "FR",
+		},
+		"tags": []any{"a", "b"},
+	}
+
+	assert.Equal(t, []sdk.FieldChange{
+		{Path: "amount", Before: 100.0, After: 120.0},
+		{Path: "customer.country", Before: "IT", After: "FR"},
+		{Path: "notes", Before: "draft"},
+		{Path: "status", After: "open"},
+		{Path: "tags", Before: []any{"a"}, After: []any{"a", "b"}},
+	}, sdk.DiffDocuments(before, after))
+	assert.Empty(t, sdk.DiffDocuments(after, after))
+}
+
+func TestNewHistoryEntry(t *testing.T) {
+	session := sdk.Session{UserId: "u1"}
+	created := sdk.NewEntityEvent(sdk.EntityEventCreated, "contract", "", "c1", nil, map[string]any{"id": "c1", "amount": 100.0}, session)
+
+	entry := sdk.NewHistoryEntry(created, 1)
+	assert.Equal(t, created.Id, entry.Id)
+	assert.Equal(t, "c1", entry.InstanceId)
+	assert.Equal(t, int64(1), entry.Version)
+	assert.Equal(t, "u1", entry.UserId)
+	assert.Equal(t, created.After, entry.Snapshot)
+	assert.Equal(t, []sdk.FieldChange{{Path: "amount", After: 100.0}, {Path: "id", After: "c1"}}, entry.Changes)
+
+	deleted := sdk.NewEntityEvent(sdk.EntityEventDeleted, "contract", "", "c1", created.After, nil, session)
+	entry = sdk.NewHistoryEntry(deleted, 2)
+	assert.Nil(t, entry.Snapshot)
+	assert.Empty(t, entry.Changes)
+}
+
+func TestHistoryEntry_Redact(t *testing.T) {
+	before := map[string]any{"name": "mario", "token": "t1", "credentials": map[string]any{"password": "p1"}, "accounts": []any{}}
+	after := map[string]any{"name": "mario", "token": "t2", "credentials": map[string]any{"password": "p2"}, "accounts": []any{map[string]any{"password": "p3"}}}
+	event := sdk.NewEntityEvent(sdk.EntityEventUpdated, "user", "", "u1", before, after, sdk.Session{})
+
+	entry := sdk.NewHistoryEntry(event, 2).Redact(sdk.NewSchema(auditPayload{}))
+	assert.Equal(t, map[string]any{
+		"name":        "mario",
+		"token":       sdk.AuditRedacted,
+		"credentials": map[string]any{"password": sdk.AuditRedacted},
+		"accounts":    []any{map[string]any{"password": sdk.AuditRedacted}},
+	}, entry.Snapshot)
+	assert.Equal(t, []sdk.FieldChange{
+		{Path: "accounts", Before: []any{}, After: []any{map[string]any{"password": sdk.AuditRedacted}}},
+		{Path: "credentials.password", Before: sdk.AuditRedacted, After: sdk.AuditRedacted},
+		{Path: "token", Before: sdk.AuditRedacted, After: sdk.AuditRedacted},
+	}, entry.Changes)
+	assert.Equal(t, "t2", after["token"], "the event must not be modified")
+}
+
+func TestRestoreRedacted(t *testing.T) {
+	snapshot := map[string]any{
+		"name":        "mario",
+		"token":       sdk.AuditRedacted,
+		"credentials": map[string]any{"password": sdk.AuditRedacted},
+		"accounts":    []any{map[string]any{"password": sdk.AuditRedacted}},
+	}
+	current := map[string]any{
+		"name":        "luigi",
+		"credentials": map[string]any{"password": "p2"},
+		"accounts":    []any{map[string]any{"password": "p3"}, map[string]any{"password": "p4"}},
+	}
+
+	assert.Equal(t, map[string]any{
+		"name":        "mario",
+		"credentials": map[string]any{"password": "p2"},
+		"accounts":    []any{map[string]any{"password": "p3"}, map[string]any{"password": "p4"}},
+	}, sdk.RestoreRedacted(snapshot, current))
+}
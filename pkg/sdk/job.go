@@ -0,0 +1,431 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// #region Job
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusDone      JobStatus = "done"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Completed reports whether the job reached a final state.
+func (s JobStatus) Completed() bool {
+	return s == JobStatusDone || s == JobStatusFailed || s == JobStatusCancelled
+}
+
+// Job is the persisted state of a run of an asynchronous action.
+type Job struct {
+	Id       string    `json:"id" bson:"_id" schema:"title=${t.sdk.job.fields.id},readOnly=true"`
+	ActionId string    `json:"actionId" bson:"actionId" schema:"title=${t.sdk.job.fields.action_id},readOnly=true"`
+	UserId   string    `json:"userId" bson:"userId" schema:"title=${t.sdk.job.fields.user_id},readOnly=true"`
+	Status   JobStatus `json:"status" bson:"status" schema:"title=${t.sdk.job.fields.status},enum=pending|running|done|failed|cancelled,readOnly=true"`
+	// Progress is a percentage reported by the handler, 100 once done.
+	Progress int               `json:"progress" bson:"progress" schema:"title=${t.sdk.job.fields.progress},readOnly=true"`
+	Messages []ResponseMessage `json:"messages" bson:"messages" schema:"title=${t.sdk.job.fields.messages},readOnly=true"`
+	// Result is the Response of the handler, available once done.
+	Result      map[string]any `json:"result,omitempty" bson:"result,omitempty" schema:"title=${t.sdk.job.fields.result},readOnly=true"`
+	CreatedAt   time.Time      `json:"createdAt" bson:"createdAt" schema:"title=${t.sdk.job.fields.created_at},readOnly=true"`
+	StartedAt   *time.Time     `json:"startedAt,omitempty" bson:"startedAt,omitempty" schema:"title=${t.sdk.job.fields.started_at},readOnly=true"`
+	CompletedAt *time.Time     `json:"completedAt,omitempty" bson:"completedAt,omitempty" schema:"title=${t.sdk.job.fields.completed_at},readOnly=true"`
+}
+
+func (j *Job) GetID() any {
+	return j.Id
+}
+
+// JobRepositoryInterface persists the state of the jobs.
+type JobRepositoryInterface interface {
+	Create(ctx context.Context, job Job) error
+	Update(ctx context.Context, job Job) error
+	Instance(ctx context.Context, id string) (*Job, error)
+	// List returns the jobs of userId, most recent first.
+	List(ctx context.Context, userId string) ([]Job, error)
+}
+
+func jobNotFoundError(id string) error {
+	return NewNotFoundError(fmt.Errorf("job %s not found", id)).WithTranslation("sdk.job.messages.not_found", map[string]any{"id": id})
+}
+
+// InMemoryJobRepository keeps the jobs in memory: they are lost on restart and not shared
+// between instances, so it is meant for tests and local development.
+type InMemoryJobRepository struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+func NewInMemoryJobRepository() *InMemoryJobRepository {
+	return &InMemoryJobRepository{jobs: map[string]Job{}}
+}
+
+func (r *InMemoryJobRepository) Create(ctx context.Context, job Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.jobs[job.Id]; exists {
+		return NewConflictError(fmt.Errorf("job %s already exists", job.Id))
+	}
+	r.jobs[job.Id] = job
+	return nil
+}
+
+func (r *InMemoryJobRepository) Update(ctx context.Context, job Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.jobs[job.Id]; !exists {
+		return jobNotFoundError(job.Id)
+	}
+	r.jobs[job.Id] = job
+	return nil
+}
+
+func (r *InMemoryJobRepository) Instance(ctx context.Context, id string) (*Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	job, exists := r.jobs[id]
+	if !exists {
+		return nil, jobNotFoundError(id)
+	}
+	return &job, nil
+}
+
+func (r *InMemoryJobRepository) List(ctx context.Context, userId string) ([]Job, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	jobs := []Job{}
+	for _, job := range r.jobs {
+		if job.UserId == userId {
+			jobs = append(jobs, job)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return jobs, nil
+}
+
+// #endregion
+
+// #region Job manager
+
+const (
+	defaultJobWorkers   = 4
+	defaultJobQueueSize = 100
+)
+
+// JobManager runs the jobs in a bounded pool of workers and persists their state.
+// Jobs are not resumed after a restart: the ones left pending or running stay so.
+type JobManager struct {
+	repository JobRepositoryInterface
+	workers    int
+	queue      chan *jobTask
+	startOnce  sync.Once
+	now        func() time.Time
+
+	mu     sync.Mutex
+	active map[string]*jobTask
+}
+
+// NewJobManager creates a manager with the given number of workers; at most queueSize
+// jobs wait for a free worker, further submissions are rejected with 503.
+func NewJobManager(repository JobRepositoryInterface, workers int, queueSize int) *JobManager {
+	if workers <= 0 {
+		workers = defaultJobWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultJobQueueSize
+	}
+	return &JobManager{
+		repository: repository,
+		workers:    workers,
+		queue:      make(chan *jobTask, queueSize),
+		now:        time.Now,
+		active:     map[string]*jobTask{},
+	}
+}
+
+// JobRunFunc is the work of a job; ctx is cancelled when the job is cancelled.
+type JobRunFunc func(ctx context.Context, job *JobHandle) (any, error)
+
+type jobTask struct {
+	handle  *JobHandle
+	ctx     context.Context
+	cancel  context.CancelFunc
+	run     JobRunFunc
+	caller  EndorContextInterface
+	started bool
+}
+
+// JobHandle lets the handler of an async action report the progress of its job.
+type JobHandle struct {
+	repository JobRepositoryInterface
+	ctx        context.Context
+
+	mu  sync.Mutex
+	job Job
+}
+
+func (h *JobHandle) Id() string {
+	return h.job.Id
+}
+
+// SetProgress records the progress percentage and appends messages to the job.
+// It returns the context error once the job has been cancelled.
+func (h *JobHandle) SetProgress(percent int, messages ...ResponseMessage) error {
+	if err := h.ctx.Err(); err != nil {
+		return err
+	}
+	return h.update(func(job *Job) bool {
+		if job.Status != JobStatusRunning {
+			return false
+		}
+		job.Progress = min(max(percent, 0), 100)
+		job.Messages = append(job.Messages, messages...)
+		return true
+	})
+}
+
+// update applies fn to the job and persists it if fn reports a change.
+func (h *JobHandle) update(fn func(job *Job) bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !fn(&h.job) {
+		return nil
+	}
+	// the state must be saved even after the job context is cancelled
+	return h.repository.Update(context.WithoutCancel(h.ctx), h.job)
+}
+
+func (h *JobHandle) snapshot() Job {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	job := h.job
+	job.Messages = slices.Clone(job.Messages)
+	return job
+}
+
+// Submit persists a pending job owned by the session user of caller and queues run.
+// The job context keeps the values of the caller context but not its cancellation.
+func (m *JobManager) Submit(caller EndorContextInterface, run JobRunFunc) (*Job, error) {
+	m.startOnce.Do(func() {
+		for i := 0; i < m.workers; i++ {
+			go m.work()
+		}
+	})
+
+	job := Job{
+		Id:        primitive.NewObjectID().Hex(),
+		ActionId:  caller.GetActionId(),
+		UserId:    caller.GetSession().UserId,
+		Status:    JobStatusPending,
+		Messages:  []ResponseMessage{},
+		CreatedAt: m.now(),
+	}
+	if err := m.repository.Create(caller.Context(), job); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(caller.Context()))
+	task := &jobTask{
+		handle: &JobHandle{repository: m.repository, ctx: ctx, job: job},
+		ctx:    ctx,
+		cancel: cancel,
+		run:    run,
+		caller: caller,
+	}
+
+	m.mu.Lock()
+	m.active[job.Id] = task
+	m.mu.Unlock()
+	select {
+	case m.queue <- task:
+		return &job, nil
+	default:
+		err := NewGenericError(http.StatusServiceUnavailable, fmt.Errorf("job queue is full")).WithTranslation("sdk.job.messages.queue_full", nil)
+		m.complete(task, nil, err)
+		m.release(task)
+		return nil, err
+	}
+}
+
+// Instance returns the job if it belongs to the session user.
+func (m *JobManager) Instance(ctx context.Context, session Session, id string) (*Job, error) {
+	job, err := m.repository.Instance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserId != session.UserId {
+		return nil, jobNotFoundError(id)
+	}
+	return job, nil
+}
+
+// List returns the jobs of the session user, most recent first.
+func (m *JobManager) List(ctx context.Context, session Session) ([]Job, error) {
+	return m.repository.List(ctx, session.UserId)
+}
+
+// Cancel cancels a pending or running job of the session user. A running handler is
+// notified through its context and should return as soon as possible.
+func (m *JobManager) Cancel(ctx context.Context, session Session, id string) (*Job, error) {
+	job, err := m.Instance(ctx, session, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.Completed() {
+		return nil, NewConflictError(fmt.Errorf("job %s is already %s", id, job.Status)).WithTranslation("sdk.job.messages.not_cancellable", map[string]any{"id": id, "status": string(job.Status)})
+	}
+	completedAt := m.now()
+	cancelled := func(job *Job) bool {
+		if job.Status.Completed() {
+			return false
+		}
+		job.Status = JobStatusCancelled
+		job.CompletedAt = &completedAt
+		return true
+	}
+
+	m.mu.Lock()
+	task, local := m.active[id]
+	m.mu.Unlock()
+	if !local {
+		// the job runs on another instance, which keeps the cancelled state when it completes
+		cancelled(job)
+		if err := m.repository.Update(ctx, *job); err != nil {
+			return nil, err
+		}
+		return job, nil
+	}
+	task.cancel()
+	if err := task.handle.update(cancelled); err != nil {
+		return nil, err
+	}
+	result := task.handle.snapshot()
+	return &result, nil
+}
+
+func (m *JobManager) work() {
+	for task := range m.queue {
+		m.execute(task)
+	}
+}
+
+func (m *JobManager) execute(task *jobTask) {
+	m.mu.Lock()
+	if task.ctx.Err() != nil {
+		// cancelled while pending
+		delete(m.active, task.handle.Id())
+		m.mu.Unlock()
+		return
+	}
+	task.started = true
+	m.mu.Unlock()
+	defer m.release(task)
+
+	startedAt := m.now()
+	if err := task.handle.update(func(job *Job) bool {
+		if job.Status != JobStatusPending {
+			return false
+		}
+		job.Status = JobStatusRunning
+		job.StartedAt = &startedAt
+		return true
+	}); err != nil {
+		task.caller.GetLogger().ErrorWithStackTrace(err)
+	}
+
+	result, err := m.call(task)
+	m.complete(task, result, err)
+}
+
+// call runs the job turning a panic into an error.
+func (m *JobManager) call(task *jobTask) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewInternalServerError(fmt.Errorf("job %s panicked: %v", task.handle.Id(), r))
+		}
+	}()
+	return task.run(task.ctx, task.handle)
+}
+
+func (m *JobManager) complete(task *jobTask, result any, runErr error) {
+	var data map[string]any
+	if runErr == nil && result != nil {
+		data, runErr = toResultMap(result)
+	}
+	// another instance may have cancelled the job meanwhile
+	if stored, err := m.repository.Instance(context.WithoutCancel(task.ctx), task.handle.Id()); err == nil && stored.Status == JobStatusCancelled {
+		task.cancel()
+	}
+	completedAt := m.now()
+	failed := false
+	err := task.handle.update(func(job *Job) bool {
+		if job.Status.Completed() {
+			return false
+		}
+		job.CompletedAt = &completedAt
+		switch {
+		case task.ctx.Err() != nil:
+			job.Status = JobStatusCancelled
+		case runErr != nil:
+			failed = true
+			job.Status = JobStatusFailed
+			job.Messages = append(job.Messages, NewMessage(ResponseMessageGravityFatal, translateError(task.caller, runErr)))
+		default:
+			job.Status = JobStatusDone
+			job.Progress = 100
+			job.Result = data
+		}
+		return true
+	})
+	if failed {
+		task.caller.GetLogger().ErrorWithStackTrace(runErr)
+	}
+	if err != nil {
+		task.caller.GetLogger().ErrorWithStackTrace(err)
+	}
+}
+
+func (m *JobManager) release(task *jobTask) {
+	m.mu.Lock()
+	delete(m.active, task.handle.Id())
+	m.mu.Unlock()
+	task.cancel()
+}
+
+func toResultMap(result any) (map[string]any, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, NewInternalServerError(fmt.Errorf("encode job result: %w", err))
+	}
+	data := map[string]any{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, NewInternalServerError(fmt.Errorf("encode job result: %w", err))
+	}
+	return data, nil
+}
+
+// translateError returns the message of err in the locale of the caller session.
+func translateError(caller EndorContextInterface, err error) string {
+	var endorError *EndorError
+	if errors.As(err, &endorError) && endorError.TranslationKey != "" && caller.GetDIContainer() != nil {
+		return caller.T(endorError.TranslationKey, endorError.TranslationArgs)
+	}
+	return err.Error()
+}
+
+// #endregion
@@ -0,0 +1,176 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type importPayload struct {
+	Rows int `json:"rows"`
+}
+
+func newJobContext(manager *sdk.JobManager, userId string) *sdk.EndorContext[importPayload] {
+	return &sdk.EndorContext[importPayload]{
+		ActionId:    "sdk/order/import",
+		Session:     sdk.Session{UserId: userId, Locale: "en"},
+		Payload:     importPayload{Rows: 3},
+		DIContainer: testDIContainer{jobs: manager},
+		Logger:      *sdk.NewLogger(sdk.LogConfig{}, sdk.LogContext{}),
+	}
+}
+
+func waitForJob(t *testing.T, manager *sdk.JobManager, session sdk.Session, id string) *sdk.Job {
+	t.Helper()
+	var job *sdk.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = manager.Instance(context.Background(), session, id)
+		require.NoError(t, err)
+		return job.Status.Completed()
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func useJobManager(t *testing.T, workers int, queueSize int) *sdk.JobManager {
+	t.Helper()
+	return sdk.NewJobManager(sdk.NewInMemoryJobRepository(), workers, queueSize)
+}
+
+func TestAsyncAction_RunsInBackground(t *testing.T) {
+	manager := useJobManager(t, 2, 10)
+	release := make(chan struct{})
+	action := sdk.NewAsyncAction(func(c *sdk.EndorContext[importPayload], job *sdk.JobHandle) (*sdk.Response[int], error) {
+		<-release
+		for i := 1; i <= c.Payload.Rows; i++ {
+			require.NoError(t, job.SetProgress(i*100/c.Payload.Rows, sdk.NewMessage(sdk.ResponseMessageGravityInfo, "row imported")))
+		}
+		imported := c.Payload.Rows
+		return sdk.NewResponseBuilder[int]().AddData(&imported).Build(), nil
+	}, "import orders")
+
+	ec := newJobContext(manager, "user-1")
+	result, err := action.Invoke(ec)
+	require.NoError(t, err)
+	response := result.(*sdk.Response[sdk.Job])
+	assert.Equal(t, sdk.JobStatusPending, response.Data.Status)
+	assert.Equal(t, "sdk/order/import", response.Data.ActionId)
+	assert.Equal(t, "job "+response.Data.Id+" queued", response.Messages[0].Value)
+
+	close(release)
+	job := waitForJob(t, manager, ec.Session, response.Data.Id)
+	assert.Equal(t, sdk.JobStatusDone, job.Status)
+	assert.Equal(t, 100, job.Progress)
+	assert.Len(t, job.Messages, 3)
+	assert.Equal(t, float64(3), job.Result["data"])
+	assert.NotNil(t, job.StartedAt)
+	assert.NotNil(t, job.CompletedAt)
+
+	_, err = manager.Cancel(context.Background(), ec.Session, job.Id)
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusConflict, endorError.StatusCode)
+}
+
+func TestAsyncAction_FailureIsRecorded(t *testing.T) {
+	manager := useJobManager(t, 1, 10)
+	action := sdk.NewAsyncAction(func(c *sdk.EndorContext[importPayload], job *sdk.JobHandle) (*sdk.Response[int], error) {
+		return nil, sdk.NewBadRequestError(errors.New("bad row")).WithTranslation("sdk.validation.required", map[string]any{"field": "code"})
+	}, "import orders")
+
+	ec := newJobContext(manager, "user-1")
+	result, err := action.Invoke(ec)
+	require.NoError(t, err)
+	job := waitForJob(t, manager, ec.Session, result.(*sdk.Response[sdk.Job]).Data.Id)
+	assert.Equal(t, sdk.JobStatusFailed, job.Status)
+	require.Len(t, job.Messages, 1)
+	assert.Equal(t, "code is required", job.Messages[0].Value)
+	assert.Nil(t, job.Result)
+}
+
+func TestJobManager_Cancel(t *testing.T) {
+	manager := useJobManager(t, 1, 10)
+	started := make(chan struct{})
+	progressErr := make(chan error, 1)
+	action := sdk.NewAsyncAction(func(c *sdk.EndorContext[importPayload], job *sdk.JobHandle) (*sdk.Response[int], error) {
+		close(started)
+		<-c.Context().Done()
+		progressErr <- job.SetProgress(50)
+		return nil, c.Context().Err()
+	}, "import orders")
+
+	ec := newJobContext(manager, "user-1")
+	running, err := action.Invoke(ec)
+	require.NoError(t, err)
+	pending, err := action.Invoke(ec)
+	require.NoError(t, err)
+	<-started
+
+	job, err := manager.Cancel(context.Background(), ec.Session, pending.(*sdk.Response[sdk.Job]).Data.Id)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.JobStatusCancelled, job.Status)
+
+	job, err = manager.Cancel(context.Background(), ec.Session, running.(*sdk.Response[sdk.Job]).Data.Id)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.JobStatusCancelled, job.Status)
+	assert.ErrorIs(t, <-progressErr, context.Canceled)
+
+	job = waitForJob(t, manager, ec.Session, running.(*sdk.Response[sdk.Job]).Data.Id)
+	assert.Equal(t, sdk.JobStatusCancelled, job.Status)
+	assert.Empty(t, job.Messages)
+}
+
+func TestJobManager_ScopedToUser(t *testing.T) {
+	manager := useJobManager(t, 1, 10)
+	action := sdk.NewAsyncAction(func(c *sdk.EndorContext[importPayload], job *sdk.JobHandle) (*sdk.Response[int], error) {
+		return nil, nil
+	}, "import orders")
+
+	result, err := action.Invoke(newJobContext(manager, "user-1"))
+	require.NoError(t, err)
+	id := result.(*sdk.Response[sdk.Job]).Data.Id
+	_, err = action.Invoke(newJobContext(manager, "user-2"))
+	require.NoError(t, err)
+
+	other := sdk.Session{UserId: "user-2"}
+	_, err = manager.Instance(context.Background(), other, id)
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusNotFound, endorError.StatusCode)
+	_, err = manager.Cancel(context.Background(), other, id)
+	assert.ErrorAs(t, err, &endorError)
+
+	jobs, err := manager.List(context.Background(), sdk.Session{UserId: "user-1"})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, id, jobs[0].Id)
+}
+
+func TestJobManager_QueueFull(t *testing.T) {
+	manager := useJobManager(t, 1, 1)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 2)
+	action := sdk.NewAsyncAction(func(c *sdk.EndorContext[importPayload], job *sdk.JobHandle) (*sdk.Response[int], error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}, "import orders")
+
+	_, err := action.Invoke(newJobContext(manager, "user-1"))
+	require.NoError(t, err)
+	<-started
+	_, err = action.Invoke(newJobContext(manager, "user-1"))
+	require.NoError(t, err)
+
+	_, err = action.Invoke(newJobContext(manager, "user-1"))
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusServiceUnavailable, endorError.StatusCode)
+}
@@ -0,0 +1,70 @@
+package sdk
+
+import "fmt"
+
+// EndorResponseInterface is the untyped view of a *Response[T] exposed to middlewares,
+// allowing them to decorate a response without knowing its data type.
+type EndorResponseInterface interface {
+	GetMessages() []ResponseMessage
+	AppendMessage(message ResponseMessage)
+}
+
+// EndorActionNext invokes the next middleware in the chain, or the action handler itself.
+type EndorActionNext func() (EndorResponseInterface, error)
+
+// EndorActionMiddleware intercepts the execution of an action. It receives the typed
+// EndorContext (through EndorContextInterface; type-assert to *EndorContext[T] for the
+// typed payload) and decides whether and when to call next. Returning an error without
+// calling next short-circuits the action.
+//
+// Middlewares are registered globally (EndorInitializer.WithActionMiddlewares), per handler
+// (WithMiddlewares on the handler builders) or per action (EndorHandlerActionOptions.Middlewares)
+// and run in that order, outermost first, both for HTTP calls and for Invoke.
+type EndorActionMiddleware func(ctx EndorContextInterface, next EndorActionNext) (EndorResponseInterface, error)
+
+// WithActionMiddlewares returns the actions wrapped by the given middlewares.
+// The middlewares run before the ones already attached to each action.
+func WithActionMiddlewares(actions map[string]EndorHandlerActionInterface, middlewares ...EndorActionMiddleware) map[string]EndorHandlerActionInterface {
+	if len(middlewares) == 0 {
+		return actions
+	}
+	wrapped := make(map[string]EndorHandlerActionInterface, len(actions))
+	for name, action := range actions {
+		wrapped[name] = action.WithMiddlewares(middlewares...)
+	}
+	return wrapped
+}
+
+// runChain runs the action handler through the middleware chain.
+func (m *endorHandlerActionImpl[T, R]) runChain(ec *EndorContext[T]) (*Response[R], error) {
+	chain := make([]EndorActionMiddleware, 0, len(m.middlewares)+len(m.options.Middlewares))
+	chain = append(chain, m.middlewares...)
+	chain = append(chain, m.options.Middlewares...)
+	if len(chain) == 0 {
+		return m.handler(ec)
+	}
+
+	next := func() (EndorResponseInterface, error) {
+		response, err := m.handler(ec)
+		if response == nil {
+			return nil, err
+		}
+		return response, err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		middleware, inner := chain[i], next
+		next = func() (EndorResponseInterface, error) {
+			return middleware(ec, inner)
+		}
+	}
+
+	result, err := next()
+	if result == nil {
+		return nil, err
+	}
+	response, ok := result.(*Response[R])
+	if !ok {
+		return nil, NewInternalServerError(fmt.Errorf("middleware returned incompatible response type %T", result))
+	}
+	return response, err
+}
@@ -0,0 +1,105 @@
+package sdk_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type middlewarePayload struct {
+	Name string `json:"name"`
+}
+
+func recordingMiddleware(name string, trace *[]string) sdk.EndorActionMiddleware {
+	return func(ctx sdk.EndorContextInterface, next sdk.EndorActionNext) (sdk.EndorResponseInterface, error) {
+		*trace = append(*trace, name+":before")
+		response, err := next()
+		*trace = append(*trace, name+":after")
+		return response, err
+	}
+}
+
+func TestMiddleware_OrderAndInvoke(t *testing.T) {
+	trace := []string{}
+	action := sdk.NewConfigurableAction(
+		sdk.EndorHandlerActionOptions{
+			Description: "test",
+			Middlewares: []sdk.EndorActionMiddleware{recordingMiddleware("action", &trace)},
+		},
+		func(c *sdk.EndorContext[middlewarePayload]) (*sdk.Response[string], error) {
+			trace = append(trace, "handler")
+			return sdk.NewResponseBuilder[string]().AddData(&c.Payload.Name).Build(), nil
+		},
+	).WithMiddlewares(recordingMiddleware("handler-level", &trace)).WithMiddlewares(recordingMiddleware("global", &trace))
+
+	result, err := action.Invoke(&sdk.EndorContext[middlewarePayload]{Payload: middlewarePayload{Name: "mario"}})
+	require.NoError(t, err)
+	response, ok := result.(*sdk.Response[string])
+	require.True(t, ok)
+	assert.Equal(t, "mario", *response.Data)
+	assert.Equal(t, []string{
+		"global:before", "handler-level:before", "action:before",
+		"handler",
+		"action:after", "handler-level:after", "global:after",
+	}, trace)
+}
+
+func TestMiddleware_ShortCircuitAndTypedContext(t *testing.T) {
+	called := false
+	guard := func(ctx sdk.EndorContextInterface, next sdk.EndorActionNext) (sdk.EndorResponseInterface, error) {
+		typed, ok := ctx.(*sdk.EndorContext[middlewarePayload])
+		if !ok || typed.Payload.Name != "admin" {
+			return nil, sdk.NewForbiddenError(errors.New("denied"))
+		}
+		return next()
+	}
+	action := sdk.NewConfigurableAction(
+		sdk.EndorHandlerActionOptions{Middlewares: []sdk.EndorActionMiddleware{guard}},
+		func(c *sdk.EndorContext[middlewarePayload]) (*sdk.Response[any], error) {
+			called = true
+			return sdk.NewResponseBuilder[any]().Build(), nil
+		},
+	)
+
+	_, err := action.Invoke(&sdk.EndorContext[middlewarePayload]{Payload: middlewarePayload{Name: "guest"}})
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusForbidden, endorError.StatusCode)
+	assert.False(t, called)
+
+	_, err = action.Invoke(&sdk.EndorContext[middlewarePayload]{Payload: middlewarePayload{Name: "admin"}})
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestMiddleware_HTTPCallbackDecoratesResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seenActionId string
+	decorate := func(ctx sdk.EndorContextInterface, next sdk.EndorActionNext) (sdk.EndorResponseInterface, error) {
+		seenActionId = ctx.GetActionId()
+		response, err := next()
+		if err == nil {
+			response.AppendMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, "decorated"))
+		}
+		return response, err
+	}
+	action := sdk.NewAction(func(c *sdk.EndorContext[middlewarePayload]) (*sdk.Response[any], error) {
+		return sdk.NewResponseBuilder[any]().Build(), nil
+	}, "test").WithMiddlewares(decorate)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/order/cat-1/create", strings.NewReader(`{"name":"x"}`))
+	action.CreateHTTPCallback("sdk", "order", "create", "cat-1", sdk.Session{}, testDIContainer{})(c)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "decorated")
+	assert.Equal(t, "sdk/order/cat-1/create", seenActionId)
+}
@@ -0,0 +1,345 @@
+package sdk
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// #region Outbox entries
+
+type OutboxEntryStatus string
+
+const (
+	OutboxEntryStatusPending   OutboxEntryStatus = "pending"
+	OutboxEntryStatusDelivered OutboxEntryStatus = "delivered"
+	// OutboxEntryStatusFailed entries exhausted their attempts and are no longer delivered.
+	OutboxEntryStatusFailed OutboxEntryStatus = "failed"
+)
+
+// OutboxEntry is an entity event waiting to be delivered to the outbox publishers. It is
+// written in the same transaction as the change it describes.
+type OutboxEntry struct {
+	// Id is the id of the event.
+	Id            string            `json:"id" bson:"_id"`
+	Event         EntityEvent       `json:"event" bson:"event"`
+	Status        OutboxEntryStatus `json:"status" bson:"status"`
+	Attempts      int               `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time         `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	// LockedUntil is the end of the lease of the relay delivering the entry.
+	LockedUntil *time.Time `json:"lockedUntil,omitempty" bson:"lockedUntil,omitempty"`
+	LastError   string     `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt" bson:"createdAt"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
+
+// OutboxRepositoryInterface stores the outbox entries. Add is called inside the transaction
+// of the write, so the Mongo implementation must use ctx.
+type OutboxRepositoryInterface interface {
+	Add(ctx context.Context, entry OutboxEntry) error
+	// Claim leases up to limit pending entries due at now until now+lease, oldest first:
+	// a claimed entry is not returned to other relays until the lease expires.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxEntry, error)
+	Update(ctx context.Context, entry OutboxEntry) error
+}
+
+// InMemoryOutboxRepository keeps the entries in memory, without transactions: it is meant
+// for tests and local development.
+type InMemoryOutboxRepository struct {
+	mu      sync.Mutex
+	entries map[string]OutboxEntry
+}
+
+func NewInMemoryOutboxRepository() *InMemoryOutboxRepository {
+	return &InMemoryOutboxRepository{entries: map[string]OutboxEntry{}}
+}
+
+func (r *InMemoryOutboxRepository) Add(ctx context.Context, entry OutboxEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[entry.Id] = entry
+	return nil
+}
+
+func (r *InMemoryOutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]OutboxEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := []OutboxEntry{}
+	for _, entry := range r.entries {
+		if entry.Status == OutboxEntryStatusPending && !entry.NextAttemptAt.After(now) && (entry.LockedUntil == nil || entry.LockedUntil.Before(now)) {
+			due = append(due, entry)
+		}
+	}
+	slices.SortFunc(due, func(a, b OutboxEntry) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.Id, b.Id))
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	lockedUntil := now.Add(lease)
+	for i := range due {
+		due[i].LockedUntil = &lockedUntil
+		r.entries[due[i].Id] = due[i]
+	}
+	return due, nil
+}
+
+func (r *InMemoryOutboxRepository) Update(ctx context.Context, entry OutboxEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[entry.Id] = entry
+	return nil
+}
+
+// Entries returns the stored entries, oldest first.
+func (r *InMemoryOutboxRepository) Entries() []OutboxEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]OutboxEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b OutboxEntry) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.Id, b.Id))
+	})
+	return entries
+}
+
+// #endregion
+
+// #region Publishers
+
+// OutboxPublisher delivers the events of the outbox to a downstream system. Events may be
+// delivered more than once: receivers should deduplicate them by id.
+type OutboxPublisher interface {
+	Publish(ctx context.Context, event EntityEvent) error
+}
+
+// WebhookOutboxPublisher POSTs every event as JSON to URL; any status other than 2xx is
+// a failure.
+type WebhookOutboxPublisher struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func NewWebhookOutboxPublisher(url string) *WebhookOutboxPublisher {
+	return &WebhookOutboxPublisher{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *WebhookOutboxPublisher) Publish(ctx context.Context, event EntityEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(X_ENDOR_EVENT_ID, event.Id)
+	for key, value := range p.Headers {
+		request.Header.Set(key, value)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", p.URL, response.Status)
+	}
+	return nil
+}
+
+// InMemoryOutboxPublisher records the published events; Fail, if set, makes Publish fail
+// with the returned error. It is meant for tests.
+type InMemoryOutboxPublisher struct {
+	mu     sync.Mutex
+	events []EntityEvent
+	Fail   func(event EntityEvent) error
+}
+
+func NewInMemoryOutboxPublisher() *InMemoryOutboxPublisher {
+	return &InMemoryOutboxPublisher{}
+}
+
+func (p *InMemoryOutboxPublisher) Publish(ctx context.Context, event EntityEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Fail != nil {
+		if err := p.Fail(event); err != nil {
+			return err
+		}
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns the published events in publication order.
+func (p *InMemoryOutboxPublisher) Events() []EntityEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.events)
+}
+
+// #endregion
+
+// #region Relay
+
+type OutboxOptions struct {
+	// PollInterval is the wait between two relay rounds (default 1s).
+	PollInterval time.Duration
+	// BatchSize bounds the entries delivered by a round (default 100).
+	BatchSize int
+	// MaxAttempts is the number of deliveries before an entry is failed (default 10).
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt, doubled at every further one
+	// up to MaxBackoff (defaults 1s and 5m).
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Lease is how long a claimed entry is reserved to a relay (default 1m).
+	Lease time.Duration
+}
+
+func (o OutboxOptions) withDefaults() OutboxOptions {
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 10
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Minute
+	}
+	if o.Lease <= 0 {
+		o.Lease = time.Minute
+	}
+	return o
+}
+
+// Outbox records the entity events in the transaction of the writes and relays them to the
+// publishers. See docs/EVENTS.md for the delivery guarantees.
+type Outbox struct {
+	repository OutboxRepositoryInterface
+	publishers []OutboxPublisher
+	options    OutboxOptions
+	logger     *Logger
+	now        func() time.Time
+}
+
+func NewOutbox(repository OutboxRepositoryInterface, publishers []OutboxPublisher, options OutboxOptions, logger *Logger) *Outbox {
+	if logger == nil {
+		logger = NewLogger(LogConfig{}, LogContext{})
+	}
+	return &Outbox{
+		repository: repository,
+		publishers: publishers,
+		options:    options.withDefaults(),
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Add records event; ctx is the one of the transaction of the write.
+func (o *Outbox) Add(ctx context.Context, event EntityEvent) error {
+	now := o.now().UTC()
+	return o.repository.Add(ctx, OutboxEntry{
+		Id:            event.Id,
+		Event:         event,
+		Status:        OutboxEntryStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+// Start runs the relay every PollInterval until ctx is done.
+func (o *Outbox) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(o.options.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := o.Relay(ctx); err != nil {
+					o.logger.Error(fmt.Sprintf("outbox relay failed: %s", err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// Relay delivers the due entries once.
+func (o *Outbox) Relay(ctx context.Context) error {
+	entries, err := o.repository.Claim(ctx, o.now().UTC(), o.options.Lease, o.options.BatchSize)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if err := o.deliver(ctx, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver publishes entry to every publisher and records the outcome.
+func (o *Outbox) deliver(ctx context.Context, entry OutboxEntry) error {
+	var errs []error
+	for _, publisher := range o.publishers {
+		if err := publisher.Publish(ctx, entry.Event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	now := o.now().UTC()
+	entry.Attempts++
+	entry.LockedUntil = nil
+	if publishErr := errors.Join(errs...); publishErr != nil {
+		entry.LastError = publishErr.Error()
+		if entry.Attempts >= o.options.MaxAttempts {
+			entry.Status = OutboxEntryStatusFailed
+			o.logger.Error(fmt.Sprintf("outbox entry %s failed after %d attempts: %s", entry.Id, entry.Attempts, entry.LastError))
+		} else {
+			entry.NextAttemptAt = now.Add(o.backoff(entry.Attempts))
+		}
+	} else {
+		entry.Status = OutboxEntryStatusDelivered
+		entry.LastError = ""
+		entry.DeliveredAt = &now
+	}
+	return o.repository.Update(ctx, entry)
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	backoff := o.options.InitialBackoff
+	for i := 1; i < attempts && backoff < o.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, o.options.MaxBackoff)
+}
+
+// #endregion
@@ -0,0 +1,119 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_RelayDelivers(t *testing.T) {
+	repository := sdk.NewInMemoryOutboxRepository()
+	publisher := sdk.NewInMemoryOutboxPublisher()
+	outbox := sdk.NewOutbox(repository, []sdk.OutboxPublisher{publisher}, sdk.OutboxOptions{}, nil)
+
+	event := newOrderEvent(sdk.EntityEventCreated)
+	require.NoError(t, outbox.Add(context.Background(), event))
+	require.NoError(t, outbox.Relay(context.Background()))
+	require.NoError(t, outbox.Relay(context.Background()))
+
+	require.Len(t, publisher.Events(), 1)
+	assert.Equal(t, event.Id, publisher.Events()[0].Id)
+	entries := repository.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, sdk.OutboxEntryStatusDelivered, entries[0].Status)
+	assert.Equal(t, 1, entries[0].Attempts)
+	assert.NotNil(t, entries[0].DeliveredAt)
+	assert.Nil(t, entries[0].LockedUntil)
+}
+
+func TestOutbox_RetriesWithBackoff(t *testing.T) {
+	repository := sdk.NewInMemoryOutboxRepository()
+	publisher := sdk.NewInMemoryOutboxPublisher()
+	publisher.Fail = func(event sdk.EntityEvent) error { return errors.New("downstream unavailable") }
+	outbox := sdk.NewOutbox(repository, []sdk.OutboxPublisher{publisher}, sdk.OutboxOptions{InitialBackoff: time.Hour, MaxBackoff: 2 * time.Hour}, nil)
+
+	require.NoError(t, outbox.Add(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	require.NoError(t, outbox.Relay(context.Background()))
+	entry := repository.Entries()[0]
+	assert.Equal(t, sdk.OutboxEntryStatusPending, entry.Status)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.Equal(t, "downstream unavailable", entry.LastError)
+	assert.True(t, entry.NextAttemptAt.After(entry.CreatedAt.Add(59*time.Minute)))
+
+	// not due before the backoff elapses
+	publisher.Fail = nil
+	require.NoError(t, outbox.Relay(context.Background()))
+	assert.Empty(t, publisher.Events())
+	assert.Equal(t, 1, repository.Entries()[0].Attempts)
+}
+
+func TestOutbox_FailsAfterMaxAttempts(t *testing.T) {
+	repository := sdk.NewInMemoryOutboxRepository()
+	publisher := sdk.NewInMemoryOutboxPublisher()
+	publisher.Fail = func(event sdk.EntityEvent) error { return errors.New("rejected") }
+	outbox := sdk.NewOutbox(repository, []sdk.OutboxPublisher{publisher}, sdk.OutboxOptions{MaxAttempts: 2, InitialBackoff: time.Nanosecond}, nil)
+
+	require.NoError(t, outbox.Add(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, outbox.Relay(context.Background()))
+		time.Sleep(time.Millisecond)
+	}
+	entry := repository.Entries()[0]
+	assert.Equal(t, sdk.OutboxEntryStatusFailed, entry.Status)
+	assert.Equal(t, 2, entry.Attempts)
+}
+
+func TestInMemoryOutboxRepository_ClaimLease(t *testing.T) {
+	repository := sdk.NewInMemoryOutboxRepository()
+	outbox := sdk.NewOutbox(repository, nil, sdk.OutboxOptions{}, nil)
+	require.NoError(t, outbox.Add(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	require.NoError(t, outbox.Add(context.Background(), newOrderEvent(sdk.EntityEventUpdated)))
+
+	now := time.Now().UTC()
+	claimed, err := repository.Claim(context.Background(), now, time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, sdk.EntityEventCreated, claimed[0].Event.Type)
+
+	claimed, err = repository.Claim(context.Background(), now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, sdk.EntityEventUpdated, claimed[0].Event.Type)
+
+	// leases expire
+	claimed, err = repository.Claim(context.Background(), now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+}
+
+func TestWebhookOutboxPublisher(t *testing.T) {
+	var received sdk.EntityEvent
+	var eventId string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventId = r.Header.Get(sdk.X_ENDOR_EVENT_ID)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	publisher := sdk.NewWebhookOutboxPublisher(server.URL)
+
+	event := sdk.NewEntityEvent(sdk.EntityEventCreated, "order", "", "order-1", nil, map[string]any{"code": "A"}, sdk.Session{UserId: "user-1", AccessToken: "Bearer secret"})
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	assert.Equal(t, event.Id, eventId)
+	assert.Equal(t, "order-1", received.InstanceId)
+	assert.Equal(t, "A", received.After["code"])
+	assert.Equal(t, "user-1", received.Session.UserId)
+	assert.Empty(t, received.Session.AccessToken)
+
+	status = http.StatusBadGateway
+	assert.Error(t, publisher.Publish(context.Background(), event))
+}
@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PermissionWildcard grants every permission; "<resource>:*" grants every permission of a resource.
+const PermissionWildcard = "*"
+
+// HasPermission reports whether the session grants the given permission (e.g. "order:write").
+func (s Session) HasPermission(permission string) bool {
+	for _, granted := range s.Permissions {
+		if permissionMatches(granted, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermissions reports whether the session grants all the given permissions.
+func (s Session) HasPermissions(permissions []string) bool {
+	return len(s.MissingPermissions(permissions)) == 0
+}
+
+// MissingPermissions returns the permissions, among the required ones, not granted by the session.
+func (s Session) MissingPermissions(permissions []string) []string {
+	var missing []string
+	for _, permission := range permissions {
+		if !s.HasPermission(permission) {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
+
+// AuthorizeAction returns a 403 error listing the permissions required by the action
+// actionId that the session does not grant, nil if it grants all of them.
+func (s Session) AuthorizeAction(actionId string, permissions []string) error {
+	missing := s.MissingPermissions(permissions)
+	if len(missing) == 0 {
+		return nil
+	}
+	return NewForbiddenError(fmt.Errorf("missing permissions %v for action %s", missing, actionId)).WithTranslation("sdk.authorization.forbidden", map[string]any{
+		"action":      actionId,
+		"permissions": strings.Join(missing, ", "),
+	})
+}
+
+func permissionMatches(granted string, required string) bool {
+	if granted == PermissionWildcard || granted == required {
+		return true
+	}
+	if resource, ok := strings.CutSuffix(granted, ":"+PermissionWildcard); ok {
+		return strings.HasPrefix(required, resource+":")
+	}
+	return false
+}
+
+// RolePermissions maps a role to the permissions it grants.
+type RolePermissions map[string][]string
+
+// Resolve returns the given permissions plus the ones granted by roles, without duplicates.
+func (r RolePermissions) Resolve(roles []string, permissions []string) []string {
+	groups := [][]string{permissions}
+	for _, role := range roles {
+		groups = append(groups, r[role])
+	}
+	return mergePermissions(groups...)
+}
+
+func mergePermissions(groups ...[]string) []string {
+	merged := []string{}
+	seen := map[string]struct{}{}
+	for _, group := range groups {
+		for _, permission := range group {
+			if _, ok := seen[permission]; !ok {
+				seen[permission] = struct{}{}
+				merged = append(merged, permission)
+			}
+		}
+	}
+	return merged
+}
+
+// ParseHeaderList splits a comma or space separated header value (e.g. X-Endor-Roles).
+func ParseHeaderList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// WithActionRequiredPermissions returns the actions with the given permissions added to
+// the ones they already require.
+func WithActionRequiredPermissions(actions map[string]EndorHandlerActionInterface, permissions ...string) map[string]EndorHandlerActionInterface {
+	if len(permissions) == 0 {
+		return actions
+	}
+	wrapped := make(map[string]EndorHandlerActionInterface, len(actions))
+	for name, action := range actions {
+		wrapped[name] = action.WithRequiredPermissions(permissions...)
+	}
+	return wrapped
+}
@@ -0,0 +1,74 @@
+package sdk_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_HasPermission(t *testing.T) {
+	session := sdk.Session{Permissions: []string{"order:read", "invoice:*"}}
+	assert.True(t, session.HasPermission("order:read"))
+	assert.False(t, session.HasPermission("order:write"))
+	assert.True(t, session.HasPermission("invoice:write"))
+	assert.False(t, session.HasPermission("invoices:write"))
+	assert.True(t, session.HasPermissions(nil))
+	assert.Equal(t, []string{"order:write"}, session.MissingPermissions([]string{"order:read", "order:write", "invoice:read"}))
+
+	admin := sdk.Session{Permissions: []string{sdk.PermissionWildcard}}
+	assert.True(t, admin.HasPermissions([]string{"order:write", "invoice:delete"}))
+}
+
+func TestRolePermissions_Resolve(t *testing.T) {
+	roles := sdk.RolePermissions{
+		"sales":   {"order:read", "order:write"},
+		"auditor": {"order:read", "invoice:read"},
+	}
+	assert.Equal(t,
+		[]string{"customer:read", "order:read", "order:write", "invoice:read"},
+		roles.Resolve(sdk.ParseHeaderList("sales, auditor,unknown"), []string{"customer:read"}),
+	)
+	assert.Equal(t, []string{}, sdk.RolePermissions(nil).Resolve([]string{"sales"}, nil))
+}
+
+func TestRequiredPermissions_RejectedBeforeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	called := false
+	action := sdk.NewConfigurableAction(
+		sdk.EndorHandlerActionOptions{RequiredPermissions: []string{"order:write"}},
+		func(c *sdk.EndorContext[middlewarePayload]) (*sdk.Response[any], error) {
+			called = true
+			return sdk.NewResponseBuilder[any]().Build(), nil
+		},
+	).WithRequiredPermissions("order:approve")
+	assert.Equal(t, []string{"order:write", "order:approve"}, action.GetOptions().RequiredPermissions)
+
+	session := sdk.Session{Locale: "en", Permissions: []string{"order:write"}}
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/order/approve", nil)
+	action.CreateHTTPCallback("sdk", "order", "approve", "", session, testDIContainer{})(c)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	var response sdk.Response[any]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Messages, 1)
+	assert.Equal(t, "You are not allowed to run sdk/order/approve (missing permissions: order:approve)", response.Messages[0].Value)
+
+	_, err := action.Invoke(&sdk.EndorContext[middlewarePayload]{Session: session})
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusForbidden, endorError.StatusCode)
+
+	session.Permissions = append(session.Permissions, "order:approve")
+	_, err = action.Invoke(&sdk.EndorContext[middlewarePayload]{Session: session})
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
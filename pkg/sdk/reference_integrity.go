@@ -0,0 +1,265 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ReferenceDeletePolicy is what happens to the instances referencing an instance being
+// deleted, declared on the reference field with UISchema.OnDelete.
+type ReferenceDeletePolicy string
+
+const (
+	// ReferenceDeleteRestrict rejects the deletion while the instance is referenced.
+	ReferenceDeleteRestrict ReferenceDeletePolicy = "restrict"
+	// ReferenceDeleteCascade deletes the referencing instances too.
+	ReferenceDeleteCascade ReferenceDeletePolicy = "cascade"
+	// ReferenceDeleteSetNull sets the reference field of the referencing instances to null.
+	ReferenceDeleteSetNull ReferenceDeletePolicy = "setNull"
+)
+
+// EntityReference is a field of Entity referencing another entity.
+type EntityReference struct {
+	Entity string
+	// Field is the path of the field, with dots for nested objects and arrays of objects
+	// (e.g. "lines.product").
+	Field string
+	// Array is the path of the array of objects containing Field, empty if none.
+	Array  string
+	Policy ReferenceDeletePolicy
+}
+
+// ReferenceIndex maps each entity to the references to it that declare a delete policy.
+type ReferenceIndex map[string][]EntityReference
+
+// NewReferenceIndex builds the reverse index of the references declared by the schemas of
+// repositories (keyed by entity) of module. Qualified references to module
+// ("module/entity") are indexed by entity, the ones to other modules are skipped since
+// their instances are deleted by other services. Unknown policies are rejected when the
+// schemas are loaded (see RootSchema.ValidateDeletePolicies); the ones of schemas built
+// otherwise are indexed as ReferenceDeleteRestrict.
+func NewReferenceIndex(module string, repositories map[string]EndorRepositoryInterface) ReferenceIndex {
+	index := ReferenceIndex{}
+	for entity, repository := range repositories {
+		schema := repository.GetSchema()
+		if schema == nil {
+			continue
+		}
+		schema.collectReferences(&schema.Schema, module, entity, "", "", index, 0)
+	}
+	for entity := range index {
+		slices.SortFunc(index[entity], func(a, b EntityReference) int {
+			return strings.Compare(a.Entity+"."+a.Field, b.Entity+"."+b.Field)
+		})
+	}
+	return index
+}
+
+// maxReferenceDepth stops the walk of recursive schemas.
+const maxReferenceDepth = 16
+
+func (rs *RootSchema) collectReferences(s *Schema, module, entity, prefix, array string, index ReferenceIndex, depth int) {
+	s = rs.resolveReference(s)
+	if s.Properties == nil || depth > maxReferenceDepth {
+		return
+	}
+	for name, property := range *s.Properties {
+		property := rs.resolveReference(&property)
+		field := prefix + name
+		if property.UISchema != nil && property.UISchema.Entity != nil {
+			if property.UISchema.OnDelete != nil {
+				target := *property.UISchema.Entity
+				if targetModule, parsed, err := ParseEntityID(target); err == nil {
+					if targetModule != module {
+						continue
+					}
+					target = parsed
+				}
+				index[target] = append(index[target], EntityReference{
+					Entity: entity,
+					Field:  field,
+					Array:  array,
+					Policy: property.UISchema.OnDelete.normalize(),
+				})
+			}
+			continue
+		}
+		switch {
+		case property.Items != nil && array == "":
+			rs.collectReferences(property.Items, module, entity, field+".", field, index, depth+1)
+		case property.Items == nil:
+			rs.collectReferences(property, module, entity, field+".", array, index, depth+1)
+		}
+	}
+}
+
+func (p ReferenceDeletePolicy) normalize() ReferenceDeletePolicy {
+	if p.validate("") != nil {
+		return ReferenceDeleteRestrict
+	}
+	return p
+}
+
+// validate returns an error naming field if p is not a known policy.
+func (p ReferenceDeletePolicy) validate(field string) error {
+	switch p {
+	case ReferenceDeleteRestrict, ReferenceDeleteCascade, ReferenceDeleteSetNull:
+		return nil
+	}
+	return fmt.Errorf("field %s: unknown onDelete policy %q (%s, %s or %s)", field, p, ReferenceDeleteRestrict, ReferenceDeleteCascade, ReferenceDeleteSetNull)
+}
+
+// ValidateDeletePolicies returns an error naming the first field, in dot notation, whose
+// onDelete is not a known ReferenceDeletePolicy.
+func (rs *RootSchema) ValidateDeletePolicies() error {
+	return rs.validateDeletePolicies(&rs.Schema, "", 0)
+}
+
+func (rs *RootSchema) validateDeletePolicies(s *Schema, prefix string, depth int) error {
+	s = rs.resolveReference(s)
+	if s.Items != nil {
+		s = rs.resolveReference(s.Items)
+	}
+	if s.Properties == nil || depth > maxReferenceDepth {
+		return nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(*s.Properties)) {
+		property := (*s.Properties)[name]
+		property = *rs.resolveReference(&property)
+		field := prefix + name
+		if property.UISchema != nil && property.UISchema.OnDelete != nil {
+			if err := property.UISchema.OnDelete.validate(field); err != nil {
+				return err
+			}
+		}
+		if err := rs.validateDeletePolicies(&property, field+".", depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReferencesTo returns the references to entity.
+func (i ReferenceIndex) ReferencesTo(entity string) []EntityReference {
+	return i[entity]
+}
+
+// referenceIndexProvider is implemented by the DI containers that keep the index of the
+// references of their repositories.
+type referenceIndexProvider interface {
+	GetReferenceIndex() ReferenceIndex
+}
+
+// ReferenceIndexOf returns the index of the references of the repositories of container,
+// the one kept by the container if any.
+func ReferenceIndexOf(container EndorDIContainerInterface) ReferenceIndex {
+	if container == nil {
+		return nil
+	}
+	if provider, ok := container.(referenceIndexProvider); ok {
+		return provider.GetReferenceIndex()
+	}
+	return NewReferenceIndex(container.GetModule(), container.GetRepositories())
+}
+
+// ReferencingRepositoryInterface is the part of the entity repositories used to enforce the
+// delete policies of their references.
+type ReferencingRepositoryInterface interface {
+	FindReferencing(ctx context.Context, reference EntityReference, id string) ([]string, error)
+	ClearReference(ctx context.Context, reference EntityReference, id string) error
+	Delete(ctx context.Context, dto ReadInstanceDTO) error
+}
+
+type deletingKey struct{}
+
+// deletingInstances are the instances ("entity/id") whose deletion is in progress in a
+// cascade: the ones referencing themselves, or each other in a cycle, are found again by
+// the references to them and must not be deleted twice.
+type deletingInstances map[string]bool
+
+// EnforceReferencesOnDelete applies the delete policies of the references to the instance
+// id of entity, within the transaction of ctx if any. Restricted references fail with a
+// 409 listing the referencing entities; cascading ones delete the referencing instances and
+// set-null ones clear their field. The instances already being deleted by the cascade of
+// ctx, the instance itself included, are skipped.
+func EnforceReferencesOnDelete(ctx context.Context, container EndorDIContainerInterface, entity, id string) error {
+	references := ReferenceIndexOf(container).ReferencesTo(entity)
+	if len(references) == 0 {
+		return nil
+	}
+	deleting, ok := ctx.Value(deletingKey{}).(deletingInstances)
+	if !ok {
+		deleting = deletingInstances{}
+		ctx = context.WithValue(ctx, deletingKey{}, deleting)
+	}
+	deleting[entity+"/"+id] = true
+	repositories := container.GetRepositories()
+	referencing := func(reference EntityReference) (ReferencingRepositoryInterface, []string, error) {
+		repository, ok := repositories[reference.Entity].(ReferencingRepositoryInterface)
+		if !ok {
+			return nil, nil, nil
+		}
+		ids, err := repository.FindReferencing(ctx, reference, id)
+		ids = slices.DeleteFunc(ids, func(referencingId string) bool {
+			return deleting[reference.Entity+"/"+referencingId]
+		})
+		return repository, ids, err
+	}
+
+	blocking := []string{}
+	for _, reference := range references {
+		if reference.Policy != ReferenceDeleteRestrict || slices.Contains(blocking, reference.Entity) {
+			continue
+		}
+		_, ids, err := referencing(reference)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			blocking = append(blocking, reference.Entity)
+		}
+	}
+	if len(blocking) > 0 {
+		entities := strings.Join(blocking, ", ")
+		return NewConflictError(fmt.Errorf("%s %s is referenced by %s", entity, id, entities)).WithTranslation("sdk.entity.messages.delete_restricted", map[string]any{
+			"id":       id,
+			"entities": entities,
+		})
+	}
+
+	for _, reference := range references {
+		switch reference.Policy {
+		case ReferenceDeleteCascade:
+			repository, ids, err := referencing(reference)
+			if err != nil {
+				return err
+			}
+			for _, referencingId := range ids {
+				if err := repository.Delete(ctx, ReadInstanceDTO{Id: referencingId}); err != nil {
+					return err
+				}
+			}
+		case ReferenceDeleteSetNull:
+			if repository, ok := repositories[reference.Entity].(ReferencingRepositoryInterface); ok {
+				if err := repository.ClearReference(ctx, reference, id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// HasWritingReferences reports whether deleting an instance of entity may write other
+// instances (cascade or set-null references), so that it needs a transaction.
+func HasWritingReferences(container EndorDIContainerInterface, entity string) bool {
+	for _, reference := range ReferenceIndexOf(container).ReferencesTo(entity) {
+		if reference.Policy != ReferenceDeleteRestrict {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type referenceLine struct {
+	Product string `json:"product" ui-schema:"entity=product,onDelete=setNull"`
+}
+
+type referenceOrder struct {
+	Id       string          `json:"id"`
+	Customer string          `json:"customer" ui-schema:"entity=test/customer,onDelete=cascade"`
+	Seller   string          `json:"seller" ui-schema:"entity=customer"`
+	Payer    string          `json:"payer" ui-schema:"entity=billing/customer,onDelete=cascade"`
+	Lines    []referenceLine `json:"lines"`
+}
+
+type referenceInvoice struct {
+	Id       string `json:"id"`
+	Customer string `json:"customer" ui-schema:"entity=customer,onDelete=restrict"`
+}
+
+// referenceRepository keeps the ids of the instances referencing each id, by field.
+type referenceRepository struct {
+	schema      *sdk.RootSchema
+	referencing map[string]map[string][]string
+	deleted     []string
+	cleared     []string
+}
+
+func (r *referenceRepository) FindReferences(ctx context.Context, dto sdk.ReadInstancesDTO) (sdk.EntityReferenceGroupDescriptions, error) {
+	return nil, nil
+}
+func (r *referenceRepository) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	return nil, nil
+}
+func (r *referenceRepository) GetEntity() string { return "" }
+func (r *referenceRepository) RawList(ctx context.Context, dto sdk.ReadDTO) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+func (r *referenceRepository) GetSchema() *sdk.RootSchema { return r.schema }
+
+func (r *referenceRepository) FindReferencing(ctx context.Context, reference sdk.EntityReference, id string) ([]string, error) {
+	ids := []string{}
+	for _, referencingId := range r.referencing[reference.Field][id] {
+		if !slices.Contains(r.deleted, referencingId) {
+			ids = append(ids, referencingId)
+		}
+	}
+	return ids, nil
+}
+
+func (r *referenceRepository) ClearReference(ctx context.Context, reference sdk.EntityReference, id string) error {
+	r.cleared = append(r.cleared, reference.Field+"="+id)
+	return nil
+}
+
+func (r *referenceRepository) Delete(ctx context.Context, dto sdk.ReadInstanceDTO) error {
+	r.deleted = append(r.deleted, dto.Id)
+	return nil
+}
+
+type referenceContainer struct {
+	repositories map[string]sdk.EndorRepositoryInterface
+	// forbidden are the actions the sessions may not run
+	forbidden []string
+	resolver  sdk.ReferenceResolver
+}
+
+func (c referenceContainer) GetRepositories() map[string]sdk.EndorRepositoryInterface {
+	return c.repositories
+}
+func (referenceContainer) GetModule() string                             { return "test" }
+func (referenceContainer) GetTranslator() *sdk_i18n.Translator           { return sdk_i18n.NewTranslator(nil) }
+func (referenceContainer) GetEventBus() *sdk.EventBus                    { return nil }
+func (referenceContainer) GetJobManager() *sdk.JobManager                { return nil }
+func (referenceContainer) GetOutbox() *sdk.Outbox                        { return nil }
+func (referenceContainer) GetWebhookDispatcher() *sdk.WebhookDispatcher  { return nil }
+func (referenceContainer) GetAuditTrail() *sdk.AuditTrail                { return nil }
+func (c referenceContainer) GetReferenceResolver() sdk.ReferenceResolver { return c.resolver }
+func (c referenceContainer) AuthorizeAction(session sdk.Session, actionId string) error {
+	if slices.Contains(c.forbidden, actionId) {
+		return session.AuthorizeAction(actionId, []string{actionId})
+	}
+	return nil
+}
+func (referenceContainer) InvokeAction(ctx sdk.EndorContextInterface, actionId string, payload any) (any, error) {
+	return nil, nil
+}
+
+func TestNewReferenceIndex(t *testing.T) {
+	index := sdk.NewReferenceIndex("test", map[string]sdk.EndorRepositoryInterface{
+		"order":   &referenceRepository{schema: sdk.NewSchema(referenceOrder{})},
+		"invoice": &referenceRepository{schema: sdk.NewSchema(referenceInvoice{})},
+	})
+
+	assert.Equal(t, []sdk.EntityReference{
+		{Entity: "invoice", Field: "customer", Policy: sdk.ReferenceDeleteRestrict},
+		{Entity: "order", Field: "customer", Policy: sdk.ReferenceDeleteCascade},
+	}, index.ReferencesTo("customer"), "references without policy or to other modules are not indexed")
+	assert.Equal(t, []sdk.EntityReference{
+		{Entity: "order", Field: "lines.product", Array: "lines", Policy: sdk.ReferenceDeleteSetNull},
+	}, index.ReferencesTo("product"))
+}
+
+func TestEnforceReferencesOnDelete(t *testing.T) {
+	orders := &referenceRepository{
+		schema: sdk.NewSchema(referenceOrder{}),
+		referencing: map[string]map[string][]string{
+			"customer":      {"c1": {"o1", "o2"}},
+			"lines.product": {"p1": {"o3"}},
+		},
+	}
+	invoices := &referenceRepository{
+		schema:      sdk.NewSchema(referenceInvoice{}),
+		referencing: map[string]map[string][]string{"customer": {"c2": {"i1"}}},
+	}
+	container := referenceContainer{repositories: map[string]sdk.EndorRepositoryInterface{
+		"order":   orders,
+		"invoice": invoices,
+	}}
+
+	t.Run("restrict", func(t *testing.T) {
+		err := sdk.EnforceReferencesOnDelete(context.Background(), container, "customer", "c2")
+		var endorError *sdk.EndorError
+		require.ErrorAs(t, err, &endorError)
+		assert.Equal(t, http.StatusConflict, endorError.StatusCode)
+		assert.Equal(t, "sdk.entity.messages.delete_restricted", endorError.TranslationKey)
+		assert.Equal(t, "invoice", endorError.TranslationArgs["entities"])
+		assert.Empty(t, orders.deleted, "nothing is cascaded when the deletion is restricted")
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		require.NoError(t, sdk.EnforceReferencesOnDelete(context.Background(), container, "customer", "c1"))
+		assert.Equal(t, []string{"o1", "o2"}, orders.deleted)
+	})
+
+	t.Run("set null", func(t *testing.T) {
+		require.NoError(t, sdk.EnforceReferencesOnDelete(context.Background(), container, "product", "p1"))
+		assert.Equal(t, []string{"lines.product=p1"}, orders.cleared)
+	})
+
+	assert.True(t, sdk.HasWritingReferences(container, "customer"))
+	assert.False(t, sdk.HasWritingReferences(container, "invoice"))
+}
+
+type referenceCategory struct {
+	Id     string `json:"id"`
+	Parent string `json:"parent" ui-schema:"entity=category,onDelete=cascade"`
+}
+
+// cascadingRepository enforces the references to its instances on Delete, like the Mongo
+// repositories, and fails if the cascade recurses too deep.
+type cascadingRepository struct {
+	referenceRepository
+	container sdk.EndorDIContainerInterface
+	depth     int
+}
+
+func (r *cascadingRepository) Delete(ctx context.Context, dto sdk.ReadInstanceDTO) error {
+	if r.depth++; r.depth > 5 {
+		return errors.New("the cascade does not end")
+	}
+	defer func() { r.depth-- }()
+	if err := sdk.EnforceReferencesOnDelete(ctx, r.container, "category", dto.Id); err != nil {
+		return err
+	}
+	return r.referenceRepository.Delete(ctx, dto)
+}
+
+func TestEnforceReferencesOnDelete_Cycles(t *testing.T) {
+	categories := &cascadingRepository{referenceRepository: referenceRepository{
+		schema: sdk.NewSchema(referenceCategory{}),
+		referencing: map[string]map[string][]string{"parent": {
+			"k1": {"k1"},
+			"k2": {"k3"},
+			"k3": {"k2"},
+		}},
+	}}
+	categories.container = referenceContainer{repositories: map[string]sdk.EndorRepositoryInterface{"category": categories}}
+
+	require.NoError(t, categories.Delete(context.Background(), sdk.ReadInstanceDTO{Id: "k1"}))
+	assert.Equal(t, []string{"k1"}, categories.deleted, "an instance referencing itself is deleted once")
+
+	require.NoError(t, categories.Delete(context.Background(), sdk.ReadInstanceDTO{Id: "k2"}))
+	assert.Equal(t, []string{"k1", "k3", "k2"}, categories.deleted, "the instances of a cycle are deleted once")
+}
+
+func TestDeletePolicies_Unknown(t *testing.T) {
+	type typo struct {
+		Customer string `json:"customer" ui-schema:"entity=customer,onDelete=cascde"`
+	}
+	assert.PanicsWithValue(t, `ui-schema: field Customer: unknown onDelete policy "cascde" (restrict, cascade or setNull)`, func() {
+		sdk.NewSchema(typo{})
+	})
+
+	var schema sdk.RootSchema
+	require.NoError(t, yaml.Unmarshal([]byte(`
+type: object
+properties:
+  lines:
+    type: array
+    items:
+      type: object
+      properties:
+        product:
+          type: string
+          x-ui:
+            entity: product
+            onDelete: setnull
+`), &schema))
+	assert.EqualError(t, schema.ValidateDeletePolicies(), `field lines.product: unknown onDelete policy "setnull" (restrict, cascade or setNull)`)
+	assert.NoError(t, sdk.NewSchema(referenceOrder{}).ValidateDeletePolicies())
+}
@@ -0,0 +1,64 @@
+package sdk
+
+import (
+	"context"
+)
+
+// ReferenceResolver resolves the descriptions of the references to the entities of other
+// modules, owned by other services (see EndorDIContainerInterface.GetReferenceResolver).
+type ReferenceResolver interface {
+	// ResolveReferences returns the descriptions of the instances of entityId
+	// ("module/entity") with the given ids; ids not found are left out.
+	ResolveReferences(ctx context.Context, entityId string, ids []string) (EntityReferenceGroupDescriptions, error)
+}
+
+// failureLoggingResolver is implemented by the resolvers logging their own failures, which
+// ResolveEntityReferences then does not log again.
+type failureLoggingResolver interface {
+	LogsFailures() bool
+}
+
+// ReadReferencesDTO is the payload of the references action of the entity handler, which
+// resolves the references to the entities of its module for the other services.
+type ReadReferencesDTO struct {
+	Entity string   `json:"entity" binding:"required"`
+	Ids    []string `json:"ids"`
+}
+
+// ResolveEntityReferences returns the descriptions of the referenced ids, by entity as
+// declared by UISchema.Entity. References to the entities of the service are resolved with
+// FindReferences of the repositories of container, the others with the ReferenceResolver
+// of container, one call per entity. References that cannot be resolved, including those
+// failed by the resolver, are left out: they only describe the instances. The failures of
+// the resolver are logged, unless it logs them itself.
+func ResolveEntityReferences(ctx context.Context, container EndorDIContainerInterface, entityIDs map[string][]string) (EntityRefererenceGroup, error) {
+	references := make(EntityRefererenceGroup)
+	resolver, module := container.GetReferenceResolver(), container.GetModule()
+	for entityName, ids := range entityIDs {
+		entityModule, entity, err := ParseEntityID(entityName)
+		if err != nil {
+			entity = entityName
+		}
+		if resolver != nil && entityModule != "" && entityModule != module {
+			descriptions, err := resolver.ResolveReferences(ctx, entityName, ids)
+			if err != nil {
+				if logging, ok := resolver.(failureLoggingResolver); !ok || !logging.LogsFailures() {
+					NewLogger(LogConfig{}, LogContext{}).WarnWithFields("Failed to resolve remote references", map[string]interface{}{"entity": entityName, "error": err.Error()})
+				}
+				continue
+			}
+			references[entityName] = descriptions
+			continue
+		}
+		repository, found := container.GetRepositories()[entity]
+		if !found {
+			continue
+		}
+		descriptions, err := repository.FindReferences(ctx, ReadInstancesDTO{Ids: ids})
+		if err != nil {
+			return nil, err
+		}
+		references[entityName] = descriptions
+	}
+	return references, nil
+}
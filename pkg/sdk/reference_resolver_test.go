@@ -0,0 +1,70 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// describedRepository describes every id as "<prefix><id>".
+type describedRepository struct {
+	referenceRepository
+	prefix string
+}
+
+func (r *describedRepository) FindReferences(ctx context.Context, dto sdk.ReadInstancesDTO) (sdk.EntityReferenceGroupDescriptions, error) {
+	descriptions := sdk.EntityReferenceGroupDescriptions{}
+	for _, id := range dto.Ids {
+		descriptions[id] = r.prefix + id
+	}
+	return descriptions, nil
+}
+
+type remoteResolver struct {
+	calls []string
+}
+
+func (r *remoteResolver) ResolveReferences(ctx context.Context, entityId string, ids []string) (sdk.EntityReferenceGroupDescriptions, error) {
+	r.calls = append(r.calls, entityId)
+	if entityId == "crm/lead" {
+		return nil, errors.New("crm unavailable")
+	}
+	return sdk.EntityReferenceGroupDescriptions{ids[0]: "remote " + ids[0]}, nil
+}
+
+func TestResolveEntityReferences(t *testing.T) {
+	container := referenceContainer{repositories: map[string]sdk.EndorRepositoryInterface{
+		"customer": &describedRepository{prefix: "customer "},
+		"invoice":  &describedRepository{prefix: "local invoice "},
+	}}
+	entityIDs := map[string][]string{
+		"test/customer":   {"c1"},
+		"customer":        {"c2"},
+		"billing/invoice": {"i1"},
+		"crm/lead":        {"l1"},
+	}
+
+	references, err := sdk.ResolveEntityReferences(context.Background(), container, entityIDs)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.EntityRefererenceGroup{
+		"test/customer":   {"c1": "customer c1"},
+		"customer":        {"c2": "customer c2"},
+		"billing/invoice": {"i1": "local invoice i1"},
+	}, references, "without a resolver every reference is resolved locally")
+
+	resolver := &remoteResolver{}
+	container.resolver = resolver
+
+	references, err = sdk.ResolveEntityReferences(context.Background(), container, entityIDs)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.EntityRefererenceGroup{
+		"test/customer":   {"c1": "customer c1"},
+		"customer":        {"c2": "customer c2"},
+		"billing/invoice": {"i1": "remote i1"},
+	}, references, "the references the resolver fails are left out")
+	assert.ElementsMatch(t, []string{"billing/invoice", "crm/lead"}, resolver.calls)
+}
@@ -10,6 +10,18 @@ type EntityInstanceRepositoryOptions struct {
 	// When true (default): Empty IDs are auto-generated using primitive.ObjectID.Hex()
 	// When false: IDs must be provided by the user, empty IDs cause BadRequestError
 	AutoGenerateID *bool
+	// Versioned makes the repository keep a version field in every instance, starting at 1
+	// and incremented by every update: writes with an expected version fail with a conflict
+	// if the instance has been changed in the meantime.
+	Versioned bool
+	// History records every write of the instances, with its author and changes, in the
+	// <entity>__history collection (see HistoryEntry); History, HistoryVersion and Revert
+	// fail on repositories without it.
+	History bool
+	// SoftDelete makes Delete mark the instance with DeletedAtField and DeletedByField instead
+	// of removing it: deleted instances are hidden from reads unless IncludeDeleted is set,
+	// and can be brought back with Restore.
+	SoftDelete bool
 }
 
 // RepositoryInterface defines the common operations shared by all repository types.
@@ -17,6 +29,9 @@ type EntityInstanceRepositoryOptions struct {
 // common operations (e.g. resolving references) without knowing the concrete repository type.
 type EndorRepositoryInterface interface {
 	FindReferences(ctx context.Context, ids ReadInstancesDTO) (EntityReferenceGroupDescriptions, error)
+	// FindDocuments returns the instances with the given ids as JSON documents keyed by id
+	// (see ExpandReferences).
+	FindDocuments(ctx context.Context, dto ReadInstancesDTO) (map[string]map[string]interface{}, error)
 	GetEntity() string
 	RawList(ctx context.Context, dto ReadDTO) ([]map[string]interface{}, error)
 	GetSchema() *RootSchema
@@ -29,9 +44,26 @@ type EntityInstanceRepositoryInterface[T EntityInstanceInterface] interface {
 	Create(ctx context.Context, dto CreateDTO[EntityInstance[T]]) (*EntityInstance[T], error)
 	Delete(ctx context.Context, dto ReadInstanceDTO) error
 	Update(ctx context.Context, dto UpdateByIdDTO[PartialEntityInstance[T]]) (*EntityInstance[T], error)
+	// Restore brings back an instance removed by Delete on a soft-delete repository.
+	Restore(ctx context.Context, dto ReadInstanceDTO) (*EntityInstance[T], error)
+	// History returns the versions of the instance, oldest first, without their snapshots.
+	History(ctx context.Context, dto ReadInstanceDTO) ([]HistoryEntry, error)
+	// HistoryVersion returns a version of the instance with its snapshot.
+	HistoryVersion(ctx context.Context, dto ReadHistoryVersionDTO) (*HistoryEntry, error)
+	// Revert writes the snapshot of a version back to the instance, recreating it if deleted.
+	Revert(ctx context.Context, dto ReadHistoryVersionDTO) (*EntityInstance[T], error)
+	// FindReferencing returns the ids of the instances whose reference field holds id.
+	FindReferencing(ctx context.Context, reference EntityReference, id string) ([]string, error)
+	// ClearReference sets to null the reference field of the instances holding id.
+	ClearReference(ctx context.Context, reference EntityReference, id string) error
 
 	InstanceWithReferences(ctx context.Context, dto ReadInstanceDTO) (*EntityInstance[T], EntityRefererenceGroup, error)
 	ListWithReferences(ctx context.Context, dto ReadDTO) ([]EntityInstance[T], EntityRefererenceGroup, error)
+	// ListPage is ListWithReferences plus the pagination of the result (see Pagination).
+	ListPage(ctx context.Context, dto ReadDTO) ([]EntityInstance[T], EntityRefererenceGroup, *Pagination, error)
+	// StreamWithReferences calls yield for every instance matching dto without loading them
+	// all in memory, and returns the references of all of them once done.
+	StreamWithReferences(ctx context.Context, dto ReadDTO, yield func(instance EntityInstance[T]) error) (EntityRefererenceGroup, error)
 }
 
 // StaticEntityInstanceRepositoryOptions defines configuration options for StaticEntityInstanceRepository
@@ -41,6 +73,13 @@ type StaticEntityInstanceRepositoryOptions[T EntityInstanceInterface] struct {
 	// When true (default): Empty IDs are auto-generated using primitive.ObjectID.Hex()
 	// When false: IDs must be provided by the user, empty IDs cause BadRequestError
 	AutoGenerateID *bool
+	// Versioned mirrors EntityInstanceRepositoryOptions.Versioned; the version is returned
+	// only by models with a version field.
+	Versioned bool
+	// SoftDelete mirrors EntityInstanceRepositoryOptions.SoftDelete.
+	SoftDelete bool
+	// History mirrors EntityInstanceRepositoryOptions.History.
+	History bool
 
 	Hooks StaticEntityInstanceRepositoryOptionsHooks[T]
 }
@@ -59,17 +98,50 @@ type StaticEntityInstanceRepositoryInterface[T EntityInstanceInterface] interfac
 	Create(ctx context.Context, dto CreateDTO[T]) (T, error)
 	Delete(ctx context.Context, dto ReadInstanceDTO) error
 	Update(ctx context.Context, dto UpdateByIdDTO[map[string]interface{}]) (T, error)
+	// Restore brings back an instance removed by Delete on a soft-delete repository.
+	Restore(ctx context.Context, dto ReadInstanceDTO) (T, error)
+	// History returns the versions of the instance, oldest first, without their snapshots.
+	History(ctx context.Context, dto ReadInstanceDTO) ([]HistoryEntry, error)
+	// HistoryVersion returns a version of the instance with its snapshot.
+	HistoryVersion(ctx context.Context, dto ReadHistoryVersionDTO) (*HistoryEntry, error)
+	// Revert writes the snapshot of a version back to the instance, recreating it if deleted.
+	Revert(ctx context.Context, dto ReadHistoryVersionDTO) (T, error)
+	// FindReferencing returns the ids of the instances whose reference field holds id.
+	FindReferencing(ctx context.Context, reference EntityReference, id string) ([]string, error)
+	// ClearReference sets to null the reference field of the instances holding id.
+	ClearReference(ctx context.Context, reference EntityReference, id string) error
 
 	InstanceWithReferences(ctx context.Context, dto ReadInstanceDTO) (T, EntityRefererenceGroup, error)
 	ListWithReferences(ctx context.Context, dto ReadDTO) ([]T, EntityRefererenceGroup, error)
+	// ListPage is ListWithReferences plus the pagination of the result (see Pagination).
+	ListPage(ctx context.Context, dto ReadDTO) ([]T, EntityRefererenceGroup, *Pagination, error)
+	// StreamWithReferences calls yield for every instance matching dto without loading them
+	// all in memory, and returns the references of all of them once done.
+	StreamWithReferences(ctx context.Context, dto ReadDTO, yield func(instance T) error) (EntityRefererenceGroup, error)
 }
 
 type ReadInstanceDTO struct {
 	Id string `json:"id,omitempty"`
+	// Version is the expected version of a versioned instance for Delete; reads ignore it.
+	Version *int64 `json:"version,omitempty"`
+	// IncludeDeleted also finds soft-deleted instances.
+	IncludeDeleted bool `json:"includeDeleted,omitempty"`
+	// Expand, ExpandDepth and ExpandProjection embed the referenced documents in the
+	// response, as in ReadDTO.
+	Expand           []string            `json:"expand,omitempty"`
+	ExpandDepth      int                 `json:"expandDepth,omitempty"`
+	ExpandProjection map[string][]string `json:"expandProjection,omitempty"`
+}
+
+// ExpandOptions returns the expansion asked by dto.
+func (dto ReadInstanceDTO) ExpandOptions() ExpandOptions {
+	return ExpandOptions{Fields: dto.Expand, Depth: dto.ExpandDepth, Projection: dto.ExpandProjection}
 }
 
 type ReadInstancesDTO struct {
 	Ids []string `json:"ids,omitempty"`
+	// Fields restricts FindDocuments to the given top-level fields, all of them if empty.
+	Fields []string `json:"fields,omitempty"`
 }
 
 type CreateDTO[T any] struct {
@@ -79,12 +151,65 @@ type CreateDTO[T any] struct {
 type ReadDTO struct {
 	Filter     map[string]interface{} `json:"filter"`
 	Projection map[string]interface{} `json:"projection"`
+	// Sort orders the results by the given fields, in order of priority. Paged lists are
+	// also ordered by _id, so that pages never overlap.
+	Sort []SortField `json:"sort,omitempty"`
+	// Skip and Limit select a page by offset; Limit 0 means no limit.
+	Skip  int64 `json:"skip,omitempty"`
+	Limit int64 `json:"limit,omitempty"`
+	// After is the Pagination.Next cursor of the previous page: the results start after the
+	// last instance of that page. It must be used with the same Filter and Sort.
+	After string `json:"after,omitempty"`
+	// Count asks for the total number of instances matching Filter in Pagination.Total.
+	Count bool `json:"count,omitempty"`
+	// IncludeDeleted also lists soft-deleted instances.
+	IncludeDeleted bool `json:"includeDeleted,omitempty"`
+	// Expand embeds in the response the documents referenced by the given reference fields
+	// (dot notation for nested objects and arrays of objects, e.g. "lines.productId").
+	Expand []string `json:"expand,omitempty"`
+	// ExpandDepth also expands the references of the embedded documents, up to the given
+	// number of levels: 1 (the default) embeds only the documents referenced by Expand.
+	ExpandDepth int `json:"expandDepth,omitempty"`
+	// ExpandProjection restricts the embedded documents of an entity to the given top-level
+	// fields (and id), by entity.
+	ExpandProjection map[string][]string `json:"expandProjection,omitempty"`
+}
+
+// ExpandOptions returns the expansion asked by dto.
+func (dto ReadDTO) ExpandOptions() ExpandOptions {
+	return ExpandOptions{Fields: dto.Expand, Depth: dto.ExpandDepth, Projection: dto.ExpandProjection}
+}
+
+// SortField orders a list by Field (a stored field, dot notation allowed).
+type SortField struct {
+	Field     string        `json:"field"`
+	Direction SortDirection `json:"direction,omitempty"`
+}
+
+// SortDirection is 1 (or 0) for ascending and -1 for descending order.
+type SortDirection int
+
+const (
+	SortAscending  SortDirection = 1
+	SortDescending SortDirection = -1
+)
+
+// Pagination describes the page returned by a list.
+type Pagination struct {
+	// Total is the number of instances matching the filter, set when ReadDTO.Count is.
+	Total *int64 `json:"total,omitempty"`
+	// Next is the ReadDTO.After cursor of the next page, set when a page limited by
+	// ReadDTO.Limit is full (the next page may still be empty).
+	Next string `json:"next,omitempty"`
 }
 
 // UpdateById defines the structure for updates with a generic data type
 type UpdateByIdDTO[T any] struct {
 	Id   string `json:"id,omitempty"`
 	Data T      `json:"data" binding:"required"`
+	// Version is the expected version of a versioned instance: the update fails with a
+	// conflict if the instance has a different one.
+	Version *int64 `json:"version,omitempty"`
 }
 
 // #region Entity References
@@ -6,6 +6,13 @@ type Response[T any] struct {
 	Data       *T                      `json:"data"`
 	Schema     *RootSchema             `json:"schema"`
 	References *EntityRefererenceGroup `json:"references"`
+	Pagination *Pagination             `json:"pagination,omitempty"`
+	// Expanded contains the referenced documents asked with ReadDTO.Expand
+	Expanded EntityExpansionGroup `json:"expanded,omitempty"`
+	// stream replaces Data when the client asked for a streamed response (see AddStream)
+	stream ResponseStream
+	// headers are added to the HTTP response (see AddHeader)
+	headers map[string]string
 }
 
 // ResponseBuilder with Generics
@@ -53,6 +60,36 @@ func (h *ResponseBuilder[T]) AddReferences(references EntityRefererenceGroup) *R
 	return h
 }
 
+// AddExpanded attaches the referenced documents expanded by ExpandReferences.
+func (h *ResponseBuilder[T]) AddExpanded(expanded EntityExpansionGroup) *ResponseBuilder[T] {
+	h.response.Expanded = expanded
+	return h
+}
+
+// AddPagination attaches the pagination of a list; a nil pagination is ignored.
+func (h *ResponseBuilder[T]) AddPagination(pagination *Pagination) *ResponseBuilder[T] {
+	if pagination != nil {
+		h.response.Pagination = pagination
+	}
+	return h
+}
+
+// AddHeader sets a header of the HTTP response, e.g. the ETag of an instance.
+func (h *ResponseBuilder[T]) AddHeader(key string, value string) *ResponseBuilder[T] {
+	if h.response.headers == nil {
+		h.response.headers = map[string]string{}
+	}
+	h.response.headers[key] = value
+	return h
+}
+
+// AddStream makes the response streamed: the HTTP callback writes it as NDJSON, calling
+// stream while writing instead of holding all the items in Data.
+func (h *ResponseBuilder[T]) AddStream(stream ResponseStream) *ResponseBuilder[T] {
+	h.response.stream = stream
+	return h
+}
+
 func (h *ResponseBuilder[T]) Build() *Response[T] {
 	return &h.response
 }
@@ -79,6 +116,26 @@ func NewMessage(gravity ResponseMessageGravity, value string) ResponseMessage {
 	}
 }
 
+// GetMessages returns the messages attached to the response.
+func (r *Response[T]) GetMessages() []ResponseMessage {
+	return r.Messages
+}
+
+// AppendMessage attaches a message to an already built response.
+func (r *Response[T]) AppendMessage(message ResponseMessage) {
+	r.Messages = append(r.Messages, message)
+}
+
+// Headers returns the headers added to the HTTP response.
+func (r *Response[T]) Headers() map[string]string {
+	return r.headers
+}
+
+// IsStream reports whether the response is streamed (see AddStream).
+func (r *Response[T]) IsStream() bool {
+	return r.stream != nil
+}
+
 // ResolveTranslations resolves t(key) tokens in the schema (if present).
 func (r *Response[T]) ResolveTranslations(resolveExpr func(string) string) {
 	if r.Schema != nil {
@@ -0,0 +1,139 @@
+package sdk
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Schedule declares a periodic invocation of an action of the handler, run with the
+// system session.
+type Schedule struct {
+	// Action is the action name within the entity (e.g. "cleanup" or "<category>/<action>").
+	Action string `json:"action" yaml:"action"`
+	// Cron is a five fields expression (minute hour day-of-month month day-of-week),
+	// e.g. "0 2 * * *", or one of @yearly, @monthly, @weekly, @daily, @hourly.
+	Cron string `json:"cron" yaml:"cron"`
+	// Timezone is the IANA location the expression is evaluated in, UTC if empty.
+	Timezone string         `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Payload  map[string]any `json:"payload,omitempty" yaml:"payload,omitempty"`
+}
+
+// ScheduleReadPermission is required to read the schedules and their runs.
+const ScheduleReadPermission = "schedule:read"
+
+// SystemSession is the session of the invocations started by the SDK itself, such as the
+// scheduled actions. It is granted every permission.
+func SystemSession() Session {
+	return Session{
+		Id:          "system",
+		UserId:      "system",
+		Username:    "system",
+		Locale:      "en",
+		Permissions: []string{PermissionWildcard},
+	}
+}
+
+// ScheduledAction is a schedule declared by a handler, as listed by the schedule entity.
+type ScheduledAction struct {
+	// Id is <actionId>@<cron>.
+	Id       string `json:"id" schema:"title=${t.sdk.schedule.fields.id},readOnly=true"`
+	ActionId string `json:"actionId" schema:"title=${t.sdk.schedule.fields.action_id},readOnly=true"`
+	Cron     string `json:"cron" schema:"title=${t.sdk.schedule.fields.cron},readOnly=true"`
+	Timezone string `json:"timezone,omitempty" schema:"title=${t.sdk.schedule.fields.timezone},readOnly=true"`
+	// NextRun is nil if the expression is invalid or never fires.
+	NextRun *time.Time `json:"nextRun,omitempty" schema:"title=${t.sdk.schedule.fields.next_run},readOnly=true"`
+}
+
+func (s *ScheduledAction) GetID() any {
+	return s.Id
+}
+
+// #region Schedule runs
+
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunStatusRunning ScheduleRunStatus = "running"
+	ScheduleRunStatusDone    ScheduleRunStatus = "done"
+	ScheduleRunStatusFailed  ScheduleRunStatus = "failed"
+)
+
+// ScheduleRun records a tick of a schedule, fired by a single instance of the service.
+type ScheduleRun struct {
+	// Id is unique per schedule and tick (<scheduleId>@<tick>).
+	Id         string            `json:"id" bson:"_id" schema:"title=${t.sdk.schedule.fields.id},readOnly=true"`
+	ScheduleId string            `json:"scheduleId" bson:"scheduleId" schema:"title=${t.sdk.schedule.fields.schedule_id},readOnly=true"`
+	ActionId   string            `json:"actionId" bson:"actionId" schema:"title=${t.sdk.schedule.fields.action_id},readOnly=true"`
+	Tick       time.Time         `json:"tick" bson:"tick" schema:"title=${t.sdk.schedule.fields.tick},readOnly=true"`
+	Instance   string            `json:"instance" bson:"instance" schema:"title=${t.sdk.schedule.fields.instance},readOnly=true"`
+	Status     ScheduleRunStatus `json:"status" bson:"status" schema:"title=${t.sdk.schedule.fields.status},enum=running|done|failed,readOnly=true"`
+	Messages   []ResponseMessage `json:"messages" bson:"messages" schema:"title=${t.sdk.schedule.fields.messages},readOnly=true"`
+	StartedAt  time.Time         `json:"startedAt" bson:"startedAt" schema:"title=${t.sdk.schedule.fields.started_at},readOnly=true"`
+	// CompletedAt is nil while running or if the instance stopped during the run.
+	CompletedAt *time.Time `json:"completedAt,omitempty" bson:"completedAt,omitempty" schema:"title=${t.sdk.schedule.fields.completed_at},readOnly=true"`
+}
+
+func (r *ScheduleRun) GetID() any {
+	return r.Id
+}
+
+// ReadScheduleRunsDTO selects the runs of a schedule, or of every schedule if empty.
+type ReadScheduleRunsDTO struct {
+	ScheduleId string `json:"scheduleId,omitempty"`
+}
+
+// ScheduleRunRepositoryInterface stores the run history. Acquire is the lease that makes
+// a single instance fire each tick: it creates the run only if no run with the same Id exists.
+type ScheduleRunRepositoryInterface interface {
+	Acquire(ctx context.Context, run ScheduleRun) (bool, error)
+	Update(ctx context.Context, run ScheduleRun) error
+	// List returns the last runs, most recent tick first.
+	List(ctx context.Context, dto ReadScheduleRunsDTO) ([]ScheduleRun, error)
+}
+
+// InMemoryScheduleRunRepository keeps the run history in memory; the lease only holds
+// within a single process, so it is meant for tests and local development.
+type InMemoryScheduleRunRepository struct {
+	mu   sync.RWMutex
+	runs map[string]ScheduleRun
+}
+
+func NewInMemoryScheduleRunRepository() *InMemoryScheduleRunRepository {
+	return &InMemoryScheduleRunRepository{runs: map[string]ScheduleRun{}}
+}
+
+func (r *InMemoryScheduleRunRepository) Acquire(ctx context.Context, run ScheduleRun) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.runs[run.Id]; exists {
+		return false, nil
+	}
+	r.runs[run.Id] = run
+	return true, nil
+}
+
+func (r *InMemoryScheduleRunRepository) Update(ctx context.Context, run ScheduleRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.Id] = run
+	return nil
+}
+
+func (r *InMemoryScheduleRunRepository) List(ctx context.Context, dto ReadScheduleRunsDTO) ([]ScheduleRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	runs := []ScheduleRun{}
+	for _, run := range r.runs {
+		if dto.ScheduleId == "" || run.ScheduleId == dto.ScheduleId {
+			runs = append(runs, run)
+		}
+	}
+	slices.SortFunc(runs, func(a, b ScheduleRun) int {
+		return b.Tick.Compare(a.Tick)
+	})
+	return runs, nil
+}
+
+// #endregion
@@ -2,8 +2,10 @@ package sdk
 
 import (
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -70,12 +72,14 @@ type Schema struct {
 }
 
 type UISchema struct {
-	Entity               *string   `json:"entity,omitempty" yaml:"entity,omitempty"`                             // define the reference entity
-	Query                *string   `json:"query,omitempty" yaml:"query,omitempty"`                               // define the query to get the data of reference entity "$filter() $projection()"
-	Order                *[]string `json:"order,omitempty" yaml:"order,omitempty"`                               // define the order of the attributes
-	Hidden               *bool     `json:"hidden,omitempty" yaml:"hidden,omitempty"`                             // define if the property is displayable
-	EntityIdKey          *string   `json:"entityIdKey,omitempty" yaml:"entityIdKey,omitempty"`                   // define which property is the entity id key
-	EntityDescriptionKey *string   `json:"entityDescriptionKey,omitempty" yaml:"entityDescriptionKey,omitempty"` // define which property is the entity description key
+	Entity                    *string                `json:"entity,omitempty" yaml:"entity,omitempty"`                                       // define the reference entity
+	Query                     *string                `json:"query,omitempty" yaml:"query,omitempty"`                                         // define the query to get the data of reference entity "$filter() $projection()"
+	Order                     *[]string              `json:"order,omitempty" yaml:"order,omitempty"`                                         // define the order of the attributes
+	Hidden                    *bool                  `json:"hidden,omitempty" yaml:"hidden,omitempty"`                                       // define if the property is displayable
+	EntityIdKey               *string                `json:"entityIdKey,omitempty" yaml:"entityIdKey,omitempty"`                             // define which property is the entity id key
+	EntityDescriptionKey      *string                `json:"entityDescriptionKey,omitempty" yaml:"entityDescriptionKey,omitempty"`           // define which property is the entity description key
+	EntityDescriptionTemplate *string                `json:"entityDescriptionTemplate,omitempty" yaml:"entityDescriptionTemplate,omitempty"` // define the entity description from several properties, e.g. "{{name}} {{surname}}"
+	OnDelete                  *ReferenceDeletePolicy `json:"onDelete,omitempty" yaml:"onDelete,omitempty"`                                   // define the policy of the reference when the referenced instance is deleted
 }
 
 type RootSchema struct {
@@ -294,6 +298,9 @@ func buildExpandedSchema(t reflect.Type, visited map[string]bool) Schema {
 					if embeddedSchema.UISchema.EntityDescriptionKey != nil && schema.UISchema.EntityDescriptionKey == nil {
 						schema.UISchema.EntityDescriptionKey = embeddedSchema.UISchema.EntityDescriptionKey
 					}
+					if embeddedSchema.UISchema.EntityDescriptionTemplate != nil && schema.UISchema.EntityDescriptionTemplate == nil {
+						schema.UISchema.EntityDescriptionTemplate = embeddedSchema.UISchema.EntityDescriptionTemplate
+					}
 				}
 			}
 			continue
@@ -308,13 +315,17 @@ func buildExpandedSchema(t reflect.Type, visited map[string]bool) Schema {
 
 		// Check for root-level UI schema decorators
 		if uiSchemaTag := field.Tag.Get("ui-schema"); uiSchemaTag != "" {
-			uiProps := parseSchemaTag(uiSchemaTag)
+			keys, template := splitDescriptionTemplate(uiSchemaTag)
+			uiProps := parseSchemaTag(keys)
 			if val, ok := uiProps["entityIdKey"]; ok && val == "true" {
 				schema.UISchema.EntityIdKey = &name
 			}
 			if val, ok := uiProps["entityDescriptionKey"]; ok && val == "true" {
 				schema.UISchema.EntityDescriptionKey = &name
 			}
+			if template != nil {
+				schema.UISchema.EntityDescriptionTemplate = template
+			}
 		}
 
 		// add field to order
@@ -342,6 +353,8 @@ func resolveExpandedFieldSchema(f reflect.StructField, t reflect.Type, visited m
 		schema = Schema{Type: SchemaTypeString}
 	} else if t.PkgPath() == "go.mongodb.org/mongo-driver/bson/primitive" && t.Name() == "DateTime" {
 		schema = Schema{Type: SchemaTypeString, Format: NewSchemaFormat(SchemaFormatDateTime)}
+	} else if t == reflect.TypeOf(time.Time{}) {
+		schema = Schema{Type: SchemaTypeString, Format: NewSchemaFormat(SchemaFormatDateTime)}
 	} else {
 		// Handle built-in kinds
 		switch t.Kind() {
@@ -391,8 +404,9 @@ func resolveExpandedFieldSchema(f reflect.StructField, t reflect.Type, visited m
 		applySchemaDecorators(&schema, props)
 	}
 	if tag := f.Tag.Get("ui-schema"); tag != "" {
-		props := parseSchemaTag(tag)
-		applyUISchemaDecorators(&schema, props)
+		keys, _ := splitDescriptionTemplate(tag)
+		props := parseSchemaTag(keys)
+		applyUISchemaDecorators(&schema, f.Name, props)
 	}
 
 	return schema
@@ -437,6 +451,24 @@ func hasInlineTag(tagParts []string) bool {
 	return false
 }
 
+// followingTagKey matches a key=value pair after a comma, in what would be a template.
+var followingTagKey = regexp.MustCompile(`,\s*[A-Za-z]+=`)
+
+// splitDescriptionTemplate separates the entityDescriptionTemplate of a ui-schema tag from
+// its other keys: the template may contain commas, so it takes the rest of the tag and must
+// be its last key.
+func splitDescriptionTemplate(tag string) (keys string, template *string) {
+	keys, value, ok := strings.Cut(tag, "entityDescriptionTemplate=")
+	if !ok {
+		return tag, nil
+	}
+	if followingTagKey.MatchString(value) {
+		panic("ui-schema: entityDescriptionTemplate must be the last key of the tag " + strconv.Quote(tag))
+	}
+	value = strings.TrimSpace(value)
+	return keys, &value
+}
+
 func parseSchemaTag(tag string) map[string]string {
 	parts := strings.Split(tag, ",")
 	props := make(map[string]string)
@@ -509,7 +541,9 @@ func applySchemaDecorators(s *Schema, props map[string]string) {
 	}
 }
 
-func applyUISchemaDecorators(s *Schema, props map[string]string) {
+// applyUISchemaDecorators applies the ui-schema tag of field; it panics on an unknown
+// onDelete policy, like the other errors of the models.
+func applyUISchemaDecorators(s *Schema, field string, props map[string]string) {
 	if s.UISchema == nil {
 		s.UISchema = &UISchema{}
 	}
@@ -520,12 +554,18 @@ func applyUISchemaDecorators(s *Schema, props map[string]string) {
 			s.UISchema.Entity = &v
 		case "query":
 			s.UISchema.Query = &v
+		case "onDelete":
+			policy := ReferenceDeletePolicy(v)
+			if err := policy.validate(field); err != nil {
+				panic("ui-schema: " + err.Error())
+			}
+			s.UISchema.OnDelete = &policy
 		case "hidden":
 			if v == "true" {
 				trueValue := true
 				s.UISchema.Hidden = &trueValue
 			}
-			// Note: entityIdKey and entityDescriptionKey are handled at root schema level,
+			// Note: entityIdKey, entityDescriptionKey and entityDescriptionTemplate are handled at root schema level,
 			// not at field level, so they are intentionally not processed here
 		}
 	}
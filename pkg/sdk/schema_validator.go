@@ -0,0 +1,313 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaViolation describes a single payload value that does not satisfy its schema.
+// Path is the dot/bracket notation of the failing field (e.g. "data.lines[0].productId");
+// it is empty when the payload root itself is invalid.
+type SchemaViolation struct {
+	Path            string
+	TranslationKey  string
+	TranslationArgs map[string]any
+}
+
+// Field returns the path used in user-facing messages ("payload" for the root).
+func (v SchemaViolation) Field() string {
+	if v.Path == "" {
+		return "payload"
+	}
+	return v.Path
+}
+
+var (
+	uuidRegexp         = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnameRegexp     = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+	languageCodeRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+)
+
+// ValidateJSON decodes body and validates it against the schema.
+// A nil schema or a body that is not valid JSON produces no violations: malformed
+// JSON is reported by the binding step that follows validation.
+func (rs *RootSchema) ValidateJSON(body []byte) []SchemaViolation {
+	if rs == nil {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+	return rs.Validate(value)
+}
+
+// Validate checks a generic JSON value (as produced by encoding/json) against the schema.
+// At most one violation is reported per field path, in path order.
+func (rs *RootSchema) Validate(value any) []SchemaViolation {
+	if rs == nil {
+		return nil
+	}
+	violations := map[string]SchemaViolation{}
+	rs.validateValue(&rs.Schema, value, "", violations)
+
+	paths := make([]string, 0, len(violations))
+	for p := range violations {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	result := make([]SchemaViolation, 0, len(paths))
+	for _, p := range paths {
+		result = append(result, violations[p])
+	}
+	return result
+}
+
+func (rs *RootSchema) resolveReference(s *Schema) *Schema {
+	if s.Reference == "" {
+		return s
+	}
+	name := strings.TrimPrefix(s.Reference, "#/$defs/")
+	if def, ok := rs.Definitions[name]; ok {
+		return &def
+	}
+	return s
+}
+
+func (rs *RootSchema) validateValue(s *Schema, value any, path string, violations map[string]SchemaViolation) {
+	s = rs.resolveReference(s)
+	if value == nil {
+		// null is accepted for any optional field; presence is checked through Required.
+		return
+	}
+	report := func(key string, args map[string]any) {
+		if _, exists := violations[path]; exists {
+			return
+		}
+		v := SchemaViolation{Path: path, TranslationKey: key}
+		if args == nil {
+			args = map[string]any{}
+		}
+		args["field"] = v.Field()
+		v.TranslationArgs = args
+		violations[path] = v
+	}
+
+	if s.Type != "" && !matchesSchemaType(s.Type, value) {
+		report("sdk.validation.type", map[string]any{"type": string(s.Type)})
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.MinLength != nil && utf8.RuneCountInString(v) < *s.MinLength {
+			report("sdk.validation.min_length", map[string]any{"min": *s.MinLength})
+			return
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(v) > *s.MaxLength {
+			report("sdk.validation.max_length", map[string]any{"max": *s.MaxLength})
+			return
+		}
+		if s.Format != nil && !matchesSchemaFormat(*s.Format, v) {
+			report("sdk.validation.format", map[string]any{"format": string(*s.Format)})
+			return
+		}
+	case map[string]any:
+		for _, required := range s.Required {
+			if val, ok := v[required]; !ok || val == nil {
+				childPath := joinSchemaPath(path, required)
+				if _, exists := violations[childPath]; !exists {
+					violations[childPath] = SchemaViolation{
+						Path:            childPath,
+						TranslationKey:  "sdk.validation.required",
+						TranslationArgs: map[string]any{"field": childPath},
+					}
+				}
+			}
+		}
+		for key, val := range v {
+			childPath := joinSchemaPath(path, key)
+			if s.Properties != nil {
+				if propSchema, ok := (*s.Properties)[key]; ok {
+					rs.validateValue(&propSchema, val, childPath, violations)
+					continue
+				}
+			}
+			if s.AdditionalProperties != nil {
+				rs.validateValue(s.AdditionalProperties, val, childPath, violations)
+			}
+		}
+	case []any:
+		if s.UniqueItems != nil && *s.UniqueItems && !hasUniqueItems(v) {
+			report("sdk.validation.unique_items", nil)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				rs.validateValue(s.Items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+
+	if s.Enum != nil && !matchesEnum(*s.Enum, value) {
+		report("sdk.validation.enum", map[string]any{"values": strings.Join(*s.Enum, ", ")})
+	}
+}
+
+func joinSchemaPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+func matchesSchemaType(t SchemaTypeName, value any) bool {
+	switch t {
+	case SchemaTypeString:
+		_, ok := value.(string)
+		return ok
+	case SchemaTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case SchemaTypeObject:
+		_, ok := value.(map[string]any)
+		return ok
+	case SchemaTypeArray:
+		_, ok := value.([]any)
+		return ok
+	case SchemaTypeNumber:
+		_, ok := toFloat(value)
+		return ok
+	case SchemaTypeInteger:
+		if n, ok := value.(json.Number); ok {
+			if _, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+				return true
+			}
+		}
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	}
+	return true
+}
+
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func matchesEnum(values []string, value any) bool {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case bool:
+		s = strconv.FormatBool(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		// enum is only declared on scalar values
+		return true
+	}
+	for _, allowed := range values {
+		if allowed == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasUniqueItems(items []any) bool {
+	seen := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return true
+		}
+		key := string(encoded)
+		if _, exists := seen[key]; exists {
+			return false
+		}
+		seen[key] = struct{}{}
+	}
+	return true
+}
+
+// matchesSchemaFormat reports whether value satisfies the given format.
+// Formats that describe presentation only (password, asset types) always match.
+func matchesSchemaFormat(format SchemaFormatName, value string) bool {
+	switch format {
+	case SchemaFormatDateTime:
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case SchemaFormatDate:
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case SchemaFormatTime:
+		for _, layout := range []string{time.TimeOnly, "15:04:05Z07:00", "15:04:05.999999999Z07:00", "15:04"} {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	case SchemaFormatEmail:
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	case SchemaFormatHostname:
+		return len(value) <= 253 && hostnameRegexp.MatchString(value)
+	case SchemaFormatIPv4:
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	case SchemaFormatIPv6:
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	case SchemaFormatURI:
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	case SchemaFormatUUID:
+		return uuidRegexp.MatchString(value)
+	case SchemaFormatCountryCode:
+		_, ok := isoCountryCodes[value]
+		return ok
+	case SchemaFormatCurrency:
+		_, ok := isoCurrencyCodes[value]
+		return ok
+	case SchemaFormatLanguageCode:
+		return languageCodeRegexp.MatchString(value)
+	case SchemaFormatJSON:
+		return json.Valid([]byte(value))
+	case SchemaFormatYAML:
+		var out any
+		return yaml.Unmarshal([]byte(value), &out) == nil
+	}
+	return true
+}
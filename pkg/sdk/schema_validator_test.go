@@ -0,0 +1,201 @@
+package sdk_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validatedAddress struct {
+	Country string `json:"country" schema:"format=country-code"`
+}
+
+type validatedPayload struct {
+	Name     string             `json:"name" schema:"minLength=2,maxLength=5"`
+	Email    string             `json:"email" schema:"format=email"`
+	Status   string             `json:"status" schema:"enum=active|inactive"`
+	Age      int                `json:"age"`
+	Tags     []string           `json:"tags" schema:"uniqueItems=true"`
+	Currency string             `json:"currency" schema:"format=currency"`
+	Address  validatedAddress   `json:"address"`
+	Lines    []validatedAddress `json:"lines"`
+}
+
+func violationPaths(violations []sdk.SchemaViolation) []string {
+	paths := make([]string, 0, len(violations))
+	for _, v := range violations {
+		paths = append(paths, v.Path)
+	}
+	return paths
+}
+
+func TestValidate_ValidPayload(t *testing.T) {
+	schema := sdk.NewSchema(validatedPayload{})
+	body := `{"name":"Mario","email":"mario@example.com","status":"active","age":30,"tags":["a","b"],"currency":"EUR","address":{"country":"IT"},"lines":[{"country":"US"}]}`
+	assert.Empty(t, schema.ValidateJSON([]byte(body)))
+}
+
+func TestValidate_ReportsOneViolationPerPath(t *testing.T) {
+	schema := sdk.NewSchema(validatedPayload{})
+	body := `{"name":"M","email":"not-an-email","status":"deleted","age":1.5,"tags":["a","a"],"currency":"EURO","address":{"country":"XX"},"lines":[{"country":"IT"},{"country":"ZZ"}]}`
+	violations := schema.ValidateJSON([]byte(body))
+	assert.Equal(t, []string{"address.country", "age", "currency", "email", "lines[1].country", "name", "status", "tags"}, violationPaths(violations))
+
+	byPath := map[string]sdk.SchemaViolation{}
+	for _, v := range violations {
+		byPath[v.Path] = v
+	}
+	assert.Equal(t, "sdk.validation.min_length", byPath["name"].TranslationKey)
+	assert.Equal(t, "sdk.validation.format", byPath["email"].TranslationKey)
+	assert.Equal(t, "sdk.validation.enum", byPath["status"].TranslationKey)
+	assert.Equal(t, "sdk.validation.type", byPath["age"].TranslationKey)
+	assert.Equal(t, "sdk.validation.unique_items", byPath["tags"].TranslationKey)
+}
+
+func TestValidate_Required(t *testing.T) {
+	schema := &sdk.RootSchema{Schema: sdk.Schema{
+		Type: sdk.SchemaTypeObject,
+		Properties: &map[string]sdk.Schema{
+			"data": {
+				Type:       sdk.SchemaTypeObject,
+				Required:   []string{"code"},
+				Properties: &map[string]sdk.Schema{"code": {Type: sdk.SchemaTypeString}},
+			},
+		},
+		Required: []string{"data"},
+	}}
+
+	violations := schema.ValidateJSON([]byte(`{}`))
+	require.Len(t, violations, 1)
+	assert.Equal(t, "data", violations[0].Path)
+	assert.Equal(t, "sdk.validation.required", violations[0].TranslationKey)
+
+	violations = schema.ValidateJSON([]byte(`{"data":{"code":null}}`))
+	require.Len(t, violations, 1)
+	assert.Equal(t, "data.code", violations[0].Path)
+}
+
+func TestValidate_Formats(t *testing.T) {
+	cases := []struct {
+		format  sdk.SchemaFormatName
+		valid   string
+		invalid string
+	}{
+		{sdk.SchemaFormatDateTime, "2024-01-02T10:00:00Z", "2024-01-02"},
+		{sdk.SchemaFormatDate, "2024-01-02", "02/01/2024"},
+		{sdk.SchemaFormatTime, "10:30:00", "25:00"},
+		{sdk.SchemaFormatUUID, "123e4567-e89b-12d3-a456-426614174000", "123e4567"},
+		{sdk.SchemaFormatURI, "https://example.com/a", "example"},
+		{sdk.SchemaFormatHostname, "api.example.com", "-bad-.com"},
+		{sdk.SchemaFormatIPv4, "192.168.0.1", "::1"},
+		{sdk.SchemaFormatIPv6, "::1", "192.168.0.1"},
+		{sdk.SchemaFormatLanguageCode, "en-US", "english_us"},
+		{sdk.SchemaFormatJSON, `{"a":1}`, `{a:1`},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.format), func(t *testing.T) {
+			format := tc.format
+			schema := &sdk.RootSchema{Schema: sdk.Schema{Type: sdk.SchemaTypeString, Format: &format}}
+			assert.Empty(t, schema.Validate(tc.valid))
+			assert.Len(t, schema.Validate(tc.invalid), 1)
+		})
+	}
+}
+
+func TestValidate_NilSchemaAndMalformedJSON(t *testing.T) {
+	var schema *sdk.RootSchema
+	assert.Empty(t, schema.ValidateJSON([]byte(`{"a":1}`)))
+	assert.Empty(t, sdk.NewSchema(validatedPayload{}).ValidateJSON([]byte(`{not json`)))
+}
+
+type testDIContainer struct {
+	actions map[string]sdk.EndorHandlerActionInterface
+	jobs    *sdk.JobManager
+}
+
+func (testDIContainer) GetRepositories() map[string]sdk.EndorRepositoryInterface {
+	return map[string]sdk.EndorRepositoryInterface{}
+}
+
+func (testDIContainer) GetModule() string {
+	return "sdk"
+}
+
+func (testDIContainer) GetTranslator() *sdk_i18n.Translator {
+	return sdk_i18n.NewTranslator(nil)
+}
+
+func (testDIContainer) GetEventBus() *sdk.EventBus {
+	return nil
+}
+
+func (c testDIContainer) GetJobManager() *sdk.JobManager {
+	return c.jobs
+}
+
+func (testDIContainer) GetOutbox() *sdk.Outbox {
+	return nil
+}
+
+func (testDIContainer) GetWebhookDispatcher() *sdk.WebhookDispatcher {
+	return nil
+}
+
+func (testDIContainer) GetAuditTrail() *sdk.AuditTrail {
+	return nil
+}
+
+func (testDIContainer) GetReferenceResolver() sdk.ReferenceResolver {
+	return nil
+}
+
+func (testDIContainer) AuthorizeAction(session sdk.Session, actionId string) error {
+	return nil
+}
+
+func (c testDIContainer) InvokeAction(ctx sdk.EndorContextInterface, actionId string, payload any) (any, error) {
+	action, ok := c.actions[actionId]
+	if !ok {
+		return nil, sdk.NewNotFoundError(fmt.Errorf("action %s not found", actionId))
+	}
+	return action.InvokeWithPayload(ctx, actionId, payload, c)
+}
+
+func TestCreateHTTPCallback_RejectsInvalidPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	called := false
+	action := sdk.NewAction(func(c *sdk.EndorContext[validatedPayload]) (*sdk.Response[any], error) {
+		called = true
+		return sdk.NewResponseBuilder[any]().Build(), nil
+	}, "test")
+	callback := action.CreateHTTPCallback("sdk", "test", "run", "", sdk.Session{Locale: "en"}, testDIContainer{})
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/test/run", strings.NewReader(`{"name":"M","email":"x"}`))
+	callback(c)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	var response sdk.Response[map[string]any]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Messages, 2)
+	assert.Equal(t, "email is not a valid email", response.Messages[0].Value)
+	assert.Equal(t, "name must be at least 2 characters long", response.Messages[1].Value)
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/test/run", strings.NewReader(`{"name":"Mario","email":"mario@example.com","address":{"country":"IT"}}`))
+	callback(c)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentTypeNDJSON is the media type of streamed responses: one JSON document per line.
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// ResponseStream produces the data of a streamed response: it calls emit for every item,
+// in order, and returns the references of all the emitted items. ctx is the request
+// context, cancelled when the client disconnects or the timeout of the action expires.
+type ResponseStream func(ctx context.Context, emit func(item any) error) (EntityRefererenceGroup, error)
+
+// StreamLine is a line of a streamed response. The first line carries the schema, then a
+// line for every item and, once the stream is over, a line with the references and one
+// with the messages; an error in the middle of the stream is reported as a Fatal message.
+type StreamLine struct {
+	Schema     *RootSchema             `json:"schema,omitempty"`
+	Data       any                     `json:"data,omitempty"`
+	References *EntityRefererenceGroup `json:"references,omitempty"`
+	Messages   []ResponseMessage       `json:"messages,omitempty"`
+}
+
+// StreamRequested reports whether the client asked for a streamed (NDJSON) response with
+// the Accept header. It is always false for programmatic invocations.
+func (ec *EndorContext[T]) StreamRequested() bool {
+	return strings.Contains(ec.Header("Accept"), ContentTypeNDJSON)
+}
+
+// writeStreamResponse writes a response built with AddStream as NDJSON, flushing every line
+// so that the items are never buffered in memory. The stream runs with ctx: once its
+// deadline (the timeout of the action) expires no further item is written and the stream
+// ends with timeoutErr, if not nil.
+func writeStreamResponse[T any, R any](c *gin.Context, ec *EndorContext[T], ctx context.Context, logger *Logger, microserviceId string, response *Response[R], timeoutErr error) {
+	c.Header("Content-Type", ContentTypeNDJSON)
+	c.Header("X-Endor-Microservice", microserviceId)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	write := func(line StreamLine) error {
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	if err := write(StreamLine{Schema: response.Schema}); err != nil {
+		logger.ErrorWithStackTrace(err)
+		return
+	}
+	references, err := response.stream(ctx, func(item any) error {
+		// streams that do not watch ctx are stopped here
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return write(StreamLine{Data: item})
+	})
+	if err != nil && timeoutErr != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = timeoutErr
+	}
+	messages := response.Messages
+	if err != nil {
+		logger.ErrorWithStackTrace(err)
+		message := err.Error()
+		var endorError *EndorError
+		if errors.As(err, &endorError) && endorError.TranslationKey != "" {
+			message = ec.T(endorError.TranslationKey, endorError.TranslationArgs)
+		}
+		messages = append(messages, NewMessage(ResponseMessageGravityFatal, message))
+	} else if references != nil {
+		if err := write(StreamLine{References: &references}); err != nil {
+			logger.ErrorWithStackTrace(err)
+			return
+		}
+	}
+	if len(messages) > 0 {
+		if err := write(StreamLine{Messages: messages}); err != nil {
+			logger.ErrorWithStackTrace(err)
+		}
+	}
+}
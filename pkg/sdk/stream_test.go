@@ -0,0 +1,129 @@
+package sdk_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamAction(items []string, streamErr error) sdk.EndorHandlerActionInterface {
+	return sdk.NewAction(func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[[]string], error) {
+		builder := sdk.NewResponseBuilder[[]string]().AddSchema(&sdk.RootSchema{Schema: sdk.Schema{Type: sdk.SchemaTypeArray}})
+		if !c.StreamRequested() {
+			return builder.AddData(&items).Build(), nil
+		}
+		return builder.AddStream(func(ctx context.Context, emit func(item any) error) (sdk.EntityRefererenceGroup, error) {
+			for _, item := range items {
+				if err := emit(item); err != nil {
+					return nil, err
+				}
+			}
+			if streamErr != nil {
+				return nil, streamErr
+			}
+			return sdk.EntityRefererenceGroup{"customer": {"c1": "Acme"}}, nil
+		}).Build(), nil
+	}, "list")
+}
+
+func serveStream(t *testing.T, action sdk.EndorHandlerActionInterface, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/order/list", nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	action.CreateHTTPCallback("sdk", "order", "list", "", sdk.Session{Locale: "en"}, testDIContainer{})(c)
+	return recorder
+}
+
+func readStreamLines(t *testing.T, body string) []sdk.StreamLine {
+	t.Helper()
+	var lines []sdk.StreamLine
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var line sdk.StreamLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line), scanner.Text())
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestStream_WritesNDJSON(t *testing.T) {
+	recorder := serveStream(t, streamAction([]string{"a", "b"}, nil), "application/x-ndjson")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, sdk.ContentTypeNDJSON, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "sdk", recorder.Header().Get("X-Endor-Microservice"))
+	lines := readStreamLines(t, recorder.Body.String())
+	require.Len(t, lines, 4)
+	require.NotNil(t, lines[0].Schema)
+	assert.Equal(t, sdk.SchemaTypeArray, lines[0].Schema.Type)
+	assert.Equal(t, "a", lines[1].Data)
+	assert.Equal(t, "b", lines[2].Data)
+	require.NotNil(t, lines[3].References)
+	assert.Equal(t, "Acme", (*lines[3].References)["customer"]["c1"])
+}
+
+func TestStream_ErrorAfterItemsIsAFatalMessage(t *testing.T) {
+	recorder := serveStream(t, streamAction([]string{"a"}, fmt.Errorf("cursor lost")), "application/x-ndjson")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	lines := readStreamLines(t, recorder.Body.String())
+	require.Len(t, lines, 3)
+	assert.Equal(t, "a", lines[1].Data)
+	assert.Nil(t, lines[2].References)
+	require.Len(t, lines[2].Messages, 1)
+	assert.Equal(t, sdk.ResponseMessageGravityFatal, lines[2].Messages[0].Gravity)
+	assert.Equal(t, "cursor lost", lines[2].Messages[0].Value)
+}
+
+func TestStream_TimeoutBoundsTheStream(t *testing.T) {
+	action := sdk.NewConfigurableAction(sdk.EndorHandlerActionOptions{Timeout: 50 * time.Millisecond}, func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[[]string], error) {
+		return sdk.NewResponseBuilder[[]string]().AddStream(func(ctx context.Context, emit func(item any) error) (sdk.EntityRefererenceGroup, error) {
+			// a stream not watching ctx, slower than the timeout
+			for i := 0; ; i++ {
+				if err := emit(fmt.Sprint(i)); err != nil {
+					return nil, err
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}).Build(), nil
+	})
+
+	recorder := serveStream(t, action, "application/x-ndjson")
+
+	lines := readStreamLines(t, recorder.Body.String())
+	last := lines[len(lines)-1]
+	require.Len(t, last.Messages, 1)
+	assert.Equal(t, sdk.ResponseMessageGravityFatal, last.Messages[0].Gravity)
+	assert.Contains(t, last.Messages[0].Value, "50ms")
+	assert.Less(t, len(lines), 10, "no item is written after the timeout")
+}
+
+func TestStream_NotRequestedAnswersJSON(t *testing.T) {
+	recorder := serveStream(t, streamAction([]string{"a", "b"}, nil), "")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response sdk.Response[[]string]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.NotNil(t, response.Data)
+	assert.Equal(t, []string{"a", "b"}, *response.Data)
+}
+
+func TestStream_NotRequestedByProgrammaticInvocations(t *testing.T) {
+	assert.False(t, (&sdk.EndorContext[sdk.NoPayload]{}).StreamRequested())
+}
@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type transactionKey struct{}
+
+// transaction collects the work to run once the transaction opened by WithTransaction is
+// committed.
+type transaction struct {
+	mu          sync.Mutex
+	afterCommit []func(ctx context.Context) error
+}
+
+// WithTransaction runs fn in a Mongo transaction: the writes of the repositories made with
+// txCtx are committed together when fn returns nil and discarded otherwise. A transaction
+// spans every database of the cluster, so it also covers the per-user databases of the
+// development overlay and the outbox and history entries of the writes.
+//
+// If ctx already belongs to a transaction fn joins it. The driver runs fn again on
+// transient errors, so fn must not have side effects other than the writes made with
+// txCtx. The events of the writes are published on the event bus only after the commit;
+// the errors of their synchronous subscribers are logged, since the transaction is saved.
+//
+// Mongo transactions need a replica set (even of a single node).
+func WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	client, err := GetMongoClient()
+	if err != nil {
+		return NewInternalServerError(fmt.Errorf("mongo client not available: %w", err))
+	}
+	session, err := client.StartSession()
+	if err != nil {
+		return NewInternalServerError(fmt.Errorf("failed to start mongo session: %w", err))
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+	return runTransaction(ctx, func(attempt func(txCtx context.Context) error) error {
+		_, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+			return nil, attempt(sc)
+		})
+		return err
+	}, fn)
+}
+
+// runTransaction runs fn through commit, which runs its attempts in a transaction and
+// commits the last one, and then the work registered with AfterCommit by that attempt.
+func runTransaction(ctx context.Context, commit func(attempt func(txCtx context.Context) error) error, fn func(txCtx context.Context) error) error {
+	var tx *transaction
+	if err := commit(func(txCtx context.Context) error {
+		// a new attempt forgets the work of the aborted one
+		tx = &transaction{}
+		return fn(context.WithValue(txCtx, transactionKey{}, tx))
+	}); err != nil {
+		return err
+	}
+	var errs []error
+	for _, work := range tx.afterCommit {
+		if err := work(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AfterCommit runs work once the transaction of ctx opened by WithTransaction is committed,
+// with the context WithTransaction was called with; outside of such a transaction it runs
+// work at once with ctx. Work of aborted transactions never runs.
+func AfterCommit(ctx context.Context, work func(ctx context.Context) error) error {
+	tx, ok := ctx.Value(transactionKey{}).(*transaction)
+	if !ok {
+		return work(ctx)
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.afterCommit = append(tx.afterCommit, work)
+	return nil
+}
@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitAfter returns a commit running the attempts until one succeeds, at most attempts
+// times, like the transaction runner of the driver on transient errors.
+func commitAfter(attempts int) func(attempt func(txCtx context.Context) error) error {
+	return func(attempt func(txCtx context.Context) error) error {
+		var err error
+		for i := 0; i < attempts; i++ {
+			if err = attempt(context.Background()); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+}
+
+func TestRunTransaction_CommitRunsAfterCommitWork(t *testing.T) {
+	var ran []string
+	err := runTransaction(context.Background(), commitAfter(1), func(txCtx context.Context) error {
+		require.NoError(t, AfterCommit(txCtx, func(ctx context.Context) error {
+			ran = append(ran, "published")
+			return nil
+		}))
+		assert.Empty(t, ran, "work runs only after the commit")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"published"}, ran)
+}
+
+func TestRunTransaction_AbortDropsAfterCommitWork(t *testing.T) {
+	failed := errors.New("stock not available")
+	ran := false
+	err := runTransaction(context.Background(), commitAfter(1), func(txCtx context.Context) error {
+		require.NoError(t, AfterCommit(txCtx, func(ctx context.Context) error {
+			ran = true
+			return nil
+		}))
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.False(t, ran)
+}
+
+func TestRunTransaction_RetryResetsAfterCommitWork(t *testing.T) {
+	var ran []int
+	attempt := 0
+	err := runTransaction(context.Background(), commitAfter(3), func(txCtx context.Context) error {
+		attempt++
+		current := attempt
+		require.NoError(t, AfterCommit(txCtx, func(ctx context.Context) error {
+			ran = append(ran, current)
+			return nil
+		}))
+		if current < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, ran, "only the work of the committed attempt runs")
+}
+
+func TestRunTransaction_ReturnsAfterCommitErrors(t *testing.T) {
+	failed := errors.New("subscriber failed")
+	err := runTransaction(context.Background(), commitAfter(1), func(txCtx context.Context) error {
+		require.NoError(t, AfterCommit(txCtx, func(ctx context.Context) error { return failed }))
+		return nil
+	})
+	assert.ErrorIs(t, err, failed)
+}
+
+func TestAfterCommit_OutsideTransactionRunsAtOnce(t *testing.T) {
+	ran := false
+	require.NoError(t, AfterCommit(context.Background(), func(ctx context.Context) error {
+		ran = true
+		return nil
+	}))
+	assert.True(t, ran)
+}
@@ -93,3 +93,7 @@ const X_ENDOR_SESSION_ID = "X-Endor-Session-Id"
 const X_ENDOR_USER_ID = "X-Endor-User-Id"
 const X_ENDOR_USERNAME = "X-Endor-Username"
 const X_ENDOR_DEVELOPMENT = "X-Endor-Development"
+const X_ENDOR_ROLES = "X-Endor-Roles"
+const X_ENDOR_PERMISSIONS = "X-Endor-Permissions"
+const X_ENDOR_EVENT_ID = "X-Endor-Event-Id"
+const X_ENDOR_SIGNATURE = "X-Endor-Signature"
@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionField is the field of versioned entities incremented by the repositories on every
+// write (see EntityInstanceRepositoryOptions.Versioned).
+const VersionField = "version"
+
+// DeletedAtField and DeletedByField mark the instances deleted on soft-delete repositories
+// (see EntityInstanceRepositoryOptions.SoftDelete) with the time and the user of the deletion.
+const (
+	DeletedAtField = "deletedAt"
+	DeletedByField = "deletedBy"
+)
+
+// VersionETag returns the ETag header of an instance version.
+func VersionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseVersionETag returns the version of an If-Match header built with VersionETag; it
+// returns nil for an empty header or "*", which match any version.
+func ParseVersionETag(header string) (*int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 0 {
+		return nil, NewBadRequestError(fmt.Errorf("invalid If-Match header %q", header)).WithTranslation("sdk.entity.messages.invalid_if_match", map[string]any{"value": header})
+	}
+	return &version, nil
+}
+
+// InstanceVersion returns the version stored in the metadata of an instance.
+func InstanceVersion(metadata map[string]any) (int64, bool) {
+	switch v := metadata[VersionField].(type) {
+	case int64:
+		return v, true
+	case int32:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// IfMatchVersion returns the version of the If-Match header of the request, nil if absent
+// or for programmatic invocations.
+func (ec *EndorContext[T]) IfMatchVersion() (*int64, error) {
+	return ParseVersionETag(ec.Header("If-Match"))
+}
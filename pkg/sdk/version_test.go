@@ -0,0 +1,71 @@
+package sdk_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionETag(t *testing.T) {
+	for header, expected := range map[string]int64{`"3"`: 3, `W/"7"`: 7, "12": 12, ` "0" `: 0} {
+		version, err := sdk.ParseVersionETag(header)
+		require.NoError(t, err, header)
+		require.NotNil(t, version, header)
+		assert.Equal(t, expected, *version, header)
+	}
+	for _, header := range []string{"", "*"} {
+		version, err := sdk.ParseVersionETag(header)
+		require.NoError(t, err)
+		assert.Nil(t, version)
+	}
+	for _, header := range []string{`"abc"`, `"-1"`} {
+		_, err := sdk.ParseVersionETag(header)
+		var endorError *sdk.EndorError
+		require.True(t, errors.As(err, &endorError), header)
+		assert.Equal(t, http.StatusBadRequest, endorError.StatusCode)
+	}
+	assert.Equal(t, `"42"`, sdk.VersionETag(42))
+}
+
+func TestInstanceVersion(t *testing.T) {
+	for _, value := range []any{int64(4), int32(4), 4, float64(4)} {
+		version, ok := sdk.InstanceVersion(map[string]any{"version": value})
+		assert.True(t, ok)
+		assert.Equal(t, int64(4), version)
+	}
+	_, ok := sdk.InstanceVersion(map[string]any{})
+	assert.False(t, ok)
+}
+
+func TestIfMatchVersion_ReadsHeaderAndWritesETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seen *int64
+	action := sdk.NewAction(func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+		var err error
+		if seen, err = c.IfMatchVersion(); err != nil {
+			return nil, err
+		}
+		return sdk.NewResponseBuilder[any]().AddHeader("ETag", sdk.VersionETag(*seen+1)).Build(), nil
+	}, "update")
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/order/update", nil)
+	c.Request.Header.Set("If-Match", `"5"`)
+	action.CreateHTTPCallback("sdk", "order", "update", "", sdk.Session{Locale: "en"}, testDIContainer{})(c)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	require.NotNil(t, seen)
+	assert.Equal(t, int64(5), *seen)
+	assert.Equal(t, `"6"`, recorder.Header().Get("ETag"))
+
+	version, err := (&sdk.EndorContext[sdk.NoPayload]{}).IfMatchVersion()
+	require.NoError(t, err)
+	assert.Nil(t, version)
+}
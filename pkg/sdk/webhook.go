@@ -0,0 +1,361 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// #region Webhook
+
+const (
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookRetryDelay  = time.Second
+)
+
+// WebhookReadPermission is required to read the delivery log of the webhooks.
+const WebhookReadPermission = "webhook:read"
+
+// Webhook POSTs the events of the declaring entity to URL. DSL entities declare it in the
+// webhooks section.
+type Webhook struct {
+	// Id identifies the webhook within the entity in the delivery log, its position if empty.
+	Id  string `json:"id,omitempty" yaml:"id,omitempty"`
+	URL string `json:"url" yaml:"url"`
+	// Events are the delivered event types, every type if empty.
+	Events []EntityEventType `json:"events,omitempty" yaml:"events,omitempty"`
+	// Filter selects the instances whose events are delivered, with the ReadDTO.Filter
+	// syntax: it is matched against the instance after the write (before it for deletions).
+	Filter map[string]any `json:"filter,omitempty" yaml:"filter,omitempty"`
+	// SecretEnv is the environment variable holding the HMAC secret signing the payload;
+	// the payload is not signed if empty.
+	SecretEnv string `json:"secretEnv,omitempty" yaml:"secretEnv,omitempty"`
+	// MaxAttempts bounds the deliveries of an event (default 5).
+	MaxAttempts int `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+	// RetryDelay is the wait before the second attempt, doubled at every further one
+	// (default 1s).
+	RetryDelay time.Duration `json:"retryDelay,omitempty" yaml:"retryDelay,omitempty"`
+}
+
+// SignWebhookPayload returns the value of the X-Endor-Signature header of payload:
+// "sha256=" followed by the hex HMAC-SHA256 of the body with secret.
+func SignWebhookPayload(secret []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is the X-Endor-Signature of payload,
+// for the receivers of the webhooks.
+func VerifyWebhookSignature(secret []byte, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, payload)), []byte(signature))
+}
+
+func (w Webhook) withDefaults() Webhook {
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if w.RetryDelay <= 0 {
+		w.RetryDelay = defaultWebhookRetryDelay
+	}
+	return w
+}
+
+// #endregion
+
+// #region Deliveries
+
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryStatusPending deliveries failed and will be retried.
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryStatusFailed deliveries exhausted their attempts.
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery records the delivery of an event to a webhook, updated at every attempt.
+type WebhookDelivery struct {
+	// Id is <eventId>/<webhookId>.
+	Id         string                `json:"id" bson:"_id" schema:"title=${t.sdk.webhook_delivery.fields.id},readOnly=true"`
+	EntityId   string                `json:"entityId" bson:"entityId" schema:"title=${t.sdk.webhook_delivery.fields.entity_id},readOnly=true"`
+	WebhookId  string                `json:"webhookId" bson:"webhookId" schema:"title=${t.sdk.webhook_delivery.fields.webhook_id},readOnly=true"`
+	URL        string                `json:"url" bson:"url" schema:"title=${t.sdk.webhook_delivery.fields.url},readOnly=true"`
+	EventId    string                `json:"eventId" bson:"eventId" schema:"title=${t.sdk.webhook_delivery.fields.event_id},readOnly=true"`
+	EventType  EntityEventType       `json:"eventType" bson:"eventType" schema:"title=${t.sdk.webhook_delivery.fields.event_type},enum=created|updated|deleted,readOnly=true"`
+	InstanceId string                `json:"instanceId" bson:"instanceId" schema:"title=${t.sdk.webhook_delivery.fields.instance_id},readOnly=true"`
+	Status     WebhookDeliveryStatus `json:"status" bson:"status" schema:"title=${t.sdk.webhook_delivery.fields.status},enum=pending|delivered|failed,readOnly=true"`
+	Attempts   int                   `json:"attempts" bson:"attempts" schema:"title=${t.sdk.webhook_delivery.fields.attempts},readOnly=true"`
+	// ResponseStatus is the HTTP status of the last attempt, 0 if no response was received.
+	ResponseStatus int        `json:"responseStatus,omitempty" bson:"responseStatus,omitempty" schema:"title=${t.sdk.webhook_delivery.fields.response_status},readOnly=true"`
+	LastError      string     `json:"lastError,omitempty" bson:"lastError,omitempty" schema:"title=${t.sdk.webhook_delivery.fields.last_error},readOnly=true"`
+	CreatedAt      time.Time  `json:"createdAt" bson:"createdAt" schema:"title=${t.sdk.webhook_delivery.fields.created_at},readOnly=true"`
+	UpdatedAt      time.Time  `json:"updatedAt" bson:"updatedAt" schema:"title=${t.sdk.webhook_delivery.fields.updated_at},readOnly=true"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty" schema:"title=${t.sdk.webhook_delivery.fields.delivered_at},readOnly=true"`
+}
+
+func (d *WebhookDelivery) GetID() any {
+	return d.Id
+}
+
+// ReadWebhookDeliveriesDTO selects the deliveries; empty fields match every delivery.
+type ReadWebhookDeliveriesDTO struct {
+	EntityId   string                `json:"entityId,omitempty"`
+	WebhookId  string                `json:"webhookId,omitempty"`
+	InstanceId string                `json:"instanceId,omitempty"`
+	Status     WebhookDeliveryStatus `json:"status,omitempty"`
+}
+
+func (dto ReadWebhookDeliveriesDTO) matches(delivery WebhookDelivery) bool {
+	return (dto.EntityId == "" || dto.EntityId == delivery.EntityId) &&
+		(dto.WebhookId == "" || dto.WebhookId == delivery.WebhookId) &&
+		(dto.InstanceId == "" || dto.InstanceId == delivery.InstanceId) &&
+		(dto.Status == "" || dto.Status == delivery.Status)
+}
+
+// WebhookDeliveryRepositoryInterface stores the delivery log.
+type WebhookDeliveryRepositoryInterface interface {
+	// Save creates or replaces the delivery.
+	Save(ctx context.Context, delivery WebhookDelivery) error
+	Instance(ctx context.Context, id string) (*WebhookDelivery, error)
+	// List returns the last deliveries, most recent first.
+	List(ctx context.Context, dto ReadWebhookDeliveriesDTO) ([]WebhookDelivery, error)
+}
+
+func webhookDeliveryNotFoundError(id string) error {
+	return NewNotFoundError(fmt.Errorf("webhook delivery %s not found", id)).WithTranslation("sdk.webhook_delivery.messages.not_found", map[string]any{"id": id})
+}
+
+// InMemoryWebhookDeliveryRepository keeps the delivery log in memory: it is meant for tests
+// and local development.
+type InMemoryWebhookDeliveryRepository struct {
+	mu         sync.RWMutex
+	deliveries map[string]WebhookDelivery
+}
+
+func NewInMemoryWebhookDeliveryRepository() *InMemoryWebhookDeliveryRepository {
+	return &InMemoryWebhookDeliveryRepository{deliveries: map[string]WebhookDelivery{}}
+}
+
+func (r *InMemoryWebhookDeliveryRepository) Save(ctx context.Context, delivery WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[delivery.Id] = delivery
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) Instance(ctx context.Context, id string) (*WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	delivery, exists := r.deliveries[id]
+	if !exists {
+		return nil, webhookDeliveryNotFoundError(id)
+	}
+	return &delivery, nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) List(ctx context.Context, dto ReadWebhookDeliveriesDTO) ([]WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	deliveries := []WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if dto.matches(delivery) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	slices.SortFunc(deliveries, func(a, b WebhookDelivery) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return deliveries, nil
+}
+
+// #endregion
+
+// #region Dispatcher
+
+// WebhookDispatcher delivers the events to the webhooks declared by the entities and
+// records every attempt in the delivery log.
+type WebhookDispatcher struct {
+	repository WebhookDeliveryRepositoryInterface
+	client     *http.Client
+	now        func() time.Time
+}
+
+func NewWebhookDispatcher(repository WebhookDeliveryRepositoryInterface) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repository: repository,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// Repository returns the delivery log.
+func (d *WebhookDispatcher) Repository() WebhookDeliveryRepositoryInterface {
+	return d.repository
+}
+
+// Subscription returns the asynchronous subscription delivering the events of entityId to
+// webhook, redacted with the schema returned by schemas: the event bus retries the failed
+// deliveries, which are lost on restart. Services with an outbox deliver the webhooks
+// through OutboxPublisher instead.
+func (d *WebhookDispatcher) Subscription(entityId string, webhook Webhook, schemas func(event EntityEvent) *RootSchema) EventSubscription {
+	webhook = webhook.withDefaults()
+	return EventSubscription{
+		Entity:      entityId,
+		Events:      webhook.Events,
+		Async:       true,
+		MaxAttempts: webhook.MaxAttempts,
+		RetryDelay:  webhook.RetryDelay,
+		Handler: func(c *EndorContext[EntityEvent]) error {
+			return d.deliver(c.Context(), entityId, webhook, schemas, c.Payload)
+		},
+	}
+}
+
+// OutboxPublisher returns the outbox publisher delivering every event to the webhooks of
+// its entity, as returned by webhooks, redacted with the schema returned by schemas: the
+// outbox retries the failed deliveries, so they survive restarts. The events of development
+// sessions are not delivered.
+func (d *WebhookDispatcher) OutboxPublisher(webhooks func(entityId string) []Webhook, schemas func(event EntityEvent) *RootSchema) OutboxPublisher {
+	return &webhookDispatcherPublisher{dispatcher: d, webhooks: webhooks, schemas: schemas}
+}
+
+type webhookDispatcherPublisher struct {
+	dispatcher *WebhookDispatcher
+	webhooks   func(entityId string) []Webhook
+	schemas    func(event EntityEvent) *RootSchema
+}
+
+func (p *webhookDispatcherPublisher) Publish(ctx context.Context, event EntityEvent) error {
+	if event.Session.Development {
+		return nil
+	}
+	var errs []error
+	for _, webhook := range p.webhooks(event.EntityId) {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, event.Type) {
+			continue
+		}
+		if err := p.dispatcher.deliver(ctx, event.EntityId, webhook.withDefaults(), p.schemas, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// webhookEvent returns the event as delivered to the webhooks: the writeOnly and password
+// fields of schema are redacted, and the roles and permissions of the session are dropped,
+// since the receivers are outside the service.
+func webhookEvent(event EntityEvent, schema *RootSchema) EntityEvent {
+	if event.Before != nil {
+		event.Before = RedactPayload(event.Before, schema)
+	}
+	if event.After != nil {
+		event.After = RedactPayload(event.After, schema)
+	}
+	event.Session.Roles = nil
+	event.Session.Permissions = nil
+	return event
+}
+
+// deliver runs an attempt of the delivery of event, skipping the events filtered out and
+// the deliveries already completed or failed (the outbox retries every webhook of an event).
+// The filter is matched against the instance before its redaction.
+func (d *WebhookDispatcher) deliver(ctx context.Context, entityId string, webhook Webhook, schemas func(event EntityEvent) *RootSchema, event EntityEvent) error {
+	if len(webhook.Filter) > 0 {
+		document := event.After
+		if event.Type == EntityEventDeleted {
+			document = event.Before
+		}
+		if !MatchFilter(document, webhook.Filter) {
+			return nil
+		}
+	}
+
+	now := d.now().UTC()
+	id := event.Id + "/" + webhook.Id
+	delivery, err := d.repository.Instance(ctx, id)
+	if err != nil {
+		var endorError *EndorError
+		if !errors.As(err, &endorError) || endorError.StatusCode != http.StatusNotFound {
+			return err
+		}
+		delivery = &WebhookDelivery{
+			Id:         id,
+			EntityId:   entityId,
+			WebhookId:  webhook.Id,
+			URL:        webhook.URL,
+			EventId:    event.Id,
+			EventType:  event.Type,
+			InstanceId: event.InstanceId,
+			CreatedAt:  now,
+		}
+	} else if delivery.Status != WebhookDeliveryStatusPending {
+		return nil
+	}
+
+	delivery.Attempts++
+	delivery.UpdatedAt = now
+	var schema *RootSchema
+	if schemas != nil {
+		schema = schemas(event)
+	}
+	delivery.ResponseStatus, err = d.post(ctx, webhook, webhookEvent(event, schema))
+	if err == nil {
+		delivery.Status = WebhookDeliveryStatusDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.Status = WebhookDeliveryStatusPending
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= webhook.MaxAttempts {
+			delivery.Status = WebhookDeliveryStatusFailed
+		}
+	}
+	if saveErr := d.repository.Save(ctx, *delivery); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// post sends the signed event to the webhook and returns the response status.
+func (d *WebhookDispatcher) post(ctx context.Context, webhook Webhook, event EntityEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(X_ENDOR_EVENT_ID, event.Id)
+	if webhook.SecretEnv != "" {
+		secret := os.Getenv(webhook.SecretEnv)
+		if secret == "" {
+			return 0, fmt.Errorf("webhook secret %s is not set", webhook.SecretEnv)
+		}
+		request.Header.Set(X_ENDOR_SIGNATURE, SignWebhookPayload([]byte(secret), body))
+	}
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook %s answered %s", webhook.URL, response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// #endregion
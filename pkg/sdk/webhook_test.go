@@ -0,0 +1,190 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookOrder struct {
+	Code   string `json:"code"`
+	ApiKey string `json:"apiKey" schema:"writeOnly=true"`
+}
+
+func TestWebhookDispatcher_DeliversSignedEvents(t *testing.T) {
+	t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+	var received sdk.EntityEvent
+	var valid bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		valid = sdk.VerifyWebhookSignature([]byte("s3cret"), body, r.Header.Get(sdk.X_ENDOR_SIGNATURE))
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	repository := sdk.NewInMemoryWebhookDeliveryRepository()
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	bus.Subscribe(sdk.NewWebhookDispatcher(repository).Subscription("order", sdk.Webhook{
+		Id:        "erp",
+		URL:       server.URL,
+		Events:    []sdk.EntityEventType{sdk.EntityEventCreated},
+		SecretEnv: "TEST_WEBHOOK_SECRET",
+	}, func(event sdk.EntityEvent) *sdk.RootSchema { return sdk.NewSchema(webhookOrder{}) }))
+
+	event := sdk.NewEntityEvent(sdk.EntityEventCreated, "order", "", "order-1", nil, map[string]any{"code": "A", "apiKey": "k-1"}, sdk.Session{UserId: "user-1", Roles: []string{"sales"}, Permissions: []string{"order:read"}})
+	require.NoError(t, bus.Publish(context.Background(), event))
+	require.NoError(t, bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventDeleted)))
+	bus.Wait()
+
+	assert.True(t, valid)
+	assert.Equal(t, event.Id, received.Id)
+	assert.Equal(t, map[string]any{"code": "A", "apiKey": sdk.AuditRedacted}, received.After, "the writeOnly fields are redacted")
+	assert.Equal(t, "user-1", received.Session.UserId)
+	assert.Empty(t, received.Session.Roles)
+	assert.Empty(t, received.Session.Permissions)
+	deliveries, err := repository.List(context.Background(), sdk.ReadWebhookDeliveriesDTO{EntityId: "order"})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, event.Id+"/erp", deliveries[0].Id)
+	assert.Equal(t, sdk.WebhookDeliveryStatusDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+func TestWebhookDispatcher_Filter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	repository := sdk.NewInMemoryWebhookDeliveryRepository()
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	bus.Subscribe(sdk.NewWebhookDispatcher(repository).Subscription("order", sdk.Webhook{
+		Id:     "0",
+		URL:    server.URL,
+		Filter: map[string]any{"code": map[string]any{"$in": []any{"B", "C"}}},
+	}, nil))
+
+	require.NoError(t, bus.Publish(context.Background(), newOrderEvent(sdk.EntityEventCreated)))
+	// deletions are matched against the previous state
+	deleted := sdk.NewEntityEvent(sdk.EntityEventDeleted, "order", "", "order-2", map[string]any{"code": "B"}, nil, sdk.Session{})
+	require.NoError(t, bus.Publish(context.Background(), deleted))
+	bus.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	deliveries, err := repository.List(context.Background(), sdk.ReadWebhookDeliveriesDTO{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "order-2", deliveries[0].InstanceId)
+}
+
+func TestWebhookDispatcher_RetriesAndFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repository := sdk.NewInMemoryWebhookDeliveryRepository()
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	bus.Subscribe(sdk.NewWebhookDispatcher(repository).Subscription("order", sdk.Webhook{
+		Id:          "0",
+		URL:         server.URL,
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+	}, nil))
+
+	event := newOrderEvent(sdk.EntityEventUpdated)
+	require.NoError(t, bus.Publish(context.Background(), event))
+	bus.Wait()
+
+	delivery, err := repository.Instance(context.Background(), event.Id+"/0")
+	require.NoError(t, err)
+	assert.Equal(t, sdk.WebhookDeliveryStatusFailed, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseStatus)
+	assert.Contains(t, delivery.LastError, "503")
+	assert.Nil(t, delivery.DeliveredAt)
+}
+
+func TestWebhookDispatcher_MissingSecretIsNotSentUnsigned(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	repository := sdk.NewInMemoryWebhookDeliveryRepository()
+	bus := sdk.NewEventBus("test-service", testDIContainer{}, nil)
+	bus.Subscribe(sdk.NewWebhookDispatcher(repository).Subscription("order", sdk.Webhook{
+		Id:          "0",
+		URL:         server.URL,
+		SecretEnv:   "TEST_WEBHOOK_SECRET_MISSING",
+		MaxAttempts: 1,
+	}, nil))
+
+	event := newOrderEvent(sdk.EntityEventCreated)
+	require.NoError(t, bus.Publish(context.Background(), event))
+	bus.Wait()
+
+	assert.Zero(t, calls.Load())
+	delivery, err := repository.Instance(context.Background(), event.Id+"/0")
+	require.NoError(t, err)
+	assert.Equal(t, sdk.WebhookDeliveryStatusFailed, delivery.Status)
+	assert.Contains(t, delivery.LastError, "TEST_WEBHOOK_SECRET_MISSING")
+}
+
+func TestWebhookDispatcher_OutboxPublisher(t *testing.T) {
+	var calls atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	repository := sdk.NewInMemoryWebhookDeliveryRepository()
+	publisher := sdk.NewWebhookDispatcher(repository).OutboxPublisher(func(entityId string) []sdk.Webhook {
+		if entityId != "order" {
+			return nil
+		}
+		return []sdk.Webhook{
+			{Id: "ok", URL: server.URL},
+			{Id: "down", URL: failing.URL, MaxAttempts: 2},
+			{Id: "deletions", URL: server.URL, Events: []sdk.EntityEventType{sdk.EntityEventDeleted}},
+		}
+	}, nil)
+
+	event := newOrderEvent(sdk.EntityEventCreated)
+	require.Error(t, publisher.Publish(context.Background(), event))
+	// the outbox retries every publisher: the delivered webhooks are not sent again
+	require.Error(t, publisher.Publish(context.Background(), event))
+	require.NoError(t, publisher.Publish(context.Background(), event), "failed deliveries are not retried")
+	assert.Equal(t, int32(1), calls.Load())
+
+	down, err := repository.Instance(context.Background(), event.Id+"/down")
+	require.NoError(t, err)
+	assert.Equal(t, sdk.WebhookDeliveryStatusFailed, down.Status)
+	assert.Equal(t, 2, down.Attempts)
+	_, err = repository.Instance(context.Background(), event.Id+"/deletions")
+	require.Error(t, err)
+
+	development := newOrderEvent(sdk.EntityEventCreated)
+	development.Session.Development = true
+	require.NoError(t, publisher.Publish(context.Background(), development))
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,71 @@
+package sdk_client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, without contacting the remote service, while the circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calling a failing service: after Threshold consecutive failures
+// the circuit opens and calls fail fast for Cooldown; then a single trial call is let
+// through and closes the circuit again if it succeeds.
+// A CircuitBreaker is safe for concurrent use and is meant to be shared by all the
+// clients targeting the same service.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a call may be attempted.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.now().Before(b.openUntil) || b.trial {
+		return ErrCircuitOpen
+	}
+	// half-open: let a single trial call through
+	b.trial = true
+	return nil
+}
+
+// record updates the circuit with the outcome of a call.
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// Open reports whether calls are currently rejected.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && (b.now().Before(b.openUntil) || b.trial)
+}
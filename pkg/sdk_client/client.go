@@ -0,0 +1,226 @@
+// Package sdk_client calls the actions of other Endor microservices over HTTP,
+// propagating the caller session.
+package sdk_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Client calls actions exposed under <baseURL>/api/v1/<module>/<entity>/[<category>/]<action>.
+// The With* methods return a configured copy, so a Client built once at startup can be
+// bound to the session of each request with WithSession.
+type Client struct {
+	baseURL         string
+	session         sdk.Session
+	httpClient      *http.Client
+	timeout         time.Duration
+	maxRetries      int
+	retryBackoff    time.Duration
+	retryOnStatuses []int
+	circuitBreaker  *CircuitBreaker
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		httpClient:      http.DefaultClient,
+		timeout:         defaultTimeout,
+		retryBackoff:    100 * time.Millisecond,
+		retryOnStatuses: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// WithSession propagates the session identity, locale and access token to the called service.
+func (c *Client) WithSession(session sdk.Session) *Client {
+	copy := *c
+	copy.session = session
+	return &copy
+}
+
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	copy := *c
+	copy.httpClient = httpClient
+	return &copy
+}
+
+// WithTimeout bounds every attempt; 0 disables the timeout.
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	copy := *c
+	copy.timeout = timeout
+	return &copy
+}
+
+// WithRetries retries transport errors and retryable statuses (429, 502, 503, 504 unless
+// overridden) up to maxRetries times, doubling backoff after each attempt.
+// Only enable retries for idempotent actions.
+func (c *Client) WithRetries(maxRetries int, backoff time.Duration, retryOnStatuses ...int) *Client {
+	copy := *c
+	copy.maxRetries = maxRetries
+	copy.retryBackoff = backoff
+	if len(retryOnStatuses) > 0 {
+		copy.retryOnStatuses = retryOnStatuses
+	}
+	return &copy
+}
+
+func (c *Client) WithCircuitBreaker(circuitBreaker *CircuitBreaker) *Client {
+	copy := *c
+	copy.circuitBreaker = circuitBreaker
+	return &copy
+}
+
+// ActionError is the error returned by the called service, with its original messages.
+type ActionError struct {
+	ActionId   string
+	StatusCode int
+	Messages   []sdk.ResponseMessage
+}
+
+func (e *ActionError) Error() string {
+	values := make([]string, 0, len(e.Messages))
+	for _, message := range e.Messages {
+		values = append(values, message.Value)
+	}
+	if len(values) == 0 {
+		return fmt.Sprintf("action %s failed with status %d", e.ActionId, e.StatusCode)
+	}
+	return strings.Join(values, "; ")
+}
+
+// Call invokes actionId with payload and decodes the typed response.
+// Non-2xx answers are returned as *sdk.EndorError with the original status, wrapping an
+// *ActionError; transport failures map to 502, timeouts to 504 and an open circuit to 503.
+func Call[R any](ctx context.Context, client *Client, actionId string, payload any) (*sdk.Response[R], error) {
+	body := []byte("{}")
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, sdk.NewBadRequestError(err)
+		}
+		body = encoded
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= client.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, client.transportError(actionId, ctx.Err())
+			case <-time.After(client.retryBackoff << (attempt - 1)):
+			}
+		}
+		if client.circuitBreaker != nil {
+			if err := client.circuitBreaker.allow(); err != nil {
+				return nil, sdk.NewGenericError(http.StatusServiceUnavailable, fmt.Errorf("%s: %w", client.baseURL, err)).
+					WithTranslation("sdk.client.unavailable", map[string]any{"action": actionId})
+			}
+		}
+		status, responseBody, err := client.do(ctx, actionId, body)
+		failed := err != nil || status >= http.StatusInternalServerError
+		if client.circuitBreaker != nil {
+			client.circuitBreaker.record(!failed)
+		}
+		if err != nil {
+			lastErr = client.transportError(actionId, err)
+			if ctx.Err() != nil {
+				return nil, lastErr
+			}
+			continue
+		}
+		if status >= 200 && status < 300 {
+			response := &sdk.Response[R]{}
+			if err := json.Unmarshal(responseBody, response); err != nil {
+				return nil, sdk.NewGenericError(http.StatusBadGateway, fmt.Errorf("decode response of %s: %w", actionId, err))
+			}
+			return response, nil
+		}
+		lastErr = actionError(actionId, status, responseBody)
+		if !slices.Contains(client.retryOnStatuses, status) {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) do(ctx context.Context, actionId string, body []byte) (int, []byte, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/"+strings.TrimPrefix(actionId, "/"), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	c.setHeaders(request)
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return response.StatusCode, responseBody, nil
+}
+
+// setHeaders sends the session. With an access token the identity travels only in the
+// token: the permissions of the session may include the ones granted by the roles of the
+// caller, which a callee verifying the token would reject as not matching its claims.
+func (c *Client) setHeaders(request *http.Request) {
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	headers := map[string]string{
+		"Accept-Language": c.session.Locale,
+		"Authorization":   c.session.AccessToken,
+	}
+	if c.session.AccessToken == "" {
+		headers[sdk.X_ENDOR_SESSION_ID] = c.session.Id
+		headers[sdk.X_ENDOR_USER_ID] = c.session.UserId
+		headers[sdk.X_ENDOR_USERNAME] = c.session.Username
+		headers[sdk.X_ENDOR_ROLES] = strings.Join(c.session.Roles, ",")
+		headers[sdk.X_ENDOR_PERMISSIONS] = strings.Join(c.session.Permissions, ",")
+	}
+	if c.session.Development {
+		headers[sdk.X_ENDOR_DEVELOPMENT] = "true"
+	}
+	for name, value := range headers {
+		if value != "" {
+			request.Header.Set(name, value)
+		}
+	}
+}
+
+func (c *Client) transportError(actionId string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return sdk.NewGatewayTimeoutError(fmt.Errorf("call %s: %w", actionId, err)).
+			WithTranslation("sdk.commons.timeout", map[string]any{"action": actionId, "timeout": c.timeout.String()})
+	}
+	return sdk.NewGenericError(http.StatusBadGateway, fmt.Errorf("call %s: %w", actionId, err)).
+		WithTranslation("sdk.client.unavailable", map[string]any{"action": actionId})
+}
+
+func actionError(actionId string, status int, body []byte) error {
+	remote := &ActionError{ActionId: actionId, StatusCode: status}
+	var response sdk.Response[any]
+	if err := json.Unmarshal(body, &response); err == nil {
+		remote.Messages = response.Messages
+	} else if text := strings.TrimSpace(string(body)); text != "" {
+		remote.Messages = []sdk.ResponseMessage{sdk.NewMessage(sdk.ResponseMessageGravityFatal, text)}
+	}
+	return sdk.NewGenericError(status, remote)
+}
@@ -0,0 +1,179 @@
+package sdk_client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	Code string `json:"code"`
+}
+
+func writeResponse(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestCall_PropagatesSessionAndDecodesResponse(t *testing.T) {
+	var request *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+		data := order{Code: "ORD-1"}
+		writeResponse(w, http.StatusOK, sdk.NewResponseBuilder[order]().
+			AddData(&data).
+			AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, "created")).
+			AddReferences(sdk.EntityRefererenceGroup{"customer": {"c1": "Mario Rossi"}}).
+			Build())
+	}))
+	defer server.Close()
+
+	client := sdk_client.NewClient(server.URL + "/").WithSession(sdk.Session{
+		Id:          "session-1",
+		UserId:      "user-1",
+		Username:    "mario",
+		Development: true,
+		Locale:      "it",
+		AccessToken: "Bearer token",
+		Roles:       []string{"sales", "admin"},
+	})
+	response, err := sdk_client.Call[order](context.Background(), client, "sales/order/create", map[string]any{"code": "ORD-1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, "/api/v1/sales/order/create", request.URL.Path)
+	assert.JSONEq(t, `{"code":"ORD-1"}`, string(body))
+	for _, header := range []string{sdk.X_ENDOR_SESSION_ID, sdk.X_ENDOR_USER_ID, sdk.X_ENDOR_USERNAME, sdk.X_ENDOR_ROLES, sdk.X_ENDOR_PERMISSIONS} {
+		assert.Empty(t, request.Header.Get(header), "the identity travels in the token: %s", header)
+	}
+	assert.Equal(t, "true", request.Header.Get(sdk.X_ENDOR_DEVELOPMENT))
+	assert.Equal(t, "it", request.Header.Get("Accept-Language"))
+	assert.Equal(t, "Bearer token", request.Header.Get("Authorization"))
+
+	assert.Equal(t, "ORD-1", response.Data.Code)
+	require.Len(t, response.Messages, 1)
+	assert.Equal(t, "created", response.Messages[0].Value)
+	require.NotNil(t, response.References)
+	assert.Equal(t, "Mario Rossi", (*response.References)["customer"]["c1"])
+
+	// without a token the identity headers are sent
+	_, err = sdk_client.Call[order](context.Background(), client.WithSession(sdk.Session{
+		Id:       "session-1",
+		UserId:   "user-1",
+		Username: "mario",
+		Roles:    []string{"sales", "admin"},
+	}), "sales/order/create", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "session-1", request.Header.Get(sdk.X_ENDOR_SESSION_ID))
+	assert.Equal(t, "user-1", request.Header.Get(sdk.X_ENDOR_USER_ID))
+	assert.Equal(t, "mario", request.Header.Get(sdk.X_ENDOR_USERNAME))
+	assert.Equal(t, "sales,admin", request.Header.Get(sdk.X_ENDOR_ROLES))
+	assert.Empty(t, request.Header.Get(sdk.X_ENDOR_PERMISSIONS))
+	assert.Empty(t, request.Header.Get("Authorization"))
+}
+
+func TestCall_MapsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusForbidden, sdk.NewDefaultResponseBuilder().
+			AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityFatal, "not allowed")).Build())
+	}))
+	defer server.Close()
+
+	_, err := sdk_client.Call[order](context.Background(), sdk_client.NewClient(server.URL), "sales/order/delete", nil)
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusForbidden, endorError.StatusCode)
+	assert.Equal(t, "not allowed", endorError.Error())
+	var actionError *sdk_client.ActionError
+	require.ErrorAs(t, err, &actionError)
+	assert.Equal(t, "sales/order/delete", actionError.ActionId)
+}
+
+func TestCall_RetriesRetryableStatuses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			writeResponse(w, http.StatusServiceUnavailable, sdk.NewDefaultResponseBuilder().Build())
+			return
+		}
+		writeResponse(w, http.StatusOK, sdk.NewResponseBuilder[order]().Build())
+	}))
+	defer server.Close()
+
+	client := sdk_client.NewClient(server.URL).WithRetries(2, time.Millisecond)
+	_, err := sdk_client.Call[order](context.Background(), client, "sales/order/list", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	_, err = sdk_client.Call[order](context.Background(), client.WithRetries(1, time.Millisecond), "sales/order/list", nil)
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusServiceUnavailable, endorError.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestCall_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	defer server.Close()
+
+	_, err := sdk_client.Call[order](context.Background(), sdk_client.NewClient(server.URL).WithTimeout(20*time.Millisecond), "sales/order/list", nil)
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusGatewayTimeout, endorError.StatusCode)
+	assert.Equal(t, "sdk.commons.timeout", endorError.TranslationKey)
+}
+
+func TestCall_CircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	healthy := atomic.Bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			writeResponse(w, http.StatusInternalServerError, sdk.NewDefaultResponseBuilder().Build())
+			return
+		}
+		writeResponse(w, http.StatusOK, sdk.NewResponseBuilder[order]().Build())
+	}))
+	defer server.Close()
+
+	breaker := sdk_client.NewCircuitBreaker(2, 50*time.Millisecond)
+	client := sdk_client.NewClient(server.URL).WithCircuitBreaker(breaker)
+	for i := 0; i < 2; i++ {
+		_, err := sdk_client.Call[order](context.Background(), client, "sales/order/list", nil)
+		require.Error(t, err)
+	}
+	assert.True(t, breaker.Open())
+
+	_, err := sdk_client.Call[order](context.Background(), client, "sales/order/list", nil)
+	assert.True(t, errors.Is(err, sdk_client.ErrCircuitOpen))
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusServiceUnavailable, endorError.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	_, err = sdk_client.Call[order](context.Background(), client, "sales/order/list", nil)
+	require.NoError(t, err)
+	assert.False(t, breaker.Open())
+}
@@ -0,0 +1,160 @@
+package sdk_client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+const (
+	defaultReferenceTTL       = time.Minute
+	defaultReferenceBatchSize = 100
+	// maxCachedReferences bounds the cache: once reached, expired descriptions are dropped
+	// and, if still full, the whole cache.
+	maxCachedReferences = 10000
+)
+
+// ReferenceResolverOptions configures a ReferenceResolver.
+type ReferenceResolverOptions struct {
+	// TTL keeps the descriptions in cache, 1 minute when 0; a negative TTL disables the cache.
+	TTL time.Duration
+	// BatchSize is the maximum number of ids of a call, 100 when 0.
+	BatchSize int
+	// Clients route modules to other services than the one of the client passed to
+	// NewReferenceResolver.
+	Clients map[string]*Client
+	// Logger, if set, logs the failed calls (their references are left out of the responses).
+	Logger *sdk.Logger
+}
+
+type cachedReference struct {
+	description string
+	expiresAt   time.Time
+}
+
+// ReferenceResolver is the sdk.ReferenceResolver resolving the references to the entities
+// of other modules with the references action of their entity handler
+// ("<module>/entity/references"), called with the session of the request. The ids of an
+// entity are sent in batches of BatchSize and their descriptions are cached for TTL, by
+// locale and caller (user, development overlay and permissions), since the service of the
+// module answers according to them. A ReferenceResolver is safe for concurrent use.
+type ReferenceResolver struct {
+	client    *Client
+	clients   map[string]*Client
+	ttl       time.Duration
+	batchSize int
+	logger    *sdk.Logger
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedReference
+}
+
+// NewReferenceResolver resolves the references with client, typically bound to the API
+// gateway, which routes every module to its service.
+func NewReferenceResolver(client *Client, options ReferenceResolverOptions) *ReferenceResolver {
+	if options.TTL == 0 {
+		options.TTL = defaultReferenceTTL
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultReferenceBatchSize
+	}
+	return &ReferenceResolver{
+		client:    client,
+		clients:   options.Clients,
+		ttl:       options.TTL,
+		batchSize: options.BatchSize,
+		logger:    options.Logger,
+		now:       time.Now,
+		cache:     map[string]cachedReference{},
+	}
+}
+
+// LogsFailures reports whether the resolver logs its failed calls, that is whether it has
+// a Logger.
+func (r *ReferenceResolver) LogsFailures() bool {
+	return r.logger != nil
+}
+
+// ResolveReferences returns the descriptions of the instances of entityId with the given
+// ids, calling the service of its module only for the ids not in cache.
+func (r *ReferenceResolver) ResolveReferences(ctx context.Context, entityId string, ids []string) (sdk.EntityReferenceGroupDescriptions, error) {
+	module, entity, err := sdk.ParseEntityID(entityId)
+	if err != nil {
+		return nil, sdk.NewBadRequestError(fmt.Errorf("reference to %s: %w", entityId, err))
+	}
+	session, _ := sdk.SessionFromContext(ctx)
+	keyPrefix := cacheScope(session) + "|" + entityId + "|"
+
+	descriptions := sdk.EntityReferenceGroupDescriptions{}
+	missing := []string{}
+	r.mu.Lock()
+	now := r.now()
+	for _, id := range ids {
+		if cached, ok := r.cache[keyPrefix+id]; ok && now.Before(cached.expiresAt) {
+			descriptions[id] = cached.description
+		} else if !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	r.mu.Unlock()
+
+	client := r.client
+	if moduleClient, ok := r.clients[module]; ok {
+		client = moduleClient
+	}
+	client = client.WithSession(session)
+	actionId := module + "/entity/references"
+	for batch := range slices.Chunk(missing, r.batchSize) {
+		response, err := Call[sdk.EntityReferenceGroupDescriptions](ctx, client, actionId, sdk.ReadReferencesDTO{Entity: entity, Ids: batch})
+		if err != nil {
+			if r.logger != nil {
+				r.logger.WarnWithFields("Failed to resolve remote references", map[string]interface{}{"entity": entityId, "error": err.Error()})
+			}
+			return nil, err
+		}
+		if response.Data == nil {
+			continue
+		}
+		r.store(keyPrefix, *response.Data)
+		for id, description := range *response.Data {
+			descriptions[id] = description
+		}
+	}
+	return descriptions, nil
+}
+
+// cacheScope identifies the callers sharing the cached descriptions: those with the same
+// locale, user, development overlay and permissions.
+func cacheScope(session sdk.Session) string {
+	permissions := slices.Sorted(slices.Values(session.Permissions))
+	return strings.Join([]string{session.Locale, session.UserId, session.Username, strconv.FormatBool(session.Development), strings.Join(permissions, ",")}, "|")
+}
+
+func (r *ReferenceResolver) store(keyPrefix string, descriptions sdk.EntityReferenceGroupDescriptions) {
+	if r.ttl < 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if len(r.cache)+len(descriptions) > maxCachedReferences {
+		for key, cached := range r.cache {
+			if !now.Before(cached.expiresAt) {
+				delete(r.cache, key)
+			}
+		}
+		if len(r.cache)+len(descriptions) > maxCachedReferences {
+			clear(r.cache)
+		}
+	}
+	for id, description := range descriptions {
+		r.cache[keyPrefix+id] = cachedReference{description: description, expiresAt: now.Add(r.ttl)}
+	}
+}
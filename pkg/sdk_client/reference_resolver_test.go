@@ -0,0 +1,93 @@
+package sdk_client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceResolver_BatchesAndCaches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	var paths, locales []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload sdk.ReadReferencesDTO
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		mu.Lock()
+		batches = append(batches, payload.Ids)
+		paths = append(paths, r.URL.Path)
+		locales = append(locales, r.Header.Get("Accept-Language"))
+		mu.Unlock()
+		descriptions := sdk.EntityReferenceGroupDescriptions{}
+		for _, id := range payload.Ids {
+			if id != "missing" {
+				descriptions[id] = payload.Entity + " " + id
+			}
+		}
+		writeResponse(w, http.StatusOK, sdk.NewResponseBuilder[sdk.EntityReferenceGroupDescriptions]().AddData(&descriptions).Build())
+	}))
+	defer server.Close()
+
+	resolver := sdk_client.NewReferenceResolver(sdk_client.NewClient(server.URL), sdk_client.ReferenceResolverOptions{BatchSize: 2})
+	ctx := sdk.ContextWithSession(context.Background(), sdk.Session{Locale: "it"})
+
+	descriptions, err := resolver.ResolveReferences(ctx, "billing/invoice", []string{"i1", "i2", "i1", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, sdk.EntityReferenceGroupDescriptions{"i1": "invoice i1", "i2": "invoice i2"}, descriptions)
+	assert.Equal(t, [][]string{{"i1", "i2"}, {"missing"}}, batches)
+	assert.Equal(t, []string{"/api/v1/billing/entity/references", "/api/v1/billing/entity/references"}, paths)
+	assert.Equal(t, []string{"it", "it"}, locales)
+
+	descriptions, err = resolver.ResolveReferences(ctx, "billing/invoice", []string{"i2", "i3"})
+	require.NoError(t, err)
+	assert.Equal(t, sdk.EntityReferenceGroupDescriptions{"i2": "invoice i2", "i3": "invoice i3"}, descriptions)
+	assert.Equal(t, []string{"i3"}, batches[2], "cached descriptions are not requested again")
+
+	_, err = resolver.ResolveReferences(sdk.ContextWithSession(context.Background(), sdk.Session{Locale: "en"}), "billing/invoice", []string{"i2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i2"}, batches[3], "descriptions are cached by locale")
+
+	_, err = resolver.ResolveReferences(sdk.ContextWithSession(context.Background(), sdk.Session{Locale: "it", UserId: "u2"}), "billing/invoice", []string{"i2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i2"}, batches[4], "descriptions are cached by user")
+
+	_, err = resolver.ResolveReferences(sdk.ContextWithSession(context.Background(), sdk.Session{Locale: "it", Permissions: []string{"invoice:read"}}), "billing/invoice", []string{"i2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i2"}, batches[5], "descriptions are cached by permissions")
+}
+
+func TestReferenceResolver_Routes(t *testing.T) {
+	called := false
+	crm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		descriptions := sdk.EntityReferenceGroupDescriptions{"l1": "Lead"}
+		writeResponse(w, http.StatusOK, sdk.NewResponseBuilder[sdk.EntityReferenceGroupDescriptions]().AddData(&descriptions).Build())
+	}))
+	defer crm.Close()
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusServiceUnavailable, sdk.NewDefaultResponseBuilder().Build())
+	}))
+	defer gateway.Close()
+
+	resolver := sdk_client.NewReferenceResolver(sdk_client.NewClient(gateway.URL), sdk_client.ReferenceResolverOptions{
+		Clients: map[string]*sdk_client.Client{"crm": sdk_client.NewClient(crm.URL)},
+	})
+
+	descriptions, err := resolver.ResolveReferences(context.Background(), "crm/lead", []string{"l1"})
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, sdk.EntityReferenceGroupDescriptions{"l1": "Lead"}, descriptions)
+
+	_, err = resolver.ResolveReferences(context.Background(), "billing/invoice", []string{"i1"})
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusServiceUnavailable, endorError.StatusCode)
+}
@@ -3,6 +3,7 @@ package sdk_configuration
 import (
 	"log"
 	"os"
+	"strconv"
 	"sync"
 
 	"github.com/joho/godotenv"
@@ -14,6 +15,25 @@ type ServerConfig struct {
 	ModuleDBName  string
 	LogType       string
 	Development   bool
+	// Local JWT verification of the Authorization bearer token: enabled when a JWKS file
+	// or a PEM public key is configured. JWTAudience, if set, must be in the aud claim.
+	JWTJWKSPath      string
+	JWTPublicKeyPath string
+	JWTAudience      string
+	// Worker pool of the async actions: JobWorkers jobs run at the same time and at most
+	// JobQueueSize wait for a free worker.
+	JobWorkers   int
+	JobQueueSize int
+	// Transactional outbox of the entity events: OutboxEnabled records them with the writes
+	// (Mongo transactions need a replica set); OutboxWebhookURL, if set, receives them and
+	// implies OutboxEnabled.
+	OutboxEnabled    bool
+	OutboxWebhookURL string
+	// AuditEnabled records the invocations of the non-read actions in the audit trail.
+	AuditEnabled bool
+	// ReferenceResolverURL is the base URL (e.g. the API gateway) of the services resolving
+	// the references to the entities of other modules; if empty they are left unresolved.
+	ReferenceResolverURL string
 }
 
 // Variabili globali per il singleton
@@ -44,10 +64,19 @@ func loadConfiguration() *ServerConfig {
 	development := getEnvAsBool("DEVELOPMENT", false)
 
 	return &ServerConfig{
-		ServerPort:    port,
-		DocumentDBUri: dbUri,
-		LogType:       logType,
-		Development:   development,
+		ServerPort:           port,
+		DocumentDBUri:        dbUri,
+		LogType:              logType,
+		Development:          development,
+		JWTJWKSPath:          getEnv("JWT_JWKS_PATH", ""),
+		JWTPublicKeyPath:     getEnv("JWT_PUBLIC_KEY_PATH", ""),
+		JWTAudience:          getEnv("JWT_AUDIENCE", ""),
+		JobWorkers:           getEnvAsInt("JOB_WORKERS", 4),
+		JobQueueSize:         getEnvAsInt("JOB_QUEUE_SIZE", 100),
+		OutboxEnabled:        getEnvAsBool("OUTBOX_ENABLED", false),
+		OutboxWebhookURL:     getEnv("OUTBOX_WEBHOOK_URL", ""),
+		AuditEnabled:         getEnvAsBool("AUDIT_ENABLED", false),
+		ReferenceResolverURL: getEnv("REFERENCE_RESOLVER_URL", ""),
 	}
 }
 
@@ -68,3 +97,12 @@ func getEnvAsBool(key string, defaultVal bool) bool {
 	}
 	return defaultVal
 }
+
+func getEnvAsInt(key string, defaultVal int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,71 @@
+package sdk_entity
+
+import (
+	"github.com/mattiabonardi/endor-sdk-go/internal/repository"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+// NewAuditRepository returns the MongoDB repository of the audit trail.
+func NewAuditRepository() sdk.AuditRepositoryInterface {
+	return repository.NewMongoAuditRepository()
+}
+
+// NewAuditHandler exposes the audit trail of the non-read actions, read-only, to the
+// sessions granted sdk.AuditReadPermission.
+func NewAuditHandler() sdk.EndorHandlerInterface {
+	auditService := AuditHandler{}
+	return NewEndorBaseHandler[*sdk.AuditEntry]("audit", "${t.sdk.audit.handler.title}").
+		WithActions(sdk.WithActionRequiredPermissions(map[string]sdk.EndorHandlerActionInterface{
+			"schema": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.audit.handler.actions.schema}",
+					ReadOnly:    true,
+				},
+				auditService.schema,
+			),
+			"list": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.audit.handler.actions.list}",
+					ReadOnly:    true,
+				},
+				auditService.list,
+			),
+			"instance": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.audit.handler.actions.instance}",
+					ReadOnly:    true,
+				},
+				auditService.instance,
+			),
+		}, sdk.AuditReadPermission))
+}
+
+type AuditHandler struct{}
+
+func (h *AuditHandler) schema(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+	return sdk.NewResponseBuilder[any]().AddSchema(sdk.NewSchema(&sdk.AuditEntry{})).Build(), nil
+}
+
+func (h *AuditHandler) list(c *sdk.EndorContext[sdk.ReadAuditEntriesDTO]) (*sdk.Response[[]sdk.AuditEntry], error) {
+	entries, err := h.repository(c.DIContainer).List(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[[]sdk.AuditEntry]().AddData(&entries).AddSchema(sdk.NewSchema(&sdk.AuditEntry{})).Build(), nil
+}
+
+func (h *AuditHandler) instance(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk.Response[sdk.AuditEntry], error) {
+	entry, err := h.repository(c.DIContainer).Instance(c.Context(), c.Payload.Id)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[sdk.AuditEntry]().AddData(entry).AddSchema(sdk.NewSchema(&sdk.AuditEntry{})).Build(), nil
+}
+
+// repository returns the storage of the audit trail of the container.
+func (h *AuditHandler) repository(container sdk.EndorDIContainerInterface) sdk.AuditRepositoryInterface {
+	if trail := container.GetAuditTrail(); trail != nil {
+		return trail.Repository()
+	}
+	return NewAuditRepository()
+}
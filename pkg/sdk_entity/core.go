@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,6 +39,7 @@ func InitRegistryCore(microServiceId string, module string, internalEndorHandler
 			Mu:                    &sync.RWMutex{},
 			ProdDAO:               sdk.NewDSLDAO("", false),
 			EphemeralCache:        NewEphemeralCacheManager(),
+			Services:              Services{JobManager: sdk.NewJobManager(sdk.NewInMemoryJobRepository(), 0, 0)},
 			projectLocalesFS:      projectLocalesFS,
 		}
 		if err := registryCoreInstance.startDslProdWatcher(); err != nil {
@@ -61,6 +63,11 @@ type RegistryCore struct {
 	// DevDAOFactory, if non-nil, is called instead of sdk.NewDSLDAO when building
 	// a development overlay. Used in tests to inject a custom DAO path.
 	DevDAOFactory func(username string) *sdk.DSLDAO
+	// ActionMiddlewares are applied to every action resolved through the registry,
+	// outside the handler and action middlewares.
+	ActionMiddlewares []sdk.EndorActionMiddleware
+	// Services are exposed to the actions and repositories by the DI containers.
+	Services Services
 
 	CachedDictionary  map[string]EndorEntityDictionary
 	CachedDIContainer *EndorDIContainer
@@ -68,6 +75,16 @@ type RegistryCore struct {
 	projectLocalesFS  fs.FS
 }
 
+// Services are the services of the microservice shared by all its DI containers; nil ones
+// are disabled.
+type Services struct {
+	JobManager        *sdk.JobManager
+	Outbox            *sdk.Outbox
+	WebhookDispatcher *sdk.WebhookDispatcher
+	AuditTrail        *sdk.AuditTrail
+	ReferenceResolver sdk.ReferenceResolver
+}
+
 // EndorEntityDictionary is the per-entity descriptor: compiled handler and entity metadata.
 type EndorEntityDictionary struct {
 	OriginalInstance *sdk.EndorHandlerInterface
@@ -89,6 +106,7 @@ type dslCategory struct {
 	Title       string         `yaml:"title"`
 	Description string         `yaml:"description"`
 	Schema      sdk.RootSchema `yaml:"schema"`
+	Permissions []string       `yaml:"permissions"`
 }
 
 // entityDSLFile is the YAML structure for entity definition files.
@@ -98,6 +116,33 @@ type entityDSLFile struct {
 	Description string         `yaml:"description"`
 	Schema      sdk.RootSchema `yaml:"schema"`
 	Categories  []dslCategory  `yaml:"categories"`
+	// Permissions are required by every action of the entity.
+	Permissions []string `yaml:"permissions"`
+	// Schedules invoke actions of the entity periodically with the system session.
+	Schedules []sdk.Schedule `yaml:"schedules"`
+	// Events invoke actions of the entity when instances of an entity are written.
+	Events []sdk.EventSubscription `yaml:"events"`
+	// Webhooks POST the events of the entity to external URLs.
+	Webhooks []sdk.Webhook `yaml:"webhooks"`
+	// Versioned enables optimistic concurrency on the instances (ETag and If-Match).
+	Versioned bool `yaml:"versioned"`
+	// SoftDelete keeps the deleted instances, restorable with the trash and restore actions.
+	SoftDelete bool `yaml:"softDelete"`
+	// History records the versions of the instances, readable and revertible with actions.
+	History bool `yaml:"history"`
+}
+
+// validateDeletePolicies rejects the unknown onDelete policies of the schemas of the file.
+func (f entityDSLFile) validateDeletePolicies() error {
+	if err := f.Schema.ValidateDeletePolicies(); err != nil {
+		return err
+	}
+	for _, category := range f.Categories {
+		if err := category.Schema.ValidateDeletePolicies(); err != nil {
+			return fmt.Errorf("category %s: %w", category.ID, err)
+		}
+	}
+	return nil
 }
 
 // #region Public API
@@ -186,10 +231,11 @@ func toDSLCategories(dslCats []dslCategory) ([]sdk.Category, error) {
 			return nil, fmt.Errorf("marshal additional category %q additionalSchema: %w", c.ID, err)
 		}
 		result = append(result, sdk.Category{
-			ID:          c.ID,
-			Title:       c.Title,
-			Description: c.Description,
-			Schema:      s,
+			ID:                  c.ID,
+			Title:               c.Title,
+			Description:         c.Description,
+			Schema:              s,
+			RequiredPermissions: c.Permissions,
 		})
 	}
 	return result, nil
@@ -201,7 +247,8 @@ func (c *RegistryCore) buildCategorySchemas(cats []sdk.Category) ([]sdk.EndorHyb
 	handlerCats := make([]sdk.EndorHybridSpecializedHandlerCategoryInterface, 0, len(cats))
 	schemas := make(map[string]sdk.RootSchema, len(cats))
 	for _, cat := range cats {
-		handlerCats = append(handlerCats, NewEndorHybridSpecializedHandlerCategory[*sdk.DynamicEntitySpecialized](cat.ID, cat.Description))
+		handlerCats = append(handlerCats, NewEndorHybridSpecializedHandlerCategory[*sdk.DynamicEntitySpecialized](cat.ID, cat.Description).
+			WithRequiredPermissions(cat.RequiredPermissions...))
 		schemas[cat.ID] = sdk.RootSchema{}
 	}
 	return handlerCats, schemas
@@ -222,6 +269,15 @@ func (c *RegistryCore) buildHybridDSLEntry(entityID string, def entityDSLFile, e
 			return EndorEntityDictionary{}, err
 		}
 		cats, catsSchema := c.buildCategorySchemas(existing.Entity.Categories)
+		if def.Versioned {
+			specInst = specInst.WithVersioning()
+		}
+		if def.SoftDelete {
+			specInst = specInst.WithSoftDelete()
+		}
+		if def.History {
+			specInst = specInst.WithHistory()
+		}
 		entry.EndorHandler = specInst.WithHybridCategories(cats).ToEndorHandler(def.Schema, catsSchema, addCats)
 		cloned := existing.Entity
 		cloned.Schema = sdk.MergeSchemas(cloned.Schema, additionalSchema)
@@ -231,6 +287,15 @@ func (c *RegistryCore) buildHybridDSLEntry(entityID string, def entityDSLFile, e
 		if len(def.Categories) > 0 {
 			return EndorEntityDictionary{}, fmt.Errorf("hybrid DSL entity %q must not declare categories: plain hybrid handlers have no categories", entityID)
 		}
+		if def.Versioned {
+			hybridInst = hybridInst.WithVersioning()
+		}
+		if def.SoftDelete {
+			hybridInst = hybridInst.WithSoftDelete()
+		}
+		if def.History {
+			hybridInst = hybridInst.WithHistory()
+		}
 		entry.EndorHandler = hybridInst.ToEndorHandler(def.Schema)
 		cloned := existing.Entity
 		cloned.Schema = sdk.MergeSchemas(cloned.Schema, additionalSchema)
@@ -259,8 +324,18 @@ func (c *RegistryCore) buildDynamicDSLEntry(entityID string, entityName string,
 			Module:      c.Module,
 			Schema:      sdk.MergeSchemas(schema, additionalSchema),
 		}
-		handler := NewEndorHybridHandler[*sdk.DynamicEntity](entityName, def.Title).
-			WithExtendedDescription(def.Description).ToEndorHandler(def.Schema)
+		hybrid := NewEndorHybridHandler[*sdk.DynamicEntity](entityName, def.Title).
+			WithExtendedDescription(def.Description)
+		if def.Versioned {
+			hybrid = hybrid.WithVersioning()
+		}
+		if def.SoftDelete {
+			hybrid = hybrid.WithSoftDelete()
+		}
+		if def.History {
+			hybrid = hybrid.WithHistory()
+		}
+		handler := hybrid.ToEndorHandler(def.Schema)
 		entry = EndorEntityDictionary{EndorHandler: handler, Entity: e}
 	} else {
 		addCats, err := toDSLCategories(def.Categories)
@@ -278,9 +353,18 @@ func (c *RegistryCore) buildDynamicDSLEntry(entityID string, entityName string,
 			Schema:      sdk.MergeSchemas(schema, additionalSchema),
 			Categories:  addCats,
 		}
-		handler := NewEndorHybridSpecializedHandler[*sdk.DynamicEntitySpecialized](entityName, def.Title).
-			WithExtendedDescription(def.Description).WithHybridCategories(cats).
-			ToEndorHandler(def.Schema, catsSchema, addCats)
+		specialized := NewEndorHybridSpecializedHandler[*sdk.DynamicEntitySpecialized](entityName, def.Title).
+			WithExtendedDescription(def.Description).WithHybridCategories(cats)
+		if def.Versioned {
+			specialized = specialized.WithVersioning()
+		}
+		if def.SoftDelete {
+			specialized = specialized.WithSoftDelete()
+		}
+		if def.History {
+			specialized = specialized.WithHistory()
+		}
+		handler := specialized.ToEndorHandler(def.Schema, catsSchema, addCats)
 		entry = EndorEntityDictionary{EndorHandler: handler, Entity: e}
 	}
 	return entry, nil
@@ -308,6 +392,10 @@ func (c *RegistryCore) applyDSLOverlay(dict map[string]EndorEntityDictionary, da
 			c.Logger.Warn(fmt.Sprintf("invalid DSL entity %s: %s", entityName, err.Error()))
 			continue
 		}
+		if err := def.validateDeletePolicies(); err != nil {
+			c.Logger.Warn(fmt.Sprintf("invalid DSL entity %s: %s", entityName, err.Error()))
+			continue
+		}
 		entityID := path.Join(c.Module, entityName)
 		var entry EndorEntityDictionary
 		if existing, ok := dict[entityID]; ok && existing.OriginalInstance != nil {
@@ -319,11 +407,27 @@ func (c *RegistryCore) applyDSLOverlay(dict map[string]EndorEntityDictionary, da
 			c.Logger.Warn(fmt.Sprintf("unable to build entry for DSL entity %s: %s", entityName, err.Error()))
 			continue
 		}
+		entry = withEntityPermissions(entry, def.Permissions)
+		entry.EndorHandler.Schedules = append(append([]sdk.Schedule{}, entry.EndorHandler.Schedules...), def.Schedules...)
+		entry.EndorHandler.EventSubscriptions = append(append([]sdk.EventSubscription{}, entry.EndorHandler.EventSubscriptions...), def.Events...)
+		entry.EndorHandler.Webhooks = append(append([]sdk.Webhook{}, entry.EndorHandler.Webhooks...), def.Webhooks...)
 		dict[entityID] = entry
 	}
 	return translator
 }
 
+// withEntityPermissions makes every action of the entry require the given permissions.
+func withEntityPermissions(entry EndorEntityDictionary, permissions []string) EndorEntityDictionary {
+	if len(permissions) == 0 {
+		return entry
+	}
+	entry.EndorHandler.Actions = sdk.WithActionRequiredPermissions(entry.EndorHandler.Actions, permissions...)
+	entity := entry.Entity
+	entity.RequiredPermissions = append(append([]string{}, entity.RequiredPermissions...), permissions...)
+	entry.Entity = entity
+	return entry
+}
+
 // buildStaticEntry builds an EndorEntityDictionary from a compiled EndorHandlerInterface.
 func (c *RegistryCore) buildStaticEntry(h sdk.EndorHandlerInterface) (EndorEntityDictionary, error) {
 	schema, err := h.GetSchema().ToYAML()
@@ -351,10 +455,11 @@ func (c *RegistryCore) buildStaticEntry(h sdk.EndorHandlerInterface) (EndorEntit
 		entity.Type = string(sdk.EntityTypeBaseSpecialized)
 		for _, cat := range bs.GetCategories() {
 			entity.Categories = append(entity.Categories, sdk.Category{
-				ID:          cat.ID,
-				Title:       cat.Title,
-				Description: cat.Description,
-				Schema:      cat.Schema,
+				ID:                  cat.ID,
+				Title:               cat.Title,
+				Description:         cat.Description,
+				Schema:              cat.Schema,
+				RequiredPermissions: cat.RequiredPermissions,
 			})
 		}
 		handler = bs.ToEndorHandler()
@@ -420,10 +525,11 @@ func (c *RegistryCore) dictionaryMap() (map[string]EndorEntityDictionary, error)
 
 	c.applyDSLOverlay(dict, c.ProdDAO, c.projectLocalesFS)
 
-	prodContainer := &EndorDIContainer{}
+	prodContainer := &EndorDIContainer{core: c}
 	allRepos := collectAllRepositories(sdk.Session{}, dict, prodContainer)
 	prodContainer.repositories = allRepos
 	prodContainer.translator = sdk_i18n.NewTranslator(c.projectLocalesFS, c.ProdDAO.LocalesPath())
+	prodContainer.eventBus = c.newEventBus(dict, prodContainer, false)
 
 	c.CachedDictionary = dict
 	c.CachedDIContainer = prodContainer
@@ -443,10 +549,11 @@ func (c *RegistryCore) buildDevDictionary(session sdk.Session) (map[string]Endor
 		devDAO = sdk.NewDSLDAO(session.Username, true)
 	}
 	devTranslator := c.applyDSLOverlay(devDict, devDAO, c.projectLocalesFS)
-	devContainer := &EndorDIContainer{}
+	devContainer := &EndorDIContainer{core: c}
 	allRepos := collectAllRepositories(session, devDict, devContainer)
 	devContainer.repositories = allRepos
 	devContainer.translator = devTranslator
+	devContainer.eventBus = c.newEventBus(devDict, devContainer, true)
 	return devDict, devContainer, nil
 }
 
@@ -465,6 +572,102 @@ func collectAllRepositories(session sdk.Session, dict map[string]EndorEntityDict
 	return allRepos
 }
 
+// newEventBus creates the event bus of container with the subscriptions and the webhooks
+// declared by the handlers of dict: it is rebuilt with the container, so DSL changes apply to it too.
+// The webhooks are delivered by the outbox when there is one, and never for development
+// containers, whose sandbox writes must not reach external URLs.
+func (c *RegistryCore) newEventBus(dict map[string]EndorEntityDictionary, container *EndorDIContainer, development bool) *sdk.EventBus {
+	bus := sdk.NewEventBus(c.MicroServiceId, container, c.Logger)
+	for _, entry := range dict {
+		for _, subscription := range entry.EndorHandler.EventSubscriptions {
+			if subscription.Entity == "" {
+				subscription.Entity = entry.EndorHandler.Entity
+			}
+			if subscription.Handler == nil {
+				if subscription.Action == "" {
+					c.Logger.Warn(fmt.Sprintf("event subscription of %s skipped: neither handler nor action defined", entry.EndorHandler.Entity))
+					continue
+				}
+				subscription.Handler = invokeOnEvent(path.Join(c.Module, entry.EndorHandler.Entity, subscription.Action))
+			}
+			bus.Subscribe(subscription)
+		}
+		if development || c.Services.Outbox != nil || len(entry.EndorHandler.Webhooks) == 0 {
+			continue
+		}
+		dispatcher := c.Services.WebhookDispatcher
+		if dispatcher == nil {
+			c.Logger.Warn(fmt.Sprintf("webhooks of %s skipped: webhook dispatcher not initialized", entry.EndorHandler.Entity))
+			continue
+		}
+		for _, webhook := range entityWebhooks(entry) {
+			bus.Subscribe(dispatcher.Subscription(entry.EndorHandler.Entity, webhook, func(event sdk.EntityEvent) *sdk.RootSchema {
+				return eventSchema(container, event)
+			}))
+		}
+	}
+	return bus
+}
+
+// Webhooks returns the webhooks of the production handler of entityId, the lookup of the
+// outbox publisher of the webhook dispatcher.
+func (c *RegistryCore) Webhooks(entityId string) []sdk.Webhook {
+	dict, err := c.dictionaryMap()
+	if err != nil {
+		return nil
+	}
+	entry, ok := dict[path.Join(c.Module, entityId)]
+	if !ok {
+		return nil
+	}
+	return entityWebhooks(entry)
+}
+
+// EventSchema returns the schema of the instance of event in the production container, the
+// lookup redacting the events delivered by the outbox publisher of the webhook dispatcher.
+func (c *RegistryCore) EventSchema(event sdk.EntityEvent) *sdk.RootSchema {
+	container, err := c.Container(sdk.Session{})
+	if err != nil {
+		return nil
+	}
+	return eventSchema(container, event)
+}
+
+// eventSchema returns the schema of the repository of the category of event, or of its
+// entity; nil if there is none.
+func eventSchema(container *EndorDIContainer, event sdk.EntityEvent) *sdk.RootSchema {
+	repositories := container.GetRepositories()
+	if event.Category != "" {
+		if repository, ok := repositories[event.EntityId+"/"+event.Category]; ok {
+			return repository.GetSchema()
+		}
+	}
+	if repository, ok := repositories[event.EntityId]; ok {
+		return repository.GetSchema()
+	}
+	return nil
+}
+
+// entityWebhooks returns the webhooks of entry, identified by their position if without id.
+func entityWebhooks(entry EndorEntityDictionary) []sdk.Webhook {
+	webhooks := make([]sdk.Webhook, 0, len(entry.EndorHandler.Webhooks))
+	for i, webhook := range entry.EndorHandler.Webhooks {
+		if webhook.Id == "" {
+			webhook.Id = strconv.Itoa(i)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks
+}
+
+// invokeOnEvent returns the event handler invoking actionId with the event as payload.
+func invokeOnEvent(actionId string) sdk.EntityEventHandler {
+	return func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+		_, err := c.DIContainer.InvokeAction(c, actionId, c.Payload)
+		return err
+	}
+}
+
 // endorHandlerList returns all registered EndorHandlers; used internally for route config reload.
 func (c *RegistryCore) endorHandlerList() ([]sdk.EndorHandler, error) {
 	entities, err := c.dictionaryMap()
@@ -496,15 +699,23 @@ func (c *RegistryCore) reloadRouteConfiguration() error {
 func (c *RegistryCore) createAction(entityName string, actionName string, endorServiceAction sdk.EndorHandlerActionInterface) (*EndorHandlerActionDictionary, error) {
 	actionId := path.Join(entityName, actionName)
 	action := sdk.EntityAction{
-		ID:          actionId,
-		Entity:      entityName,
-		Description: endorServiceAction.GetOptions().Description,
+		ID:                  actionId,
+		Entity:              entityName,
+		Description:         endorServiceAction.GetOptions().Description,
+		RequiredPermissions: endorServiceAction.GetOptions().RequiredPermissions,
 	}
 	if endorServiceAction.GetOptions().InputSchema != nil {
 		if inputSchema, err := endorServiceAction.GetOptions().InputSchema.ToYAML(); err == nil {
 			action.InputSchema = inputSchema
 		}
 	}
+	if len(c.ActionMiddlewares) > 0 {
+		endorServiceAction = endorServiceAction.WithMiddlewares(c.ActionMiddlewares...)
+	}
+	// the audit trail is outermost: it also records the failures of the middlewares
+	if trail := c.Services.AuditTrail; trail != nil && !sdk.IsReadAction(actionName, endorServiceAction.GetOptions()) {
+		endorServiceAction = endorServiceAction.WithMiddlewares(trail.Middleware(endorServiceAction.GetOptions().InputSchema))
+	}
 	return &EndorHandlerActionDictionary{
 		EndorHandlerAction: endorServiceAction,
 		entityAction:       action,
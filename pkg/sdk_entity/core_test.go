@@ -22,9 +22,15 @@ package sdk_entity_test
 // base but the dictionary structure is unaffected.
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	examples_handlers "github.com/mattiabonardi/endor-sdk-go/internal/examples/handlers"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
@@ -436,3 +442,384 @@ func TestContainer_Prod_RepositoriesArePopulated(t *testing.T) {
 	assert.Contains(t, repos, "hybrid-specialized-entity/cat-1", "cat-1 repository should be registered")
 	assert.Contains(t, repos, "hybrid-specialized-entity/cat-2", "cat-2 repository should be registered")
 }
+
+// ---------------------------------------------------------------------------
+// Permissions
+// ---------------------------------------------------------------------------
+
+// TestDictionary_Prod_DSL_Permissions verifies that entity and category permissions
+// declared in the DSL are required by the generated actions and hide them from the
+// entity-action catalog when the session does not grant them.
+func TestDictionary_Prod_DSL_Permissions(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "order.yaml"), []byte(`title: "Order"
+permissions: ["order:read"]
+categories:
+  - id: internal
+    title: "Internal"
+    permissions: ["order:internal"]
+  - id: standard
+    title: "Standard"
+`), 0o644))
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{}, prodDir, "")
+
+	dict, err := core.Dictionary(sdk.Session{})
+	require.NoError(t, err)
+	require.Contains(t, dict, "sdk/order")
+	entry := dict["sdk/order"]
+	assert.Equal(t, []string{"order:read"}, entry.Entity.RequiredPermissions)
+	assert.Equal(t, []string{"order:read"}, entry.EndorHandler.Actions["list"].GetOptions().RequiredPermissions)
+	assert.ElementsMatch(t, []string{"order:internal", "order:read"}, entry.EndorHandler.Actions["internal/create"].GetOptions().RequiredPermissions)
+
+	actionIDs := func(session sdk.Session) []string {
+		actions, err := sdk_entity.NewEndorHandlerActionRepository(core).EntityActionList(session)
+		require.NoError(t, err)
+		ids := make([]string, 0, len(actions))
+		for _, action := range actions {
+			ids = append(ids, action.ID)
+		}
+		return ids
+	}
+	assert.Empty(t, actionIDs(sdk.Session{}))
+	reader := actionIDs(sdk.Session{Permissions: []string{"order:read"}})
+	assert.Contains(t, reader, "sdk/order/standard/list")
+	assert.NotContains(t, reader, "sdk/order/internal/list")
+	assert.Contains(t, actionIDs(sdk.Session{Permissions: []string{"order:*"}}), "sdk/order/internal/list")
+}
+
+// TestDictionary_Prod_DSL_Versioned verifies that a versioned DSL entity exposes the
+// read-only version field and accepts the expected version in update payloads.
+func TestDictionary_Prod_DSL_Versioned(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "contract.yaml"), []byte(`title: "Contract"
+versioned: true
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "note.yaml"), []byte(`title: "Note"
+`), 0o644))
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{}, prodDir, "")
+
+	dict, err := core.Dictionary(sdk.Session{})
+	require.NoError(t, err)
+	require.Contains(t, dict, "sdk/contract")
+	properties := *dict["sdk/contract"].EndorHandler.EntitySchema.Properties
+	require.Contains(t, properties, sdk.VersionField)
+	assert.Equal(t, sdk.SchemaTypeInteger, properties[sdk.VersionField].Type)
+	require.NotNil(t, properties[sdk.VersionField].ReadOnly)
+	assert.True(t, *properties[sdk.VersionField].ReadOnly)
+	assert.Contains(t, *dict["sdk/contract"].EndorHandler.Actions["update"].GetOptions().InputSchema.Properties, "version")
+
+	require.Contains(t, dict, "sdk/note")
+	assert.NotContains(t, *dict["sdk/note"].EndorHandler.EntitySchema.Properties, sdk.VersionField)
+}
+
+// TestDictionary_Prod_DSL_SoftDelete verifies that a soft-delete DSL entity exposes the
+// read-only deletion marks and the trash and restore actions.
+func TestDictionary_Prod_DSL_SoftDelete(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "contract.yaml"), []byte(`title: "Contract"
+softDelete: true
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "note.yaml"), []byte(`title: "Note"
+`), 0o644))
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{}, prodDir, "")
+
+	dict, err := core.Dictionary(sdk.Session{})
+	require.NoError(t, err)
+	require.Contains(t, dict, "sdk/contract")
+	properties := *dict["sdk/contract"].EndorHandler.EntitySchema.Properties
+	for _, field := range []string{sdk.DeletedAtField, sdk.DeletedByField} {
+		require.Contains(t, properties, field)
+		require.NotNil(t, properties[field].ReadOnly)
+		assert.True(t, *properties[field].ReadOnly)
+	}
+	require.NotNil(t, properties[sdk.DeletedAtField].Format)
+	assert.Equal(t, sdk.SchemaFormatDateTime, *properties[sdk.DeletedAtField].Format)
+	assert.NotContains(t, properties, sdk.VersionField)
+	assert.Contains(t, dict["sdk/contract"].EndorHandler.Actions, "trash")
+	assert.Contains(t, dict["sdk/contract"].EndorHandler.Actions, "restore")
+
+	require.Contains(t, dict, "sdk/note")
+	assert.NotContains(t, *dict["sdk/note"].EndorHandler.EntitySchema.Properties, sdk.DeletedAtField)
+	assert.NotContains(t, dict["sdk/note"].EndorHandler.Actions, "trash")
+	assert.NotContains(t, dict["sdk/note"].EndorHandler.Actions, "restore")
+}
+
+// TestDictionary_Prod_DSL_History verifies that a DSL entity with history exposes the
+// history, version and revert actions.
+func TestDictionary_Prod_DSL_History(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "contract.yaml"), []byte(`title: "Contract"
+history: true
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "note.yaml"), []byte(`title: "Note"
+`), 0o644))
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{}, prodDir, "")
+
+	dict, err := core.Dictionary(sdk.Session{})
+	require.NoError(t, err)
+	require.Contains(t, dict, "sdk/contract")
+	for _, action := range []string{"history", "version", "revert"} {
+		assert.Contains(t, dict["sdk/contract"].EndorHandler.Actions, action)
+		assert.NotContains(t, dict["sdk/note"].EndorHandler.Actions, action)
+	}
+}
+
+// TestContainer_ReferenceIndex verifies that the container indexes the delete policies of
+// the references declared by the DSL entities.
+func TestContainer_ReferenceIndex(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "customer.yaml"), []byte(`title: "Customer"
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "invoice.yaml"), []byte(`title: "Invoice"
+schema:
+  type: object
+  properties:
+    customer:
+      type: string
+      x-ui:
+        entity: customer
+        onDelete: cascade
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "payment.yaml"), []byte(`title: "Payment"
+schema:
+  type: object
+  properties:
+    customer:
+      type: string
+      x-ui:
+        entity: customer
+        onDelete: cascde
+`), 0o644))
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{}, prodDir, "")
+
+	container, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.EntityReference{
+		{Entity: "invoice", Field: "customer", Policy: sdk.ReferenceDeleteCascade},
+	}, sdk.ReferenceIndexOf(container).ReferencesTo("customer"))
+	assert.NotContains(t, container.GetRepositories(), "payment", "entities with unknown onDelete policies are rejected")
+}
+
+// TestContainer_InvokeAction verifies that the DI container resolves and runs another
+// action in-process with the caller session.
+func TestContainer_InvokeAction(t *testing.T) {
+	handlers := []sdk.EndorHandlerInterface{
+		examples_handlers.NewBaseEntityHandler(),
+	}
+	core := newTestRegistryCore(t, handlers, "", "")
+	container, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+	caller := &sdk.EndorContext[sdk.NoPayload]{Session: sdk.Session{Locale: "en"}, DIContainer: container}
+
+	response, err := sdk.InvokeAction[any](caller, "sdk/base-entity/action1", map[string]any{"name": "mario", "age": 30})
+	require.NoError(t, err)
+	assert.Len(t, response.Messages, 1)
+
+	_, err = sdk.InvokeAction[any](caller, "sdk/base-entity/missing", nil)
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, 404, endorError.StatusCode)
+}
+
+// TestContainer_AuthorizeAction verifies that the DI container checks a session against
+// the permissions of another action, as used by the references action and by expand.
+func TestContainer_AuthorizeAction(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "order.yaml"), []byte(`title: "Order"
+permissions: ["order:read"]
+`), 0o644))
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{}, prodDir, "")
+	container, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+
+	assert.NoError(t, container.AuthorizeAction(sdk.Session{Permissions: []string{"order:read"}}, "sdk/order/list"))
+
+	var endorError *sdk.EndorError
+	require.ErrorAs(t, container.AuthorizeAction(sdk.Session{}, "sdk/order/list"), &endorError)
+	assert.Equal(t, 403, endorError.StatusCode)
+	require.ErrorAs(t, container.AuthorizeAction(sdk.Session{}, "sdk/order/missing"), &endorError)
+	assert.Equal(t, 404, endorError.StatusCode)
+}
+
+// TestContainer_SystemLogsRequireReadPermissions verifies that the audit trail and the
+// delivery log of the webhooks are read only by the sessions granted their permission.
+func TestContainer_SystemLogsRequireReadPermissions(t *testing.T) {
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{sdk_entity.NewAuditHandler(), sdk_entity.NewWebhookDeliveryHandler()}, "", "")
+	container, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+
+	for entity, permission := range map[string]string{"audit": sdk.AuditReadPermission, "webhook-delivery": sdk.WebhookReadPermission} {
+		for _, action := range []string{"schema", "list", "instance"} {
+			actionId := coreTestModule + "/" + entity + "/" + action
+			caller := &sdk.EndorContext[sdk.NoPayload]{Session: sdk.Session{Locale: "en", Permissions: []string{"order:*"}}, DIContainer: container}
+			_, err := sdk.InvokeAction[any](caller, actionId, map[string]any{"id": "x"})
+			var endorError *sdk.EndorError
+			require.ErrorAs(t, err, &endorError, actionId)
+			assert.Equal(t, 403, endorError.StatusCode, actionId)
+			assert.NoError(t, container.AuthorizeAction(sdk.Session{Permissions: []string{permission}}, actionId), actionId)
+		}
+		dict, err := core.Dictionary(sdk.Session{})
+		require.NoError(t, err)
+		options := dict[coreTestModule+"/"+entity].EndorHandler.Actions["list"].GetOptions()
+		assert.True(t, options.ReadOnly, entity)
+	}
+}
+
+// TestContainer_EventSubscriptions verifies that the event bus of the container delivers
+// the events to the subscriptions declared by handlers and DSL entities.
+func TestContainer_EventSubscriptions(t *testing.T) {
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "invoice.yaml"), []byte(`title: "Invoice"
+events:
+  - entity: order
+    events: [deleted]
+    action: list
+    async: true
+    maxAttempts: 5
+    retryDelay: 1s
+`), 0o644))
+
+	var received []string
+	listener := sdk_entity.NewEndorBaseHandler[*examples_handlers.BaseSpecializedEntityModel]("order-listener", "Order listener").
+		WithActions(map[string]sdk.EndorHandlerActionInterface{
+			"on-order": sdk.NewAction(func(c *sdk.EndorContext[sdk.EntityEvent]) (*sdk.Response[any], error) {
+				received = append(received, "action:"+string(c.Payload.Type)+":"+c.Payload.InstanceId)
+				return sdk.NewResponseBuilder[any]().Build(), nil
+			}, "on order"),
+		}).
+		WithEventSubscriptions(
+			sdk.EventSubscription{Entity: "order", Events: []sdk.EntityEventType{sdk.EntityEventCreated}, Action: "on-order"},
+			sdk.EventSubscription{Handler: func(c *sdk.EndorContext[sdk.EntityEvent]) error {
+				received = append(received, "own:"+c.Payload.EntityId)
+				return nil
+			}},
+		)
+	core := newTestRegistryCore(t, []sdk.EndorHandlerInterface{listener}, prodDir, "")
+
+	dict, err := core.Dictionary(sdk.Session{})
+	require.NoError(t, err)
+	require.Len(t, dict["sdk/invoice"].EndorHandler.EventSubscriptions, 1)
+	subscription := dict["sdk/invoice"].EndorHandler.EventSubscriptions[0]
+	assert.Equal(t, "order", subscription.Entity)
+	assert.Equal(t, []sdk.EntityEventType{sdk.EntityEventDeleted}, subscription.Events)
+	assert.True(t, subscription.Async)
+	assert.Equal(t, 5, subscription.MaxAttempts)
+	assert.Equal(t, time.Second, subscription.RetryDelay)
+
+	container, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+	bus := container.GetEventBus()
+	assert.True(t, bus.HasSubscribers("order"))
+	assert.True(t, bus.HasSubscribers("order-listener"))
+
+	session := sdk.Session{UserId: "user-1", Locale: "en"}
+	require.NoError(t, bus.Publish(context.Background(), sdk.NewEntityEvent(sdk.EntityEventCreated, "order", "", "order-1", nil, map[string]any{}, session)))
+	require.NoError(t, bus.Publish(context.Background(), sdk.NewEntityEvent(sdk.EntityEventUpdated, "order-listener", "", "listener-1", map[string]any{}, map[string]any{}, session)))
+	assert.Equal(t, []string{"action:created:order-1", "own:order-listener"}, received)
+}
+
+// TestContainer_DSLWebhooks verifies that the webhooks of the DSL entities are delivered
+// by the event bus of the container.
+func TestContainer_DSLWebhooks(t *testing.T) {
+	received := make(chan sdk.EntityEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event sdk.EntityEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+	}))
+	defer server.Close()
+	deliveries := sdk.NewInMemoryWebhookDeliveryRepository()
+
+	prodDir := t.TempDir()
+	entitiesDir := filepath.Join(prodDir, "entities", coreTestModule)
+	require.NoError(t, os.MkdirAll(entitiesDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(entitiesDir, "invoice.yaml"), []byte(`title: "Invoice"
+schema:
+  type: object
+  properties:
+    apiKey:
+      type: string
+      writeOnly: true
+webhooks:
+  - url: `+server.URL+`
+    events: [updated]
+    filter:
+      status: paid
+    maxAttempts: 2
+`), 0o644))
+	core := newTestRegistryCore(t, nil, prodDir, "")
+	core.Services.WebhookDispatcher = sdk.NewWebhookDispatcher(deliveries)
+
+	dict, err := core.Dictionary(sdk.Session{})
+	require.NoError(t, err)
+	require.Len(t, dict["sdk/invoice"].EndorHandler.Webhooks, 1)
+	webhook := dict["sdk/invoice"].EndorHandler.Webhooks[0]
+	assert.Equal(t, []sdk.EntityEventType{sdk.EntityEventUpdated}, webhook.Events)
+	assert.Equal(t, map[string]any{"status": "paid"}, webhook.Filter)
+
+	container, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+	bus := container.GetEventBus()
+	require.True(t, bus.HasSubscribers("invoice"))
+	paid := sdk.NewEntityEvent(sdk.EntityEventUpdated, "invoice", "", "invoice-1", map[string]any{"status": "draft"}, map[string]any{"status": "paid", "apiKey": "k-1"}, sdk.Session{})
+	require.NoError(t, bus.Publish(context.Background(), paid))
+	require.NoError(t, bus.Publish(context.Background(), sdk.NewEntityEvent(sdk.EntityEventUpdated, "invoice", "", "invoice-2", nil, map[string]any{"status": "draft"}, sdk.Session{})))
+	bus.Wait()
+
+	delivered := <-received
+	assert.Equal(t, paid.Id, delivered.Id)
+	assert.Equal(t, sdk.AuditRedacted, delivered.After["apiKey"], "the writeOnly fields are redacted")
+	assert.Empty(t, received)
+	list, err := deliveries.List(context.Background(), sdk.ReadWebhookDeliveriesDTO{EntityId: "invoice", WebhookId: "0"})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, sdk.WebhookDeliveryStatusDelivered, list[0].Status)
+}
+
+// TestContainer_WebhooksSkippedForDevelopmentAndOutbox verifies that the webhooks are not
+// subscribed by development containers, and are left to the outbox when there is one.
+func TestContainer_WebhooksSkippedForDevelopmentAndOutbox(t *testing.T) {
+	webhooks := []byte(`title: "Invoice"
+webhooks:
+  - url: http://127.0.0.1:0/hook
+`)
+	prodDir, devBase := t.TempDir(), t.TempDir()
+	for _, dir := range []string{filepath.Join(prodDir, "entities", coreTestModule), filepath.Join(devBase, "user1", "entities", coreTestModule)} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "invoice.yaml"), webhooks, 0o644))
+	}
+	core := newTestRegistryCore(t, nil, prodDir, devBase)
+	core.Services.WebhookDispatcher = sdk.NewWebhookDispatcher(sdk.NewInMemoryWebhookDeliveryRepository())
+
+	devContainer, err := core.Container(sdk.Session{Development: true, Username: "user1"})
+	require.NoError(t, err)
+	assert.False(t, devContainer.GetEventBus().HasSubscribers("invoice"))
+	prodContainer, err := core.Container(sdk.Session{})
+	require.NoError(t, err)
+	assert.True(t, prodContainer.GetEventBus().HasSubscribers("invoice"))
+
+	outboxCore := newTestRegistryCore(t, nil, prodDir, devBase)
+	outboxCore.Services.WebhookDispatcher = core.Services.WebhookDispatcher
+	outboxCore.Services.Outbox = sdk.NewOutbox(sdk.NewInMemoryOutboxRepository(), nil, sdk.OutboxOptions{}, nil)
+	prodContainer, err = outboxCore.Container(sdk.Session{})
+	require.NoError(t, err)
+	assert.False(t, prodContainer.GetEventBus().HasSubscribers("invoice"))
+	require.Len(t, outboxCore.Webhooks("invoice"), 1)
+	assert.Equal(t, "0", outboxCore.Webhooks("invoice")[0].Id)
+	assert.Empty(t, outboxCore.Webhooks("order"))
+	assert.NotNil(t, outboxCore.EventSchema(sdk.EntityEvent{EntityId: "invoice"}), "the outbox deliveries are redacted with the schema of the entity")
+}
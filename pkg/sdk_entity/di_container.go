@@ -1,6 +1,9 @@
 package sdk_entity
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
 )
@@ -11,12 +14,91 @@ import (
 type EndorDIContainer struct {
 	repositories map[string]sdk.EndorRepositoryInterface
 	translator   *sdk_i18n.Translator
+	eventBus     *sdk.EventBus
+	core         *RegistryCore
+	// referenceIndex is built on first use, once the repositories are set
+	referenceIndex     sdk.ReferenceIndex
+	referenceIndexOnce sync.Once
 }
 
 func (c *EndorDIContainer) GetRepositories() map[string]sdk.EndorRepositoryInterface {
 	return c.repositories
 }
 
+func (c *EndorDIContainer) GetModule() string {
+	if c.core == nil {
+		return ""
+	}
+	return c.core.Module
+}
+
 func (c *EndorDIContainer) GetTranslator() *sdk_i18n.Translator {
 	return c.translator
 }
+
+func (c *EndorDIContainer) GetEventBus() *sdk.EventBus {
+	return c.eventBus
+}
+
+func (c *EndorDIContainer) GetJobManager() *sdk.JobManager {
+	return c.services().JobManager
+}
+
+func (c *EndorDIContainer) GetOutbox() *sdk.Outbox {
+	return c.services().Outbox
+}
+
+func (c *EndorDIContainer) GetWebhookDispatcher() *sdk.WebhookDispatcher {
+	return c.services().WebhookDispatcher
+}
+
+func (c *EndorDIContainer) GetAuditTrail() *sdk.AuditTrail {
+	return c.services().AuditTrail
+}
+
+func (c *EndorDIContainer) GetReferenceResolver() sdk.ReferenceResolver {
+	return c.services().ReferenceResolver
+}
+
+// services returns the services of the registry of the container, none if unbound.
+func (c *EndorDIContainer) services() Services {
+	if c.core == nil {
+		return Services{}
+	}
+	return c.core.Services
+}
+
+// GetReferenceIndex returns the index of the references between the entities of the
+// container, used to enforce their delete policies.
+func (c *EndorDIContainer) GetReferenceIndex() sdk.ReferenceIndex {
+	c.referenceIndexOnce.Do(func() {
+		c.referenceIndex = sdk.NewReferenceIndex(c.GetModule(), c.repositories)
+	})
+	return c.referenceIndex
+}
+
+// InvokeAction resolves the action through the registry for the session of ctx (so the
+// development overlay is honoured) and invokes it in-process.
+func (c *EndorDIContainer) InvokeAction(ctx sdk.EndorContextInterface, actionId string, payload any) (any, error) {
+	if c.core == nil {
+		return nil, sdk.NewInternalServerError(fmt.Errorf("container is not bound to a registry"))
+	}
+	action, err := NewEndorHandlerActionRepository(c.core).DictionaryActionInstance(ctx.GetSession(), sdk.ReadInstanceDTO{Id: actionId})
+	if err != nil {
+		return nil, err
+	}
+	return action.EndorHandlerAction.InvokeWithPayload(ctx, actionId, payload, action.Container)
+}
+
+// AuthorizeAction resolves the action through the registry for session, like InvokeAction,
+// and checks the session against its required permissions.
+func (c *EndorDIContainer) AuthorizeAction(session sdk.Session, actionId string) error {
+	if c.core == nil {
+		return sdk.NewInternalServerError(fmt.Errorf("container is not bound to a registry"))
+	}
+	action, err := NewEndorHandlerActionRepository(c.core).DictionaryActionInstance(session, sdk.ReadInstanceDTO{Id: actionId})
+	if err != nil {
+		return err
+	}
+	return session.AuthorizeAction(actionId, action.EndorHandlerAction.GetOptions().RequiredPermissions)
+}
@@ -5,12 +5,15 @@ import (
 )
 
 type EndorBaseHandler[T sdk.EntityInstanceInterface] struct {
-	entity            string
-	entityTitle       string
-	entityDescription string
-	priority          *int
-	actions           map[string]sdk.EndorHandlerActionInterface
-	repositoryFactory sdk.RepositoryFactory
+	entity             string
+	entityTitle        string
+	entityDescription  string
+	priority           *int
+	actions            map[string]sdk.EndorHandlerActionInterface
+	repositoryFactory  sdk.RepositoryFactory
+	middlewares        []sdk.EndorActionMiddleware
+	schedules          []sdk.Schedule
+	eventSubscriptions []sdk.EventSubscription
 }
 
 func (h EndorBaseHandler[T]) GetEntity() string {
@@ -54,6 +57,27 @@ func (h EndorBaseHandler[T]) WithPriority(
 	return h
 }
 
+func (h EndorBaseHandler[T]) WithMiddlewares(
+	middlewares ...sdk.EndorActionMiddleware,
+) sdk.EndorBaseHandlerInterface {
+	h.middlewares = append(append([]sdk.EndorActionMiddleware{}, h.middlewares...), middlewares...)
+	return h
+}
+
+func (h EndorBaseHandler[T]) WithSchedules(
+	schedules ...sdk.Schedule,
+) sdk.EndorBaseHandlerInterface {
+	h.schedules = append(append([]sdk.Schedule{}, h.schedules...), schedules...)
+	return h
+}
+
+func (h EndorBaseHandler[T]) WithEventSubscriptions(
+	subscriptions ...sdk.EventSubscription,
+) sdk.EndorBaseHandlerInterface {
+	h.eventSubscriptions = append(append([]sdk.EventSubscription{}, h.eventSubscriptions...), subscriptions...)
+	return h
+}
+
 func (h EndorBaseHandler[T]) WithActions(
 	actions map[string]sdk.EndorHandlerActionInterface,
 ) sdk.EndorBaseHandlerInterface {
@@ -76,9 +100,11 @@ func (h EndorBaseHandler[T]) ToEndorHandler() sdk.EndorHandler {
 		EntityTitle:         h.entityTitle,
 		EntityDescription:   h.entityDescription,
 		Priority:            h.priority,
-		Actions:             h.actions,
+		Actions:             sdk.WithActionMiddlewares(h.actions, h.middlewares...),
 		EntitySchema:        *rootSchema,
 		RepositoryFactories: map[string]sdk.RepositoryFactory{h.entity: h.repositoryFactory},
+		Schedules:           h.schedules,
+		EventSubscriptions:  h.eventSubscriptions,
 	}
 }
 
@@ -10,6 +10,8 @@ type EndorBaseSpecializedHandlerCategory[T sdk.EntityInstanceSpecializedInterfac
 	Description       string
 	Actions           map[string]sdk.EndorHandlerActionInterface
 	repositoryFactory sdk.RepositoryFactory
+	// RequiredPermissions are added to every action of the category.
+	RequiredPermissions []string
 }
 
 func (h *EndorBaseSpecializedHandlerCategory[T]) GetID() string {
@@ -37,6 +39,15 @@ func (h *EndorBaseSpecializedHandlerCategory[T]) GetRepository() sdk.RepositoryF
 	return h.repositoryFactory
 }
 
+func (h *EndorBaseSpecializedHandlerCategory[T]) GetRequiredPermissions() []string {
+	return h.RequiredPermissions
+}
+
+func (h *EndorBaseSpecializedHandlerCategory[T]) WithRequiredPermissions(permissions ...string) sdk.EndorBaseSpecializedHandlerCategoryInterface {
+	h.RequiredPermissions = append(h.RequiredPermissions, permissions...)
+	return h
+}
+
 func (h *EndorBaseSpecializedHandlerCategory[T]) WithExtendedDescription(description string) sdk.EndorBaseSpecializedHandlerCategoryInterface {
 	h.Description = description
 	return h
@@ -69,6 +80,9 @@ type EndorBaseSpecializedHandler[T sdk.EntityInstanceSpecializedInterface] struc
 	actions             map[string]sdk.EndorHandlerActionInterface
 	categories          map[string]sdk.EndorBaseSpecializedHandlerCategoryInterface
 	repositoryFactories map[string]sdk.RepositoryFactory
+	middlewares         []sdk.EndorActionMiddleware
+	schedules           []sdk.Schedule
+	eventSubscriptions  []sdk.EventSubscription
 }
 
 func (h EndorBaseSpecializedHandler[T]) GetEntity() string {
@@ -122,6 +136,27 @@ func (h EndorBaseSpecializedHandler[T]) WithPriority(
 	return h
 }
 
+func (h EndorBaseSpecializedHandler[T]) WithMiddlewares(
+	middlewares ...sdk.EndorActionMiddleware,
+) sdk.EndorBaseSpecializedHandlerInterface {
+	h.middlewares = append(append([]sdk.EndorActionMiddleware{}, h.middlewares...), middlewares...)
+	return h
+}
+
+func (h EndorBaseSpecializedHandler[T]) WithSchedules(
+	schedules ...sdk.Schedule,
+) sdk.EndorBaseSpecializedHandlerInterface {
+	h.schedules = append(append([]sdk.Schedule{}, h.schedules...), schedules...)
+	return h
+}
+
+func (h EndorBaseSpecializedHandler[T]) WithEventSubscriptions(
+	subscriptions ...sdk.EventSubscription,
+) sdk.EndorBaseSpecializedHandlerInterface {
+	h.eventSubscriptions = append(append([]sdk.EventSubscription{}, h.eventSubscriptions...), subscriptions...)
+	return h
+}
+
 func (h EndorBaseSpecializedHandler[T]) WithActions(
 	actions map[string]sdk.EndorHandlerActionInterface,
 ) sdk.EndorBaseSpecializedHandlerInterface {
@@ -143,10 +178,11 @@ func (h EndorBaseSpecializedHandler[T]) GetCategories() []sdk.Category {
 	categories := []sdk.Category{}
 	for _, category := range h.categories {
 		categories = append(categories, sdk.Category{
-			ID:          category.GetID(),
-			Title:       category.GetTitle(),
-			Description: category.GetDescription(),
-			Schema:      category.GetSchema(),
+			ID:                  category.GetID(),
+			Title:               category.GetTitle(),
+			Description:         category.GetDescription(),
+			Schema:              category.GetSchema(),
+			RequiredPermissions: category.GetRequiredPermissions(),
 		})
 	}
 	return categories
@@ -171,7 +207,7 @@ func (h EndorBaseSpecializedHandler[T]) ToEndorHandler() sdk.EndorHandler {
 		for _, category := range h.categories {
 			// iterate over category actions
 			if len(category.GetActions()) > 0 {
-				for actionName, action := range category.GetActions() {
+				for actionName, action := range sdk.WithActionRequiredPermissions(category.GetActions(), category.GetRequiredPermissions()...) {
 					actions[category.GetID()+"/"+actionName] = action
 					h.repositoryFactories[h.Entity+"/"+category.GetID()] = category.GetRepository()
 				}
@@ -187,8 +223,10 @@ func (h EndorBaseSpecializedHandler[T]) ToEndorHandler() sdk.EndorHandler {
 		EntityTitle:         h.EntityTitle,
 		EntityDescription:   h.EntityDescription,
 		Priority:            h.Priority,
-		Actions:             actions,
+		Actions:             sdk.WithActionMiddlewares(actions, h.middlewares...),
 		EntitySchema:        *rootSchema,
 		RepositoryFactories: h.repositoryFactories,
+		Schedules:           h.schedules,
+		EventSubscriptions:  h.eventSubscriptions,
 	}
 }
@@ -60,7 +60,9 @@ func (r *EndorHandlerActionRepository) EntityActionList(session sdk.Session) ([]
 	}
 	actionList := make([]sdk.EntityAction, 0, len(actions))
 	for _, action := range actions {
-		actionList = append(actionList, action.entityAction)
+		if session.HasPermissions(action.entityAction.RequiredPermissions) {
+			actionList = append(actionList, action.entityAction)
+		}
 	}
 	return actionList, nil
 }
@@ -42,7 +42,9 @@ func (h *EndorEntityRepository) List() ([]sdk.Entity, error) {
 	aggregatonID := path.Join(core.Module, "aggregation")
 	entityList := make([]sdk.Entity, 0, len(dict))
 	for _, v := range dict {
-		entityList = append(entityList, v.Entity)
+		if entity, ok := visibleEntity(h.session, v.Entity); ok {
+			entityList = append(entityList, entity)
+		}
 	}
 	// excluded core entities
 	filtered := make([]sdk.Entity, 0, len(dict))
@@ -61,7 +63,9 @@ func (h *EndorEntityRepository) Instance(dto sdk.ReadInstanceDTO) (*sdk.Entity,
 		return nil, err
 	}
 	if entry != nil {
-		return &entry.Entity, nil
+		if entity, ok := visibleEntity(h.session, entry.Entity); ok {
+			return &entity, nil
+		}
 	}
 	return nil, sdk.NewNotFoundError(fmt.Errorf("entity %s not found", dto.Id)).WithTranslation("sdk.entity.messages.not_found", map[string]any{"id": dto.Id})
 }
@@ -86,6 +90,27 @@ func (h *EndorEntityRepository) FindReferences(_ context.Context, ids sdk.ReadIn
 	return result, nil
 }
 
+// FindDocuments returns the visible entities with the given ids as JSON documents.
+func (h *EndorEntityRepository) FindDocuments(_ context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	result := make(map[string]map[string]interface{}, len(dto.Ids))
+	for _, id := range dto.Ids {
+		entity, err := h.Instance(sdk.ReadInstanceDTO{Id: id})
+		if err != nil {
+			continue
+		}
+		data, err := json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		result[id] = m
+	}
+	return result, nil
+}
+
 func (h *EndorEntityRepository) RawList(_ context.Context, _ sdk.ReadDTO) ([]map[string]interface{}, error) {
 	core := GetRegistryCore()
 	dict, err := core.Dictionary(h.session)
@@ -94,7 +119,11 @@ func (h *EndorEntityRepository) RawList(_ context.Context, _ sdk.ReadDTO) ([]map
 	}
 	result := make([]map[string]interface{}, 0, len(dict))
 	for _, entry := range dict {
-		data, err := json.Marshal(entry.Entity)
+		entity, ok := visibleEntity(h.session, entry.Entity)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(entity)
 		if err != nil {
 			return nil, err
 		}
@@ -107,6 +136,25 @@ func (h *EndorEntityRepository) RawList(_ context.Context, _ sdk.ReadDTO) ([]map
 	return result, nil
 }
 
+// visibleEntity hides the entity, or the categories of it, whose permissions are not granted
+// by the session.
+func visibleEntity(session sdk.Session, entity sdk.Entity) (sdk.Entity, bool) {
+	if !session.HasPermissions(entity.RequiredPermissions) {
+		return sdk.Entity{}, false
+	}
+	if len(entity.Categories) == 0 {
+		return entity, true
+	}
+	categories := make([]sdk.Category, 0, len(entity.Categories))
+	for _, category := range entity.Categories {
+		if session.HasPermissions(category.RequiredPermissions) {
+			categories = append(categories, category)
+		}
+	}
+	entity.Categories = categories
+	return entity, true
+}
+
 // EndorHandlerList returns all registered EndorHandlers.
 // Used by the server to register routes and swagger configuration.
 func (h *EndorEntityRepository) EndorHandlerList() ([]sdk.EndorHandler, error) {
@@ -2,16 +2,23 @@ package sdk_entity
 
 import (
 	"context"
+	"maps"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 )
 
 type EndorHybridHandler[T sdk.EntityInstanceInterface] struct {
-	Entity            string
-	EntityTitle       string
-	EntityDescription string
-	Priority          *int
-	methodsFn         func(getSchema func() sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface
+	Entity             string
+	EntityTitle        string
+	EntityDescription  string
+	Priority           *int
+	methodsFn          func(getSchema func() sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface
+	middlewares        []sdk.EndorActionMiddleware
+	schedules          []sdk.Schedule
+	eventSubscriptions []sdk.EventSubscription
+	versioned          bool
+	softDelete         bool
+	history            bool
 }
 
 func (h EndorHybridHandler[T]) GetEntity() string {
@@ -55,6 +62,42 @@ func (h EndorHybridHandler[T]) WithPriority(
 	return h
 }
 
+func (h EndorHybridHandler[T]) WithMiddlewares(
+	middlewares ...sdk.EndorActionMiddleware,
+) sdk.EndorHybridHandlerInterface {
+	h.middlewares = append(append([]sdk.EndorActionMiddleware{}, h.middlewares...), middlewares...)
+	return h
+}
+
+func (h EndorHybridHandler[T]) WithSchedules(
+	schedules ...sdk.Schedule,
+) sdk.EndorHybridHandlerInterface {
+	h.schedules = append(append([]sdk.Schedule{}, h.schedules...), schedules...)
+	return h
+}
+
+func (h EndorHybridHandler[T]) WithEventSubscriptions(
+	subscriptions ...sdk.EventSubscription,
+) sdk.EndorHybridHandlerInterface {
+	h.eventSubscriptions = append(append([]sdk.EventSubscription{}, h.eventSubscriptions...), subscriptions...)
+	return h
+}
+
+func (h EndorHybridHandler[T]) WithVersioning() sdk.EndorHybridHandlerInterface {
+	h.versioned = true
+	return h
+}
+
+func (h EndorHybridHandler[T]) WithSoftDelete() sdk.EndorHybridHandlerInterface {
+	h.softDelete = true
+	return h
+}
+
+func (h EndorHybridHandler[T]) WithHistory() sdk.EndorHybridHandlerInterface {
+	h.history = true
+	return h
+}
+
 // define methods. The params getSchema allow to inject the dynamic schema
 func (h EndorHybridHandler[T]) WithActions(
 	fn func(getSchema func() sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface,
@@ -68,11 +111,18 @@ func (h EndorHybridHandler[T]) ToEndorHandler(metadataSchema sdk.RootSchema) sdk
 	var methods = make(map[string]sdk.EndorHandlerActionInterface)
 
 	// schema
+	metadataSchema = withManagedFieldsSchema(metadataSchema, h.versioned, h.softDelete)
 	rootSchemWithMetadata := getRootSchemaWithMetadata[T](metadataSchema)
 	getSchemaCallback := func() sdk.RootSchema { return *rootSchemWithMetadata }
 
 	// add default CRUD methods
 	methods = getDefaultActions[T](h.Entity, *rootSchemWithMetadata)
+	if h.softDelete {
+		maps.Copy(methods, getSoftDeleteActions[T](h.Entity, *rootSchemWithMetadata))
+	}
+	if h.history {
+		maps.Copy(methods, getHistoryActions[T](h.Entity, *rootSchemWithMetadata))
+	}
 	// add custom methods
 	if h.methodsFn != nil {
 		for methodName, method := range h.methodsFn(getSchemaCallback) {
@@ -85,6 +135,9 @@ func (h EndorHybridHandler[T]) ToEndorHandler(metadataSchema sdk.RootSchema) sdk
 		autogenerateID := true
 		return NewEntityInstanceRepository[T](h.Entity, *rootSchemWithMetadata, sdk.EntityInstanceRepositoryOptions{
 			AutoGenerateID: &autogenerateID,
+			Versioned:      h.versioned,
+			SoftDelete:     h.softDelete,
+			History:        h.history,
 		}, session, container)
 	}
 
@@ -93,10 +146,40 @@ func (h EndorHybridHandler[T]) ToEndorHandler(metadataSchema sdk.RootSchema) sdk
 		EntityTitle:         h.EntityTitle,
 		EntityDescription:   h.EntityDescription,
 		Priority:            h.Priority,
-		Actions:             methods,
+		Actions:             sdk.WithActionMiddlewares(methods, h.middlewares...),
 		EntitySchema:        *rootSchemWithMetadata,
 		RepositoryFactories: map[string]sdk.RepositoryFactory{h.Entity: repositoryFactory},
+		Schedules:           h.schedules,
+		EventSubscriptions:  h.eventSubscriptions,
+	}
+}
+
+// withManagedFieldsSchema adds to the metadata schema the read-only fields maintained by the
+// repository: the version of versioned entities and the deletion marks of soft-delete ones.
+func withManagedFieldsSchema(metadataSchema sdk.RootSchema, versioned bool, softDelete bool) sdk.RootSchema {
+	if !versioned && !softDelete {
+		return metadataSchema
+	}
+	schema := *metadataSchema.Clone()
+	if schema.Properties == nil {
+		schema.Properties = &map[string]sdk.Schema{}
+	}
+	readOnly := true
+	field := func(name string, schemaType sdk.SchemaTypeName, title string) {
+		(*schema.Properties)[name] = sdk.Schema{Type: schemaType, Title: &title, ReadOnly: &readOnly}
+	}
+	if versioned {
+		field(sdk.VersionField, sdk.SchemaTypeInteger, "${t.sdk.entity.fields.version}")
+	}
+	if softDelete {
+		field(sdk.DeletedAtField, sdk.SchemaTypeString, "${t.sdk.entity.fields.deleted_at}")
+		dateTime := sdk.SchemaFormatDateTime
+		deletedAt := (*schema.Properties)[sdk.DeletedAtField]
+		deletedAt.Format = &dateTime
+		(*schema.Properties)[sdk.DeletedAtField] = deletedAt
+		field(sdk.DeletedByField, sdk.SchemaTypeString, "${t.sdk.entity.fields.deleted_by}")
 	}
+	return schema
 }
 
 func getRootSchemaWithMetadata[T sdk.EntityInstanceInterface](metadataSchema sdk.RootSchema) *sdk.RootSchema {
@@ -159,7 +242,10 @@ func getDefaultActions[T sdk.EntityInstanceInterface](entity string, schema sdk.
 							"id": {
 								Type: sdk.SchemaTypeString,
 							},
-							"data": schema.Schema,
+							"version": {
+								Type: sdk.SchemaTypeInteger,
+							},
+							"data": partialSchema(schema),
 						},
 					},
 				},
@@ -177,6 +263,58 @@ func getDefaultActions[T sdk.EntityInstanceInterface](entity string, schema sdk.
 	}
 }
 
+// getSoftDeleteActions returns the default actions of soft-delete entities: trash lists the
+// deleted instances and restore brings one back.
+func getSoftDeleteActions[T sdk.EntityInstanceInterface](entity string, schema sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface {
+	return map[string]sdk.EndorHandlerActionInterface{
+		"trash": sdk.NewAction(
+			func(c *sdk.EndorContext[sdk.ReadDTO]) (*sdk.Response[[]sdk.EntityInstance[T]], error) {
+				return defaultTrash[T](c, schema, entity)
+			},
+			"${t.sdk.handler.actions.trash} "+entity,
+		),
+		"restore": sdk.NewAction(
+			func(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk.Response[sdk.EntityInstance[T]], error) {
+				return defaultRestore[T](c, schema, entity)
+			},
+			"${t.sdk.handler.actions.restore} "+entity,
+		),
+	}
+}
+
+// getHistoryActions returns the default actions of entities with history: history lists the
+// versions of an instance, version returns one of them and revert writes it back.
+func getHistoryActions[T sdk.EntityInstanceInterface](entity string, schema sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface {
+	return map[string]sdk.EndorHandlerActionInterface{
+		"history": sdk.NewAction(
+			func(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk.Response[[]sdk.HistoryEntry], error) {
+				return defaultHistory[T](c, entity)
+			},
+			"${t.sdk.handler.actions.history} "+entity,
+		),
+		"version": sdk.NewAction(
+			func(c *sdk.EndorContext[sdk.ReadHistoryVersionDTO]) (*sdk.Response[*sdk.HistoryEntry], error) {
+				return defaultHistoryVersion[T](c, entity)
+			},
+			"${t.sdk.handler.actions.version} "+entity,
+		),
+		"revert": sdk.NewAction(
+			func(c *sdk.EndorContext[sdk.ReadHistoryVersionDTO]) (*sdk.Response[sdk.EntityInstance[T]], error) {
+				return defaultRevert[T](c, schema, entity)
+			},
+			"${t.sdk.handler.actions.revert} "+entity,
+		),
+	}
+}
+
+// partialSchema returns the entity schema used to validate update payloads:
+// updates only carry the fields being changed, so top-level required fields are dropped.
+func partialSchema(schema sdk.RootSchema) sdk.Schema {
+	partial := schema.Clone().Schema
+	partial.Required = nil
+	return partial
+}
+
 func defaultSchema[T sdk.EntityInstanceInterface](_ *sdk.EndorContext[sdk.NoPayload], schema sdk.RootSchema) (*sdk.Response[any], error) {
 	return sdk.NewResponseBuilder[any]().AddSchema(&schema).Build(), nil
 }
@@ -186,11 +324,16 @@ func defaultInstance[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.Read
 	if err != nil {
 		return nil, err
 	}
-	instance, references, err := repo.InstanceWithReferences(context.TODO(), c.Payload)
+	instance, references, err := repo.InstanceWithReferences(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := sdk.ExpandReferences(c.Context(), c.DIContainer, &schema, instance, c.Payload.ExpandOptions())
 	if err != nil {
 		return nil, err
 	}
-	return sdk.NewResponseBuilder[*sdk.EntityInstance[T]]().AddData(&instance).AddSchema(&schema).AddReferences(references).Build(), nil
+	response := sdk.NewResponseBuilder[*sdk.EntityInstance[T]]().AddData(&instance).AddSchema(&schema).AddReferences(references).AddExpanded(expanded)
+	return withVersionETag(response, instance.Metadata).Build(), nil
 }
 
 func defaultList[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadDTO], schema sdk.RootSchema, entity string) (*sdk.Response[[]sdk.EntityInstance[T]], error) {
@@ -198,11 +341,23 @@ func defaultList[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadDTO]
 	if err != nil {
 		return nil, err
 	}
-	list, references, err := repo.ListWithReferences(context.TODO(), c.Payload)
+	if c.StreamRequested() {
+		dto := c.Payload
+		return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddSchema(&schema).AddStream(func(ctx context.Context, emit func(item any) error) (sdk.EntityRefererenceGroup, error) {
+			return repo.StreamWithReferences(ctx, dto, func(instance sdk.EntityInstance[T]) error {
+				return emit(instance)
+			})
+		}).Build(), nil
+	}
+	list, references, pagination, err := repo.ListPage(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := sdk.ExpandReferences(c.Context(), c.DIContainer, &schema, list, c.Payload.ExpandOptions())
 	if err != nil {
 		return nil, err
 	}
-	return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddData(&list).AddSchema(&schema).AddReferences(references).Build(), nil
+	return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddData(&list).AddSchema(&schema).AddReferences(references).AddExpanded(expanded).AddPagination(pagination).Build(), nil
 }
 
 func defaultCreate[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.CreateDTO[sdk.EntityInstance[T]]], schema sdk.RootSchema, entity string) (*sdk.Response[sdk.EntityInstance[T]], error) {
@@ -210,7 +365,7 @@ func defaultCreate[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.Create
 	if err != nil {
 		return nil, err
 	}
-	created, err := repo.Create(context.TODO(), c.Payload)
+	created, err := repo.Create(c.Context(), c.Payload)
 	if err != nil {
 		return nil, err
 	}
@@ -222,11 +377,15 @@ func defaultUpdate[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.Update
 	if err != nil {
 		return nil, err
 	}
-	updated, err := repo.Update(context.TODO(), c.Payload)
+	if c.Payload.Version, err = expectedVersion(c, c.Payload.Version); err != nil {
+		return nil, err
+	}
+	updated, err := repo.Update(c.Context(), c.Payload)
 	if err != nil {
 		return nil, err
 	}
-	return sdk.NewResponseBuilder[sdk.EntityInstance[T]]().AddData(updated).AddSchema(&schema).AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.entity.messages.updated", map[string]any{"id": entity}))).Build(), nil
+	response := sdk.NewResponseBuilder[sdk.EntityInstance[T]]().AddData(updated).AddSchema(&schema).AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.entity.messages.updated", map[string]any{"id": entity})))
+	return withVersionETag(response, updated.Metadata).Build(), nil
 }
 
 func defaultDelete[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadInstanceDTO], entity string) (*sdk.Response[any], error) {
@@ -234,9 +393,103 @@ func defaultDelete[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadIn
 	if err != nil {
 		return nil, err
 	}
-	err = repo.Delete(context.TODO(), c.Payload)
+	if c.Payload.Version, err = expectedVersion(c, c.Payload.Version); err != nil {
+		return nil, err
+	}
+	err = repo.Delete(c.Context(), c.Payload)
 	if err != nil {
 		return nil, err
 	}
 	return sdk.NewResponseBuilder[any]().AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.entity.messages.deleted", map[string]any{"id": entity}))).Build(), nil
 }
+
+func defaultTrash[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadDTO], schema sdk.RootSchema, entity string) (*sdk.Response[[]sdk.EntityInstance[T]], error) {
+	repo, err := sdk.GetDynamicRepository[T](c.DIContainer, entity)
+	if err != nil {
+		return nil, err
+	}
+	list, references, pagination, err := repo.ListPage(c.Context(), trashDTO(c.Payload))
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddData(&list).AddSchema(&schema).AddReferences(references).AddPagination(pagination).Build(), nil
+}
+
+// trashDTO restricts dto to the soft-deleted instances, unless it already filters on the
+// deletion time.
+func trashDTO(dto sdk.ReadDTO) sdk.ReadDTO {
+	filter := make(map[string]interface{}, len(dto.Filter)+1)
+	maps.Copy(filter, dto.Filter)
+	if _, ok := filter[sdk.DeletedAtField]; !ok {
+		filter[sdk.DeletedAtField] = map[string]interface{}{"$ne": nil}
+	}
+	dto.Filter = filter
+	dto.IncludeDeleted = true
+	return dto
+}
+
+func defaultRestore[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadInstanceDTO], schema sdk.RootSchema, entity string) (*sdk.Response[sdk.EntityInstance[T]], error) {
+	repo, err := sdk.GetDynamicRepository[T](c.DIContainer, entity)
+	if err != nil {
+		return nil, err
+	}
+	restored, err := repo.Restore(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	response := sdk.NewResponseBuilder[sdk.EntityInstance[T]]().AddData(restored).AddSchema(&schema).AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.entity.messages.restored", map[string]any{"id": entity})))
+	return withVersionETag(response, restored.Metadata).Build(), nil
+}
+
+func defaultHistory[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadInstanceDTO], entity string) (*sdk.Response[[]sdk.HistoryEntry], error) {
+	repo, err := sdk.GetDynamicRepository[T](c.DIContainer, entity)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := repo.History(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[[]sdk.HistoryEntry]().AddData(&entries).Build(), nil
+}
+
+func defaultHistoryVersion[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadHistoryVersionDTO], entity string) (*sdk.Response[*sdk.HistoryEntry], error) {
+	repo, err := sdk.GetDynamicRepository[T](c.DIContainer, entity)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := repo.HistoryVersion(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[*sdk.HistoryEntry]().AddData(&entry).Build(), nil
+}
+
+func defaultRevert[T sdk.EntityInstanceInterface](c *sdk.EndorContext[sdk.ReadHistoryVersionDTO], schema sdk.RootSchema, entity string) (*sdk.Response[sdk.EntityInstance[T]], error) {
+	repo, err := sdk.GetDynamicRepository[T](c.DIContainer, entity)
+	if err != nil {
+		return nil, err
+	}
+	reverted, err := repo.Revert(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	response := sdk.NewResponseBuilder[sdk.EntityInstance[T]]().AddData(reverted).AddSchema(&schema).AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.history.reverted", map[string]any{"id": entity, "version": c.Payload.Version})))
+	return withVersionETag(response, reverted.Metadata).Build(), nil
+}
+
+// expectedVersion returns the version of the payload or, if absent, of the If-Match header.
+func expectedVersion[P any](c *sdk.EndorContext[P], version *int64) (*int64, error) {
+	if version != nil {
+		return version, nil
+	}
+	return c.IfMatchVersion()
+}
+
+// withVersionETag returns the version of versioned instances as ETag.
+func withVersionETag[R any](response *sdk.ResponseBuilder[R], metadata map[string]any) *sdk.ResponseBuilder[R] {
+	if version, ok := sdk.InstanceVersion(metadata); ok {
+		response.AddHeader("ETag", sdk.VersionETag(version))
+	}
+	return response
+}
@@ -2,6 +2,7 @@ package sdk_entity
 
 import (
 	"context"
+
 	"maps"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
@@ -14,6 +15,8 @@ type EndorHybridSpecializedHandlerCategory[T sdk.EntityInstanceSpecializedInterf
 	Description       string
 	ActionFn          func(getSchema func() sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface
 	repositoryFactory sdk.RepositoryFactory
+	// RequiredPermissions are added to every action of the category.
+	RequiredPermissions []string
 }
 
 func (h *EndorHybridSpecializedHandlerCategory[T]) GetID() string {
@@ -42,6 +45,15 @@ func (h *EndorHybridSpecializedHandlerCategory[T]) GetRepository() sdk.Repositor
 	return h.repositoryFactory
 }
 
+func (h *EndorHybridSpecializedHandlerCategory[T]) GetRequiredPermissions() []string {
+	return h.RequiredPermissions
+}
+
+func (h *EndorHybridSpecializedHandlerCategory[T]) WithRequiredPermissions(permissions ...string) sdk.EndorHybridSpecializedHandlerCategoryInterface {
+	h.RequiredPermissions = append(h.RequiredPermissions, permissions...)
+	return h
+}
+
 func (h *EndorHybridSpecializedHandlerCategory[T]) WithExtendedDescription(description string) sdk.EndorHybridSpecializedHandlerCategoryInterface {
 	h.Description = description
 	return h
@@ -80,6 +92,12 @@ type EndorHybridSpecializedHandler[T sdk.EntityInstanceSpecializedInterface] str
 	staticCategories    []string
 	categories          map[string]sdk.EndorHybridSpecializedHandlerCategoryInterface
 	repositoryFactories map[string]sdk.RepositoryFactory
+	middlewares         []sdk.EndorActionMiddleware
+	schedules           []sdk.Schedule
+	eventSubscriptions  []sdk.EventSubscription
+	versioned           bool
+	softDelete          bool
+	history             bool
 }
 
 func (h EndorHybridSpecializedHandler[T]) GetEntity() string {
@@ -123,6 +141,42 @@ func (h EndorHybridSpecializedHandler[T]) WithPriority(
 	return h
 }
 
+func (h EndorHybridSpecializedHandler[T]) WithMiddlewares(
+	middlewares ...sdk.EndorActionMiddleware,
+) sdk.EndorHybridSpecializedHandlerInterface {
+	h.middlewares = append(append([]sdk.EndorActionMiddleware{}, h.middlewares...), middlewares...)
+	return h
+}
+
+func (h EndorHybridSpecializedHandler[T]) WithSchedules(
+	schedules ...sdk.Schedule,
+) sdk.EndorHybridSpecializedHandlerInterface {
+	h.schedules = append(append([]sdk.Schedule{}, h.schedules...), schedules...)
+	return h
+}
+
+func (h EndorHybridSpecializedHandler[T]) WithEventSubscriptions(
+	subscriptions ...sdk.EventSubscription,
+) sdk.EndorHybridSpecializedHandlerInterface {
+	h.eventSubscriptions = append(append([]sdk.EventSubscription{}, h.eventSubscriptions...), subscriptions...)
+	return h
+}
+
+func (h EndorHybridSpecializedHandler[T]) WithVersioning() sdk.EndorHybridSpecializedHandlerInterface {
+	h.versioned = true
+	return h
+}
+
+func (h EndorHybridSpecializedHandler[T]) WithSoftDelete() sdk.EndorHybridSpecializedHandlerInterface {
+	h.softDelete = true
+	return h
+}
+
+func (h EndorHybridSpecializedHandler[T]) WithHistory() sdk.EndorHybridSpecializedHandlerInterface {
+	h.history = true
+	return h
+}
+
 // define methods. The params getSchema allow to inject the dynamic schema
 func (h EndorHybridSpecializedHandler[T]) WithActions(
 	fn func(getSchema func() sdk.RootSchema) map[string]sdk.EndorHandlerActionInterface,
@@ -149,10 +203,11 @@ func (h EndorHybridSpecializedHandler[T]) GetHybridCategories() []sdk.Category {
 	staticCategories := []sdk.Category{}
 	for _, categoryID := range h.staticCategories {
 		staticCategories = append(staticCategories, sdk.Category{
-			ID:          h.categories[categoryID].GetID(),
-			Title:       h.categories[categoryID].GetTitle(),
-			Description: h.categories[categoryID].GetDescription(),
-			Schema:      h.categories[categoryID].GetSchema(),
+			ID:                  h.categories[categoryID].GetID(),
+			Title:               h.categories[categoryID].GetTitle(),
+			Description:         h.categories[categoryID].GetDescription(),
+			Schema:              h.categories[categoryID].GetSchema(),
+			RequiredPermissions: h.categories[categoryID].GetRequiredPermissions(),
 		})
 	}
 	return staticCategories
@@ -169,13 +224,15 @@ func (h EndorHybridSpecializedHandler[T]) ToEndorHandler(metadataSchema sdk.Root
 	// merge additional categories
 	for _, additionalCategory := range additionalCategories {
 		h.categories[additionalCategory.ID] = NewEndorHybridSpecializedHandlerCategory[T](additionalCategory.ID, additionalCategory.Title).
-			WithExtendedDescription(additionalCategory.Description)
+			WithExtendedDescription(additionalCategory.Description).
+			WithRequiredPermissions(additionalCategory.RequiredPermissions...)
 		var additionalCategorySchema sdk.RootSchema
 		_ = yaml.Unmarshal([]byte(additionalCategory.Schema), &additionalCategorySchema)
 		categoriesMetadataSchema[additionalCategory.ID] = additionalCategorySchema
 	}
 
 	// schema
+	metadataSchema = withManagedFieldsSchema(metadataSchema, h.versioned, h.softDelete)
 	rootSchemaWithMetadata := getRootSchemaWithMetadata[T](metadataSchema)
 	getSchemaCallback := func() sdk.RootSchema { return *rootSchemaWithMetadata }
 
@@ -184,6 +241,12 @@ func (h EndorHybridSpecializedHandler[T]) ToEndorHandler(metadataSchema sdk.Root
 	// remove delete and update
 	delete(methods, "create")
 	delete(methods, "update")
+	if h.softDelete {
+		maps.Copy(methods, getSoftDeleteActions[T](h.Entity, *rootSchemaWithMetadata))
+	}
+	if h.history {
+		maps.Copy(methods, getHistoryActions[T](h.Entity, *rootSchemaWithMetadata))
+	}
 	// add custom methods
 	if h.methodsFn != nil {
 		maps.Copy(methods, h.methodsFn(getSchemaCallback))
@@ -193,6 +256,9 @@ func (h EndorHybridSpecializedHandler[T]) ToEndorHandler(metadataSchema sdk.Root
 		autogenerateID := true
 		return NewEntityInstanceRepository[T](h.Entity, *rootSchemaWithMetadata, sdk.EntityInstanceRepositoryOptions{
 			AutoGenerateID: &autogenerateID,
+			Versioned:      h.versioned,
+			SoftDelete:     h.softDelete,
+			History:        h.history,
 		}, session, container)
 	}
 	h.repositoryFactories[h.Entity] = masterRepositoryFactory
@@ -205,12 +271,15 @@ func (h EndorHybridSpecializedHandler[T]) ToEndorHandler(metadataSchema sdk.Root
 				autogenerateID := true
 				return NewEntityInstanceRepository[T](h.Entity, *rootSchemaWithMetadata, sdk.EntityInstanceRepositoryOptions{
 					AutoGenerateID: &autogenerateID,
+					Versioned:      h.versioned,
+					SoftDelete:     h.softDelete,
+					History:        h.history,
 				}, session, container)
 			}
 			h.repositoryFactories[h.Entity+"/"+categoryID] = categoryRepositoryFactory
 			// add default CRUD methods specified for category
 			categoryMethods := category.CreateDefaultActions(h.Entity, h.EntityDescription, metadataSchema, categoriesMetadataSchema[categoryID])
-			maps.Copy(methods, categoryMethods)
+			maps.Copy(methods, sdk.WithActionRequiredPermissions(categoryMethods, category.GetRequiredPermissions()...))
 		}
 	}
 
@@ -219,8 +288,10 @@ func (h EndorHybridSpecializedHandler[T]) ToEndorHandler(metadataSchema sdk.Root
 		EntityTitle:         h.EntityTitle,
 		EntityDescription:   h.EntityDescription,
 		Priority:            h.Priority,
-		Actions:             methods,
+		Actions:             sdk.WithActionMiddlewares(methods, h.middlewares...),
 		RepositoryFactories: h.repositoryFactories,
+		Schedules:           h.schedules,
+		EventSubscriptions:  h.eventSubscriptions,
 	}
 }
 
@@ -288,7 +359,10 @@ func getDefaultActionsForCategory[T sdk.EntityInstanceSpecializedInterface](enti
 							"id": {
 								Type: sdk.SchemaTypeString,
 							},
-							"data": schema.Schema,
+							"version": {
+								Type: sdk.SchemaTypeInteger,
+							},
+							"data": partialSchema(schema),
 						},
 					},
 				},
@@ -316,11 +390,23 @@ func defaultListSpecialized[T sdk.EntityInstanceSpecializedInterface](c *sdk.End
 	} else {
 		c.Payload.Filter = categoryFilter
 	}
-	list, references, err := repo.ListWithReferences(context.TODO(), c.Payload)
+	if c.StreamRequested() {
+		dto := c.Payload
+		return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddSchema(&schema).AddStream(func(ctx context.Context, emit func(item any) error) (sdk.EntityRefererenceGroup, error) {
+			return repo.StreamWithReferences(ctx, dto, func(instance sdk.EntityInstance[T]) error {
+				return emit(instance)
+			})
+		}).Build(), nil
+	}
+	list, references, pagination, err := repo.ListPage(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := sdk.ExpandReferences(c.Context(), c.DIContainer, &schema, list, c.Payload.ExpandOptions())
 	if err != nil {
 		return nil, err
 	}
-	return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddData(&list).AddSchema(&schema).AddReferences(references).Build(), nil
+	return sdk.NewResponseBuilder[[]sdk.EntityInstance[T]]().AddData(&list).AddSchema(&schema).AddReferences(references).AddExpanded(expanded).AddPagination(pagination).Build(), nil
 }
 
 func defaultCreateSpecialized[T sdk.EntityInstanceSpecializedInterface](c *sdk.EndorContext[sdk.CreateDTO[sdk.EntityInstanceSpecialized[T]]], schema sdk.RootSchema, entityPath string) (*sdk.Response[sdk.EntityInstance[T]], error) {
@@ -329,7 +415,7 @@ func defaultCreateSpecialized[T sdk.EntityInstanceSpecializedInterface](c *sdk.E
 		return nil, err
 	}
 	c.Payload.Data.SetCategoryType(c.CategoryType)
-	created, err := repo.Create(context.TODO(), sdk.CreateDTO[sdk.EntityInstance[T]]{
+	created, err := repo.Create(c.Context(), sdk.CreateDTO[sdk.EntityInstance[T]]{
 		Data: c.Payload.Data.EntityInstance,
 	})
 	if err != nil {
@@ -343,11 +429,16 @@ func defaultInstanceSpecialized[T sdk.EntityInstanceSpecializedInterface](c *sdk
 	if err != nil {
 		return nil, err
 	}
-	instance, references, err := repo.InstanceWithReferences(context.TODO(), c.Payload)
+	instance, references, err := repo.InstanceWithReferences(c.Context(), c.Payload)
 	if err != nil {
 		return nil, err
 	}
-	return sdk.NewResponseBuilder[*sdk.EntityInstance[T]]().AddData(&instance).AddSchema(&schema).AddReferences(references).Build(), nil
+	expanded, err := sdk.ExpandReferences(c.Context(), c.DIContainer, &schema, instance, c.Payload.ExpandOptions())
+	if err != nil {
+		return nil, err
+	}
+	response := sdk.NewResponseBuilder[*sdk.EntityInstance[T]]().AddData(&instance).AddSchema(&schema).AddReferences(references).AddExpanded(expanded)
+	return withVersionETag(response, instance.Metadata).Build(), nil
 }
 
 func defaultUpdateSpecialized[T sdk.EntityInstanceSpecializedInterface](c *sdk.EndorContext[sdk.UpdateByIdDTO[sdk.PartialEntityInstance[T]]], schema sdk.RootSchema, entityPath string) (*sdk.Response[sdk.EntityInstance[T]], error) {
@@ -355,9 +446,13 @@ func defaultUpdateSpecialized[T sdk.EntityInstanceSpecializedInterface](c *sdk.E
 	if err != nil {
 		return nil, err
 	}
-	updated, err := repo.Update(context.TODO(), c.Payload)
+	if c.Payload.Version, err = expectedVersion(c, c.Payload.Version); err != nil {
+		return nil, err
+	}
+	updated, err := repo.Update(c.Context(), c.Payload)
 	if err != nil {
 		return nil, err
 	}
-	return sdk.NewResponseBuilder[sdk.EntityInstance[T]]().AddData(updated).AddSchema(&schema).AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.entity.messages.updated_category", map[string]any{"name": entityPath}))).Build(), nil
+	response := sdk.NewResponseBuilder[sdk.EntityInstance[T]]().AddData(updated).AddSchema(&schema).AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.entity.messages.updated_category", map[string]any{"name": entityPath})))
+	return withVersionETag(response, updated.Metadata).Build(), nil
 }
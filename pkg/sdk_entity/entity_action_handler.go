@@ -1,6 +1,8 @@
 package sdk_entity
 
 import (
+	"fmt"
+
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 )
 
@@ -57,6 +59,9 @@ func (h *EntityActionHandler) instance(c *sdk.EndorContext[sdk.ReadInstanceDTO])
 	if err != nil {
 		return nil, err
 	}
+	if !c.Session.HasPermissions(entityAction.entityAction.RequiredPermissions) {
+		return nil, sdk.NewNotFoundError(fmt.Errorf("entity action %s not found", c.Payload.Id)).WithTranslation("sdk.entity.messages.action_not_found", nil)
+	}
 	entityAction.entityAction.Description = c.ResolveTExpr(entityAction.entityAction.Description)
 	return sdk.NewResponseBuilder[sdk.EntityAction]().AddData(&entityAction.entityAction).AddSchema(sdk.NewSchema(&sdk.EntityAction{})).Build(), nil
 }
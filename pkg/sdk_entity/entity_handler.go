@@ -2,6 +2,7 @@ package sdk_entity
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 )
@@ -32,6 +33,13 @@ func NewEntityHandler(microServiceId string, module string, handlers *[]sdk.Endo
 				entityService.list,
 				"${t.sdk.entity.handler.actions.list}",
 			),
+			"references": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.entity.handler.actions.references}",
+					ReadOnly:    true,
+				},
+				entityService.references,
+			),
 		})
 }
 
@@ -90,3 +98,21 @@ func (h *EntityHandler) instance(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk
 	}
 	return sdk.NewResponseBuilder[sdk.Entity]().AddData(&resolved).AddSchema(sdk.NewSchema(sdk.Entity{})).Build(), nil
 }
+
+// references resolves the references to an entity of the module for the other services
+// (see sdk_client.ReferenceResolver). The session must be allowed to run the list action
+// of the entity.
+func (h *EntityHandler) references(c *sdk.EndorContext[sdk.ReadReferencesDTO]) (*sdk.Response[sdk.EntityReferenceGroupDescriptions], error) {
+	repo, ok := c.DIContainer.GetRepositories()[c.Payload.Entity]
+	if !ok {
+		return nil, sdk.NewNotFoundError(fmt.Errorf("entity %s not found", c.Payload.Entity)).WithTranslation("sdk.entity.messages.not_found", map[string]any{"id": c.Payload.Entity})
+	}
+	if err := c.DIContainer.AuthorizeAction(c.Session, path.Join(c.DIContainer.GetModule(), c.Payload.Entity, "list")); err != nil {
+		return nil, err
+	}
+	descriptions, err := repo.FindReferences(c.Context(), sdk.ReadInstancesDTO{Ids: c.Payload.Ids})
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[sdk.EntityReferenceGroupDescriptions]().AddData(&descriptions).Build(), nil
+}
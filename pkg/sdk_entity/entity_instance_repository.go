@@ -50,6 +50,30 @@ func (r *EntityInstanceRepository[T]) Delete(ctx context.Context, dto sdk.ReadIn
 	return r.repository.Delete(ctx, dto)
 }
 
+func (r *EntityInstanceRepository[T]) Restore(ctx context.Context, dto sdk.ReadInstanceDTO) (*sdk.EntityInstance[T], error) {
+	return r.repository.Restore(ctx, dto)
+}
+
+func (r *EntityInstanceRepository[T]) History(ctx context.Context, dto sdk.ReadInstanceDTO) ([]sdk.HistoryEntry, error) {
+	return r.repository.History(ctx, dto)
+}
+
+func (r *EntityInstanceRepository[T]) HistoryVersion(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.HistoryEntry, error) {
+	return r.repository.HistoryVersion(ctx, dto)
+}
+
+func (r *EntityInstanceRepository[T]) Revert(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.EntityInstance[T], error) {
+	return r.repository.Revert(ctx, dto)
+}
+
+func (r *EntityInstanceRepository[T]) FindReferencing(ctx context.Context, reference sdk.EntityReference, id string) ([]string, error) {
+	return r.repository.FindReferencing(ctx, reference, id)
+}
+
+func (r *EntityInstanceRepository[T]) ClearReference(ctx context.Context, reference sdk.EntityReference, id string) error {
+	return r.repository.ClearReference(ctx, reference, id)
+}
+
 func (r *EntityInstanceRepository[T]) Update(ctx context.Context, dto sdk.UpdateByIdDTO[sdk.PartialEntityInstance[T]]) (*sdk.EntityInstance[T], error) {
 	return r.repository.Update(ctx, dto)
 }
@@ -58,6 +82,10 @@ func (r *EntityInstanceRepository[T]) FindReferences(ctx context.Context, dto sd
 	return r.repository.FindReferences(ctx, dto)
 }
 
+func (r *EntityInstanceRepository[T]) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	return r.repository.FindDocuments(ctx, dto)
+}
+
 func (r *EntityInstanceRepository[T]) GetEntity() string {
 	return r.entityId
 }
@@ -73,3 +101,11 @@ func (r *EntityInstanceRepository[T]) InstanceWithReferences(ctx context.Context
 func (r *EntityInstanceRepository[T]) ListWithReferences(ctx context.Context, dto sdk.ReadDTO) ([]sdk.EntityInstance[T], sdk.EntityRefererenceGroup, error) {
 	return r.repository.ListWithReferences(ctx, dto)
 }
+
+func (r *EntityInstanceRepository[T]) ListPage(ctx context.Context, dto sdk.ReadDTO) ([]sdk.EntityInstance[T], sdk.EntityRefererenceGroup, *sdk.Pagination, error) {
+	return r.repository.ListPage(ctx, dto)
+}
+
+func (r *EntityInstanceRepository[T]) StreamWithReferences(ctx context.Context, dto sdk.ReadDTO, yield func(instance sdk.EntityInstance[T]) error) (sdk.EntityRefererenceGroup, error) {
+	return r.repository.StreamWithReferences(ctx, dto, yield)
+}
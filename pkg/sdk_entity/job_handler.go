@@ -0,0 +1,68 @@
+package sdk_entity
+
+import (
+	"github.com/mattiabonardi/endor-sdk-go/internal/repository"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+// NewJobRepository returns the MongoDB repository of the jobs of the async actions.
+func NewJobRepository() sdk.JobRepositoryInterface {
+	return repository.NewMongoJobRepository()
+}
+
+// NewJobHandler exposes the jobs of the async actions started by the session user.
+func NewJobHandler() sdk.EndorHandlerInterface {
+	jobService := JobHandler{}
+	return NewEndorBaseHandler[*sdk.Job]("job", "${t.sdk.job.handler.title}").
+		WithActions(map[string]sdk.EndorHandlerActionInterface{
+			"schema": sdk.NewAction(
+				jobService.schema,
+				"${t.sdk.job.handler.actions.schema}",
+			),
+			"list": sdk.NewAction(
+				jobService.list,
+				"${t.sdk.job.handler.actions.list}",
+			),
+			"instance": sdk.NewAction(
+				jobService.instance,
+				"${t.sdk.job.handler.actions.instance}",
+			),
+			"cancel": sdk.NewAction(
+				jobService.cancel,
+				"${t.sdk.job.handler.actions.cancel}",
+			),
+		})
+}
+
+type JobHandler struct{}
+
+func (h *JobHandler) schema(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+	return sdk.NewResponseBuilder[any]().AddSchema(sdk.NewSchema(&sdk.Job{})).Build(), nil
+}
+
+func (h *JobHandler) list(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[[]sdk.Job], error) {
+	jobs, err := c.DIContainer.GetJobManager().List(c.Context(), c.Session)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[[]sdk.Job]().AddData(&jobs).AddSchema(sdk.NewSchema(&sdk.Job{})).Build(), nil
+}
+
+func (h *JobHandler) instance(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk.Response[sdk.Job], error) {
+	job, err := c.DIContainer.GetJobManager().Instance(c.Context(), c.Session, c.Payload.Id)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[sdk.Job]().AddData(job).AddSchema(sdk.NewSchema(&sdk.Job{})).Build(), nil
+}
+
+func (h *JobHandler) cancel(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk.Response[sdk.Job], error) {
+	job, err := c.DIContainer.GetJobManager().Cancel(c.Context(), c.Session, c.Payload.Id)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[sdk.Job]().
+		AddData(job).
+		AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityInfo, c.T("sdk.job.messages.cancelled", map[string]any{"id": job.Id}))).
+		Build(), nil
+}
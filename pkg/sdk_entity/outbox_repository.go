@@ -0,0 +1,11 @@
+package sdk_entity
+
+import (
+	"github.com/mattiabonardi/endor-sdk-go/internal/repository"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+// NewOutboxRepository returns the MongoDB repository of the outbox of the entity events.
+func NewOutboxRepository() sdk.OutboxRepositoryInterface {
+	return repository.NewMongoOutboxRepository()
+}
@@ -0,0 +1,11 @@
+package sdk_entity
+
+import (
+	"github.com/mattiabonardi/endor-sdk-go/internal/repository"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+// NewScheduleRunRepository returns the MongoDB repository of the runs of the scheduled actions.
+func NewScheduleRunRepository() sdk.ScheduleRunRepositoryInterface {
+	return repository.NewMongoScheduleRunRepository()
+}
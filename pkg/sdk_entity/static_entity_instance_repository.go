@@ -48,6 +48,30 @@ func (r *StaticEntityInstanceRepository[T]) Delete(ctx context.Context, dto sdk.
 	return r.repository.Delete(ctx, dto)
 }
 
+func (r *StaticEntityInstanceRepository[T]) Restore(ctx context.Context, dto sdk.ReadInstanceDTO) (T, error) {
+	return r.repository.Restore(ctx, dto)
+}
+
+func (r *StaticEntityInstanceRepository[T]) History(ctx context.Context, dto sdk.ReadInstanceDTO) ([]sdk.HistoryEntry, error) {
+	return r.repository.History(ctx, dto)
+}
+
+func (r *StaticEntityInstanceRepository[T]) HistoryVersion(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (*sdk.HistoryEntry, error) {
+	return r.repository.HistoryVersion(ctx, dto)
+}
+
+func (r *StaticEntityInstanceRepository[T]) Revert(ctx context.Context, dto sdk.ReadHistoryVersionDTO) (T, error) {
+	return r.repository.Revert(ctx, dto)
+}
+
+func (r *StaticEntityInstanceRepository[T]) FindReferencing(ctx context.Context, reference sdk.EntityReference, id string) ([]string, error) {
+	return r.repository.FindReferencing(ctx, reference, id)
+}
+
+func (r *StaticEntityInstanceRepository[T]) ClearReference(ctx context.Context, reference sdk.EntityReference, id string) error {
+	return r.repository.ClearReference(ctx, reference, id)
+}
+
 func (r *StaticEntityInstanceRepository[T]) Update(ctx context.Context, dto sdk.UpdateByIdDTO[map[string]interface{}]) (T, error) {
 	return r.repository.Update(ctx, dto)
 }
@@ -56,6 +80,10 @@ func (r *StaticEntityInstanceRepository[T]) FindReferences(ctx context.Context,
 	return r.repository.FindReferences(ctx, ids)
 }
 
+func (r *StaticEntityInstanceRepository[T]) FindDocuments(ctx context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	return r.repository.FindDocuments(ctx, dto)
+}
+
 func (r *StaticEntityInstanceRepository[T]) GetEntity() string {
 	return r.entityId
 }
@@ -72,3 +100,11 @@ func (r *StaticEntityInstanceRepository[T]) InstanceWithReferences(ctx context.C
 func (r *StaticEntityInstanceRepository[T]) ListWithReferences(ctx context.Context, dto sdk.ReadDTO) ([]T, sdk.EntityRefererenceGroup, error) {
 	return r.repository.ListWithReferences(ctx, dto)
 }
+
+func (r *StaticEntityInstanceRepository[T]) ListPage(ctx context.Context, dto sdk.ReadDTO) ([]T, sdk.EntityRefererenceGroup, *sdk.Pagination, error) {
+	return r.repository.ListPage(ctx, dto)
+}
+
+func (r *StaticEntityInstanceRepository[T]) StreamWithReferences(ctx context.Context, dto sdk.ReadDTO, yield func(instance T) error) (sdk.EntityRefererenceGroup, error) {
+	return r.repository.StreamWithReferences(ctx, dto, yield)
+}
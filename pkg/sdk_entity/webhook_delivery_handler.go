@@ -0,0 +1,71 @@
+package sdk_entity
+
+import (
+	"github.com/mattiabonardi/endor-sdk-go/internal/repository"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+// NewWebhookDeliveryRepository returns the MongoDB repository of the delivery log of the webhooks.
+func NewWebhookDeliveryRepository() sdk.WebhookDeliveryRepositoryInterface {
+	return repository.NewMongoWebhookDeliveryRepository()
+}
+
+// NewWebhookDeliveryHandler exposes the delivery log of the webhooks declared by the DSL
+// entities, read-only, to the sessions granted sdk.WebhookReadPermission.
+func NewWebhookDeliveryHandler() sdk.EndorHandlerInterface {
+	deliveryService := WebhookDeliveryHandler{}
+	return NewEndorBaseHandler[*sdk.WebhookDelivery]("webhook-delivery", "${t.sdk.webhook_delivery.handler.title}").
+		WithActions(sdk.WithActionRequiredPermissions(map[string]sdk.EndorHandlerActionInterface{
+			"schema": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.webhook_delivery.handler.actions.schema}",
+					ReadOnly:    true,
+				},
+				deliveryService.schema,
+			),
+			"list": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.webhook_delivery.handler.actions.list}",
+					ReadOnly:    true,
+				},
+				deliveryService.list,
+			),
+			"instance": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.webhook_delivery.handler.actions.instance}",
+					ReadOnly:    true,
+				},
+				deliveryService.instance,
+			),
+		}, sdk.WebhookReadPermission))
+}
+
+type WebhookDeliveryHandler struct{}
+
+func (h *WebhookDeliveryHandler) schema(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+	return sdk.NewResponseBuilder[any]().AddSchema(sdk.NewSchema(&sdk.WebhookDelivery{})).Build(), nil
+}
+
+func (h *WebhookDeliveryHandler) list(c *sdk.EndorContext[sdk.ReadWebhookDeliveriesDTO]) (*sdk.Response[[]sdk.WebhookDelivery], error) {
+	deliveries, err := h.repository(c.DIContainer).List(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[[]sdk.WebhookDelivery]().AddData(&deliveries).AddSchema(sdk.NewSchema(&sdk.WebhookDelivery{})).Build(), nil
+}
+
+func (h *WebhookDeliveryHandler) instance(c *sdk.EndorContext[sdk.ReadInstanceDTO]) (*sdk.Response[sdk.WebhookDelivery], error) {
+	delivery, err := h.repository(c.DIContainer).Instance(c.Context(), c.Payload.Id)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[sdk.WebhookDelivery]().AddData(delivery).AddSchema(sdk.NewSchema(&sdk.WebhookDelivery{})).Build(), nil
+}
+
+// repository returns the delivery log of the webhook dispatcher of the container.
+func (h *WebhookDeliveryHandler) repository(container sdk.EndorDIContainerInterface) sdk.WebhookDeliveryRepositoryInterface {
+	if dispatcher := container.GetWebhookDispatcher(); dispatcher != nil {
+		return dispatcher.Repository()
+	}
+	return NewWebhookDeliveryRepository()
+}
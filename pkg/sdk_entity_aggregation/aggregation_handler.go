@@ -2,6 +2,7 @@ package sdk_entity_aggregation
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_entity"
@@ -29,12 +30,17 @@ func NewAggregationHandler(priority int, handler EntityStageHandler, opts ...Agg
 				Public:                false,
 				SkipPayloadValidation: false,
 				InputSchema:           buildPipelineSchema(),
+				ReadOnly:              true,
 			},
 			func(c *sdk.EndorContext[AggregationPipeline]) (*sdk.Response[[]map[string]interface{}], error) {
+				// the pipeline runs in memory: a streamed response would not bound its memory
+				if c.StreamRequested() {
+					return nil, sdk.NewGenericError(http.StatusNotAcceptable, fmt.Errorf("aggregation results cannot be streamed")).WithTranslation("sdk.aggregation.messages.stream_not_supported", nil)
+				}
 				// opts is captured from the outer scope and already includes the
 				// executor option when a non-nil executor was provided.
 				engine := NewAggregationEngine(c.Session, c.DIContainer, opts...)
-				result, schema, refs, err := engine.Execute(c.GinContext.Request.Context(), c.Payload)
+				result, schema, refs, err := engine.Execute(c.Context(), c.Payload)
 				if err != nil {
 					return nil, sdk.NewBadRequestError(fmt.Errorf("aggregation failed: %w", err)).WithTranslation("sdk.aggregation.messages.failed", nil)
 				}
@@ -0,0 +1,34 @@
+package sdk_entity_aggregation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+)
+
+func TestAggregationHandler_StreamNotAcceptable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	execute := NewAggregationHandler(0, nil).ToEndorHandler().Actions["execute"]
+	serve := func(accept string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/test/aggregation/execute", strings.NewReader(`[]`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			c.Request.Header.Set("Accept", accept)
+		}
+		execute.CreateHTTPCallback("test", "aggregation", "execute", "", sdk.Session{Locale: "en"}, testDI)(c)
+		return recorder
+	}
+
+	if recorder := serve(sdk.ContentTypeNDJSON); recorder.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406 for a streamed aggregation, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serve(""); recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
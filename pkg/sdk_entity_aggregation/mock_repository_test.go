@@ -2,6 +2,7 @@ package sdk_entity_aggregation
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
@@ -19,10 +20,46 @@ func (m *mockDIContainer) GetRepositories() map[string]sdk.EndorRepositoryInterf
 	return m.repos
 }
 
+func (m *mockDIContainer) GetModule() string {
+	return "test"
+}
+
 func (m *mockDIContainer) GetTranslator() *sdk_i18n.Translator {
 	return sdk_i18n.NewTranslator(nil)
 }
 
+func (m *mockDIContainer) GetEventBus() *sdk.EventBus {
+	return nil
+}
+
+func (m *mockDIContainer) GetJobManager() *sdk.JobManager {
+	return nil
+}
+
+func (m *mockDIContainer) GetOutbox() *sdk.Outbox {
+	return nil
+}
+
+func (m *mockDIContainer) GetWebhookDispatcher() *sdk.WebhookDispatcher {
+	return nil
+}
+
+func (m *mockDIContainer) GetAuditTrail() *sdk.AuditTrail {
+	return nil
+}
+
+func (m *mockDIContainer) GetReferenceResolver() sdk.ReferenceResolver {
+	return nil
+}
+
+func (m *mockDIContainer) AuthorizeAction(session sdk.Session, actionId string) error {
+	return nil
+}
+
+func (m *mockDIContainer) InvokeAction(_ sdk.EndorContextInterface, actionId string, _ any) (any, error) {
+	return nil, fmt.Errorf("action %s not available in tests", actionId)
+}
+
 // mockRepository implements sdk.DocumentRepositoryInterface with an in-memory
 // document set. It is intended for use in unit tests only.
 type mockRepository struct {
@@ -49,6 +86,20 @@ func (r *mockRepository) FindReferences(_ context.Context, _ sdk.ReadInstancesDT
 	return sdk.EntityReferenceGroupDescriptions{}, nil
 }
 
+// FindDocuments returns the documents whose "id" is one of dto.Ids.
+func (r *mockRepository) FindDocuments(_ context.Context, dto sdk.ReadInstancesDTO) (map[string]map[string]interface{}, error) {
+	result := make(map[string]map[string]interface{})
+	for _, doc := range r.docs {
+		id := fmt.Sprintf("%v", doc["id"])
+		for _, wanted := range dto.Ids {
+			if id == wanted {
+				result[id] = doc
+			}
+		}
+	}
+	return result, nil
+}
+
 // RawList returns documents as []map[string]interface{}, applying the filter from ReadDTO when
 // present so that push-down $match optimizations work correctly in tests.
 func (r *mockRepository) RawList(_ context.Context, dto sdk.ReadDTO) ([]map[string]interface{}, error) {
@@ -43,27 +43,24 @@ func extractReferenceIDsFromFlatDocs(schema *sdk.Schema, docs []map[string]inter
 	return entityIDs
 }
 
-// resolveReferences looks up entity IDs in the RepositoryRegistry and returns
-// the merged EntityRefererenceGroup.
+// resolveReferences looks up entity IDs in the RepositoryRegistry (or with the
+// ReferenceResolver for the entities of other modules) and returns the merged
+// EntityRefererenceGroup, keyed by entity name without the domain.
 func resolveReferences(ctx context.Context, entityIDs map[string][]string, di sdk.EndorDIContainerInterface) (sdk.EntityRefererenceGroup, error) {
 	if len(entityIDs) == 0 {
 		return nil, nil
 	}
+	resolved, err := sdk.ResolveEntityReferences(ctx, di, entityIDs)
+	if err != nil {
+		return nil, err
+	}
 	refs := make(sdk.EntityRefererenceGroup)
-	for entityName, ids := range entityIDs {
+	for entityName, descriptions := range resolved {
 		// UISchema.Entity may be a qualified "domain/entity" ID; strip the domain.
 		lookupName := entityName
 		if _, parsed, err := sdk.ParseEntityID(entityName); err == nil {
 			lookupName = parsed
 		}
-		repo, ok := di.GetRepositories()[lookupName]
-		if !ok {
-			continue
-		}
-		descriptions, err := repo.FindReferences(ctx, sdk.ReadInstancesDTO{Ids: ids})
-		if err != nil {
-			return nil, fmt.Errorf("references for entity %q: %w", entityName, err)
-		}
 		if len(descriptions) > 0 {
 			refs[lookupName] = descriptions
 		}
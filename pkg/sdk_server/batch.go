@@ -0,0 +1,182 @@
+package sdk_server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+)
+
+const (
+	// batchAction is served at POST /api/v1/<module>/batch.
+	batchAction         = "batch"
+	maxBatchItems       = 50
+	maxBatchConcurrency = 8
+)
+
+// BatchRequest is the payload of the batch endpoint.
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+	// Concurrent runs the items in parallel: use it only when they do not depend on each other.
+	Concurrent bool `json:"concurrent"`
+	// StopOnError skips the items not started yet once an item fails.
+	StopOnError bool `json:"stopOnError"`
+}
+
+type BatchItem struct {
+	// ActionId is the full action identifier (<module>/<entity>/[<category>/]<action>).
+	ActionId string          `json:"actionId"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+}
+
+// BatchItemResult holds the status and the response body the action would have answered
+// over HTTP. Results are returned in the order of the request items.
+type BatchItemResult struct {
+	ActionId string          `json:"actionId"`
+	Status   int             `json:"status,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Skipped  bool            `json:"skipped,omitempty"`
+}
+
+// actionServer serves a single action on c with an already built session.
+type actionServer func(c *gin.Context, session sdk.Session, actionId string)
+
+// newBatchHandler returns the handler of the batch items, served with serve as
+// POST /api/v1/<actionId> requests carrying the session in their context.
+func newBatchHandler(serve actionServer) http.Handler {
+	engine := gin.New()
+	engine.POST("/api/v1/*actionId", func(c *gin.Context) {
+		session, _ := sdk.SessionFromContext(c.Request.Context())
+		serve(c, session, strings.TrimPrefix(c.Param("actionId"), "/"))
+	})
+	return engine
+}
+
+func serveBatch(c *gin.Context, session sdk.Session, translator *sdk_i18n.Translator, items http.Handler) {
+	var request BatchRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil || len(request.Items) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no items")
+		}
+		writeErrorResponse(c, translator, session.Locale, sdk.NewBadRequestError(err).WithTranslation("sdk.batch.invalid_request", map[string]any{"error": err.Error()}))
+		return
+	}
+	if len(request.Items) > maxBatchItems {
+		writeErrorResponse(c, translator, session.Locale, sdk.NewBadRequestError(fmt.Errorf("batch of %d items exceeds %d", len(request.Items), maxBatchItems)).WithTranslation("sdk.batch.too_many_items", map[string]any{"max": maxBatchItems}))
+		return
+	}
+	results := runBatch(c, session, request, items)
+	c.JSON(http.StatusOK, sdk.NewResponseBuilder[[]BatchItemResult]().AddData(&results).Build())
+}
+
+func runBatch(parent *gin.Context, session sdk.Session, request BatchRequest, items http.Handler) []BatchItemResult {
+	results := make([]BatchItemResult, len(request.Items))
+	for i, item := range request.Items {
+		results[i] = BatchItemResult{ActionId: item.ActionId, Skipped: true}
+	}
+	var failed atomic.Bool
+	run := func(i int) {
+		results[i] = runBatchItem(parent, session, request.Items[i], items)
+		if results[i].Status >= http.StatusBadRequest {
+			failed.Store(true)
+		}
+	}
+
+	if !request.Concurrent {
+		for i := range request.Items {
+			if request.StopOnError && failed.Load() {
+				break
+			}
+			run(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxBatchConcurrency)
+	for i := range request.Items {
+		slots <- struct{}{}
+		if request.StopOnError && failed.Load() {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// runBatchItem serves the item as if it were a POST /api/v1/<actionId> carrying the
+// headers of the batch request, and captures the answer. Items always answer JSON, never a
+// stream, and a panic of the action becomes the 500 answer of its item.
+func runBatchItem(parent *gin.Context, session sdk.Session, item BatchItem, items http.Handler) (result BatchItemResult) {
+	payload := []byte(item.Payload)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	ctx := sdk.ContextWithSession(parent.Request.Context(), session)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/api/v1/"+item.ActionId, bytes.NewReader(payload))
+	if err != nil {
+		return batchItemError(item, http.StatusBadRequest, err)
+	}
+	request.Header = parent.Request.Header.Clone()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = batchItemError(item, http.StatusInternalServerError, fmt.Errorf("action %s panicked: %v", item.ActionId, r))
+		}
+	}()
+	writer := &bufferedResponseWriter{header: http.Header{}}
+	items.ServeHTTP(writer, request)
+
+	return BatchItemResult{
+		ActionId: item.ActionId,
+		Status:   writer.status,
+		Response: json.RawMessage(writer.body.Bytes()),
+	}
+}
+
+func batchItemError(item BatchItem, status int, err error) BatchItemResult {
+	response, _ := json.Marshal(sdk.NewDefaultResponseBuilder().AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityFatal, err.Error())).Build())
+	return BatchItemResult{ActionId: item.ActionId, Status: status, Response: response}
+}
+
+// bufferedResponseWriter keeps the answer of a batch item in memory.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
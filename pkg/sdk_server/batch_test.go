@@ -0,0 +1,143 @@
+package sdk_server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer answers with the payload, fails actions ending with /fail and sleeps on /slow.
+func echoServer(calls *atomic.Int32) http.Handler {
+	return newBatchHandler(func(c *gin.Context, session sdk.Session, actionId string) {
+		calls.Add(1)
+		if strings.HasSuffix(actionId, "/fail") {
+			c.JSON(http.StatusBadRequest, sdk.NewDefaultResponseBuilder().AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityFatal, "failed")).Build())
+			return
+		}
+		if strings.HasSuffix(actionId, "/slow") {
+			time.Sleep(30 * time.Millisecond)
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		data := map[string]any{"user": session.UserId, "locale": c.GetHeader("Accept-Language")}
+		_ = json.Unmarshal(body, &data)
+		c.JSON(http.StatusOK, sdk.NewResponseBuilder[map[string]any]().AddData(&data).Build())
+	})
+}
+
+func newBatchContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/sdk/batch", strings.NewReader(body))
+	c.Request.Header.Set("Accept-Language", "it")
+	return c, recorder
+}
+
+func TestServeBatch_ResultsInOrder(t *testing.T) {
+	var calls atomic.Int32
+	c, recorder := newBatchContext(`{"items": [
+		{"actionId": "sdk/order/slow", "payload": {"code": "A"}},
+		{"actionId": "sdk/order/fail"},
+		{"actionId": "sdk/order/list", "payload": {"code": "B"}}
+	]}`)
+	serveBatch(c, sdk.Session{UserId: "user-1", Locale: "en"}, sdk_i18n.NewTranslator(nil), echoServer(&calls))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response sdk.Response[[]BatchItemResult]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	results := *response.Data
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "sdk/order/slow", results[0].ActionId)
+	assert.Equal(t, http.StatusOK, results[0].Status)
+	assert.JSONEq(t, `{"messages":[],"data":{"code":"A","user":"user-1","locale":"it"},"schema":null,"references":null}`, string(results[0].Response))
+	assert.Equal(t, http.StatusBadRequest, results[1].Status)
+	assert.Equal(t, http.StatusOK, results[2].Status)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRunBatch_StopOnError(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newBatchContext("")
+	results := runBatch(c, sdk.Session{}, BatchRequest{StopOnError: true, Items: []BatchItem{
+		{ActionId: "sdk/order/list"},
+		{ActionId: "sdk/order/fail"},
+		{ActionId: "sdk/order/list"},
+	}}, echoServer(&calls))
+
+	assert.Equal(t, http.StatusOK, results[0].Status)
+	assert.Equal(t, http.StatusBadRequest, results[1].Status)
+	assert.True(t, results[2].Skipped)
+	assert.Zero(t, results[2].Status)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRunBatch_Concurrent(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newBatchContext("")
+	items := make([]BatchItem, maxBatchConcurrency)
+	for i := range items {
+		items[i] = BatchItem{ActionId: "sdk/order/slow"}
+	}
+	start := time.Now()
+	results := runBatch(c, sdk.Session{}, BatchRequest{Concurrent: true, Items: items}, echoServer(&calls))
+
+	assert.Less(t, time.Since(start), time.Duration(maxBatchConcurrency)*30*time.Millisecond)
+	for _, result := range results {
+		assert.Equal(t, http.StatusOK, result.Status)
+	}
+	assert.Equal(t, int32(maxBatchConcurrency), calls.Load())
+}
+
+func TestRunBatch_PanicsAndStreams(t *testing.T) {
+	var accept atomic.Value
+	items := newBatchHandler(func(c *gin.Context, session sdk.Session, actionId string) {
+		if strings.HasSuffix(actionId, "/panic") {
+			panic("boom")
+		}
+		accept.Store(c.GetHeader("Accept"))
+		c.JSON(http.StatusOK, sdk.NewDefaultResponseBuilder().Build())
+	})
+	c, _ := newBatchContext("")
+	c.Request.Header.Set("Accept", sdk.ContentTypeNDJSON)
+	results := runBatch(c, sdk.Session{}, BatchRequest{Concurrent: true, Items: []BatchItem{
+		{ActionId: "sdk/order/panic"},
+		{ActionId: "sdk/order/list"},
+	}}, items)
+
+	assert.Equal(t, http.StatusInternalServerError, results[0].Status)
+	assert.Contains(t, string(results[0].Response), "sdk/order/panic panicked")
+	assert.Equal(t, http.StatusOK, results[1].Status)
+	assert.Equal(t, "application/json", accept.Load(), "items never stream")
+}
+
+func TestServeBatch_InvalidRequest(t *testing.T) {
+	var calls atomic.Int32
+	items := make([]string, maxBatchItems+1)
+	for i := range items {
+		items[i] = `{"actionId": "sdk/order/list"}`
+	}
+	for name, body := range map[string]string{
+		"malformed": `{"items": `,
+		"empty":     `{"items": []}`,
+		"too many":  `{"items": [` + strings.Join(items, ",") + `]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, recorder := newBatchContext(body)
+			serveBatch(c, sdk.Session{Locale: "en"}, sdk_i18n.NewTranslator(nil), echoServer(&calls))
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		})
+	}
+	assert.Zero(t, calls.Load())
+}
@@ -0,0 +1,358 @@
+package sdk_server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+)
+
+// jwtLeeway is the clock skew tolerated on exp and nbf.
+const jwtLeeway = 30 * time.Second
+
+// JWTVerifier validates the bearer token of incoming requests locally, so that a service
+// reached without the gateway authMiddleware (e.g. from inside the cluster) does not trust
+// the X-Endor-* headers blindly.
+type JWTVerifier struct {
+	keys     []jwtKey
+	audience string
+	now      func() time.Time
+}
+
+type jwtKey struct {
+	id  string
+	key any // *rsa.PublicKey, *ecdsa.PublicKey or []byte (HMAC secret)
+}
+
+// JWTClaims are the registered and Endor specific claims read from a verified token.
+type JWTClaims struct {
+	Subject           string        `json:"sub"`
+	SessionId         string        `json:"sid"`
+	Username          string        `json:"username"`
+	PreferredUsername string        `json:"preferred_username"`
+	Audience          jwtStringList `json:"aud"`
+	ExpiresAt         *int64        `json:"exp"`
+	NotBefore         *int64        `json:"nbf"`
+	Roles             jwtStringList `json:"roles"`
+	Permissions       jwtStringList `json:"permissions"`
+	Scope             string        `json:"scope"`
+	// Development grants the ephemeral registry overlay (see sdk.Session.Development).
+	Development bool `json:"development"`
+	// Raw holds every claim of the token, including custom ones.
+	Raw map[string]any `json:"-"`
+}
+
+// jwtStringList accepts both a JSON string (space separated) and an array of strings.
+type jwtStringList []string
+
+func (l *jwtStringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = strings.Fields(single)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// NewJWTVerifier builds a verifier from the JWT_* settings of the configuration.
+// It returns nil when neither a JWKS file nor a PEM public key is configured.
+func NewJWTVerifier(config *sdk_configuration.ServerConfig) (*JWTVerifier, error) {
+	if config.JWTJWKSPath == "" && config.JWTPublicKeyPath == "" {
+		return nil, nil
+	}
+	verifier := &JWTVerifier{audience: config.JWTAudience, now: time.Now}
+	if config.JWTJWKSPath != "" {
+		content, err := os.ReadFile(config.JWTJWKSPath)
+		if err != nil {
+			return nil, fmt.Errorf("read JWKS file: %w", err)
+		}
+		keys, err := parseJWKS(content)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = append(verifier.keys, keys...)
+	}
+	if config.JWTPublicKeyPath != "" {
+		content, err := os.ReadFile(config.JWTPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT public key: %w", err)
+		}
+		key, err := parsePEMPublicKey(content)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = append(verifier.keys, jwtKey{key: key})
+	}
+	return verifier, nil
+}
+
+// Verify checks signature, exp, nbf and aud of the token and returns its claims.
+func (v *JWTVerifier) Verify(token string) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+	if err := v.verifySignature(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claims := &JWTClaims{}
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	if err := decodeJWTSegment(parts[1], &claims.Raw); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	now := v.now()
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token without exp claim")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return nil, fmt.Errorf("token audience %v does not include %s", []string(claims.Audience), v.audience)
+	}
+	return claims, nil
+}
+
+// VerifySession verifies session.AccessToken and fills the session identity from the claims.
+// Identity headers already present in the session (user id, username, session id, roles,
+// permissions) must match the claims, otherwise the request is rejected. The development
+// header is honoured only with the development claim.
+func (v *JWTVerifier) VerifySession(session sdk.Session) (sdk.Session, error) {
+	token, ok := strings.CutPrefix(session.AccessToken, "Bearer ")
+	if !ok || token == "" {
+		return session, sdk.NewUnauthorizedError(errors.New("missing bearer token")).WithTranslation("sdk.authentication.missing_token", nil)
+	}
+	claims, err := v.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return session, sdk.NewUnauthorizedError(err).WithTranslation("sdk.authentication.invalid_token", nil)
+	}
+
+	username := claims.Username
+	if username == "" {
+		username = claims.PreferredUsername
+	}
+	permissions := []string(claims.Permissions)
+	if len(permissions) == 0 {
+		permissions = strings.Fields(claims.Scope)
+	}
+	checks := []struct {
+		header string
+		value  string
+		claim  string
+	}{
+		{sdk.X_ENDOR_USER_ID, session.UserId, claims.Subject},
+		{sdk.X_ENDOR_USERNAME, session.Username, username},
+		{sdk.X_ENDOR_SESSION_ID, session.Id, claims.SessionId},
+	}
+	for _, check := range checks {
+		if check.value != "" && check.value != check.claim {
+			return session, headerMismatchError(check.header)
+		}
+	}
+	if len(session.Roles) > 0 && !sameStringSet(session.Roles, claims.Roles) {
+		return session, headerMismatchError(sdk.X_ENDOR_ROLES)
+	}
+	if len(session.Permissions) > 0 && !sameStringSet(session.Permissions, permissions) {
+		return session, headerMismatchError(sdk.X_ENDOR_PERMISSIONS)
+	}
+	if session.Development && !claims.Development {
+		return session, headerMismatchError(sdk.X_ENDOR_DEVELOPMENT)
+	}
+
+	session.UserId = claims.Subject
+	session.Username = username
+	session.Id = claims.SessionId
+	session.Roles = claims.Roles
+	session.Permissions = permissions
+	return session, nil
+}
+
+func headerMismatchError(header string) *sdk.EndorError {
+	return sdk.NewUnauthorizedError(fmt.Errorf("header %s does not match the token claims", header)).WithTranslation("sdk.authentication.header_mismatch", map[string]any{"header": header})
+}
+
+func (v *JWTVerifier) verifySignature(alg string, kid string, signingInput []byte, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+	for _, candidate := range v.keys {
+		if kid != "" && candidate.id != "" && candidate.id != kid {
+			continue
+		}
+		// the key type must match the algorithm, so that a public key can never be used as an HMAC secret
+		switch key := candidate.key.(type) {
+		case *rsa.PublicKey:
+			if alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if alg == "ES256" && key.Curve == elliptic.P256() && len(signature) == 64 {
+				r := new(big.Int).SetBytes(signature[:32])
+				s := new(big.Int).SetBytes(signature[32:])
+				if ecdsa.Verify(key, digest[:], r, s) {
+					return nil
+				}
+			}
+		case []byte:
+			if alg == "HS256" {
+				mac := hmac.New(sha256.New, key)
+				mac.Write(signingInput)
+				if subtle.ConstantTimeCompare(mac.Sum(nil), signature) == 1 {
+					return nil
+				}
+			}
+		}
+	}
+	if alg != "RS256" && alg != "ES256" && alg != "HS256" {
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	return errors.New("invalid token signature")
+}
+
+func decodeJWTSegment(segment string, target any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+func parseJWKS(content []byte) ([]jwtKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make([]jwtKey, 0, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				return nil, fmt.Errorf("invalid RSA key %q in JWKS", k.Kid)
+			}
+			keys = append(keys, jwtKey{id: k.Kid, key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}})
+		case "EC":
+			if k.Crv != "P-256" {
+				return nil, fmt.Errorf("unsupported curve %q for key %q in JWKS", k.Crv, k.Kid)
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("invalid EC key %q in JWKS", k.Kid)
+			}
+			keys = append(keys, jwtKey{id: k.Kid, key: &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}})
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("invalid symmetric key %q in JWKS", k.Kid)
+			}
+			keys = append(keys, jwtKey{id: k.Kid, key: secret})
+		default:
+			return nil, fmt.Errorf("unsupported key type %q in JWKS", k.Kty)
+		}
+	}
+	return keys, nil
+}
+
+func parsePEMPublicKey(content []byte) (any, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found in JWT public key")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in JWT public key", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT public key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported JWT public key type %T", key)
+}
+
+func sameStringSet(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, value := range a {
+		if !slices.Contains(b, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package sdk_server_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signToken(t *testing.T, alg string, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	}
+	return signingInput + "." + b64(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":         "user-1",
+		"sid":         "session-1",
+		"username":    "mario",
+		"aud":         []string{"endor", "other"},
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"nbf":         time.Now().Add(-time.Minute).Unix(),
+		"roles":       []string{"sales"},
+		"permissions": "order:read order:write",
+	}
+}
+
+type testKeys struct {
+	rsa    *rsa.PrivateKey
+	ec     *ecdsa.PrivateKey
+	secret []byte
+}
+
+func newJWKSVerifier(t *testing.T) (*sdk_server.JWTVerifier, testKeys) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := testKeys{rsa: rsaKey, ec: ecKey, secret: []byte("a-very-secret-hmac-key")}
+
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "oct", "kid": "hs-1", "k": b64(keys.secret)},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	verifier, err := sdk_server.NewJWTVerifier(&sdk_configuration.ServerConfig{JWTJWKSPath: path, JWTAudience: "endor"})
+	require.NoError(t, err)
+	require.NotNil(t, verifier)
+	return verifier, keys
+}
+
+func TestJWTVerifier_DisabledWithoutKeys(t *testing.T) {
+	verifier, err := sdk_server.NewJWTVerifier(&sdk_configuration.ServerConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, verifier)
+}
+
+func TestJWTVerifier_Algorithms(t *testing.T) {
+	verifier, keys := newJWKSVerifier(t)
+	for name, token := range map[string]string{
+		"RS256": signToken(t, "RS256", "rsa-1", keys.rsa, validClaims()),
+		"ES256": signToken(t, "ES256", "ec-1", keys.ec, validClaims()),
+		"HS256": signToken(t, "HS256", "hs-1", keys.secret, validClaims()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			claims, err := verifier.Verify(token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims.Subject)
+			assert.Equal(t, "mario", claims.Raw["username"])
+		})
+	}
+}
+
+func TestJWTVerifier_RejectsInvalidTokens(t *testing.T) {
+	verifier, keys := newJWKSVerifier(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	notYetValid := validClaims()
+	notYetValid["nbf"] = time.Now().Add(time.Hour).Unix()
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "someone-else"
+	withoutExp := validClaims()
+	delete(withoutExp, "exp")
+
+	cases := map[string]string{
+		"expired":         signToken(t, "RS256", "rsa-1", keys.rsa, expired),
+		"not yet valid":   signToken(t, "RS256", "rsa-1", keys.rsa, notYetValid),
+		"wrong audience":  signToken(t, "RS256", "rsa-1", keys.rsa, wrongAudience),
+		"without exp":     signToken(t, "RS256", "rsa-1", keys.rsa, withoutExp),
+		"unknown signer":  signToken(t, "RS256", "rsa-1", otherKey, validClaims()),
+		"alg none":        signToken(t, "none", "", nil, validClaims()),
+		"alg confusion":   signToken(t, "HS256", "rsa-1", x509.MarshalPKCS1PublicKey(&keys.rsa.PublicKey), validClaims()),
+		"malformed token": "not-a-token",
+	}
+	for name, token := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJWTVerifier_PEMPublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	verifier, err := sdk_server.NewJWTVerifier(&sdk_configuration.ServerConfig{JWTPublicKeyPath: path})
+	require.NoError(t, err)
+	_, err = verifier.Verify(signToken(t, "ES256", "", ecKey, validClaims()))
+	assert.NoError(t, err)
+}
+
+func TestJWTVerifier_VerifySession(t *testing.T) {
+	verifier, keys := newJWKSVerifier(t)
+	token := "Bearer " + signToken(t, "RS256", "rsa-1", keys.rsa, validClaims())
+
+	session, err := verifier.VerifySession(sdk.Session{AccessToken: token, Locale: "it", Username: "mario"})
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", session.UserId)
+	assert.Equal(t, "mario", session.Username)
+	assert.Equal(t, "session-1", session.Id)
+	assert.Equal(t, "it", session.Locale)
+	assert.Equal(t, []string{"sales"}, session.Roles)
+	assert.Equal(t, []string{"order:read", "order:write"}, session.Permissions)
+	assert.False(t, session.Development)
+
+	var endorError *sdk.EndorError
+	_, err = verifier.VerifySession(sdk.Session{AccessToken: token, UserId: "user-2"})
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, http.StatusUnauthorized, endorError.StatusCode)
+	assert.Equal(t, "sdk.authentication.header_mismatch", endorError.TranslationKey)
+	assert.Equal(t, sdk.X_ENDOR_USER_ID, endorError.TranslationArgs["header"])
+
+	_, err = verifier.VerifySession(sdk.Session{AccessToken: token, Permissions: []string{"*"}})
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, sdk.X_ENDOR_PERMISSIONS, endorError.TranslationArgs["header"])
+
+	_, err = verifier.VerifySession(sdk.Session{AccessToken: token, Development: true})
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, sdk.X_ENDOR_DEVELOPMENT, endorError.TranslationArgs["header"], "the development overlay needs the claim")
+
+	claims := validClaims()
+	claims["development"] = true
+	developer := "Bearer " + signToken(t, "RS256", "rsa-1", keys.rsa, claims)
+	session, err = verifier.VerifySession(sdk.Session{AccessToken: developer, Development: true})
+	require.NoError(t, err)
+	assert.True(t, session.Development)
+	session, err = verifier.VerifySession(sdk.Session{AccessToken: developer})
+	require.NoError(t, err)
+	assert.False(t, session.Development, "the claim alone does not switch to the overlay")
+
+	_, err = verifier.VerifySession(sdk.Session{AccessToken: "Basic abc"})
+	require.ErrorAs(t, err, &endorError)
+	assert.Equal(t, "sdk.authentication.missing_token", endorError.TranslationKey)
+}
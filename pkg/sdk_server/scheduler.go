@@ -0,0 +1,240 @@
+package sdk_server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_entity"
+)
+
+// scheduler fires the schedules declared by the handlers of the module. Every instance
+// of the service runs one: the lease on the run of each tick makes a single one invoke it.
+type scheduler struct {
+	module   string
+	handlers func() ([]sdk.EndorHandler, error)
+	invoke   func(actionId string, payload map[string]any) error
+	runs     sdk.ScheduleRunRepositoryInterface
+	instance string
+	now      func() time.Time
+	logger   *sdk.Logger
+
+	// warned holds the schedules already reported as invalid, so they are logged once
+	warned sync.Map
+	// running tracks the fired runs, which outlive their tick
+	running sync.WaitGroup
+}
+
+// scheduledAction is a valid declared schedule with its parsed expression.
+type scheduledAction struct {
+	sdk.ScheduledAction
+	payload map[string]any
+	cron    *cronSchedule
+}
+
+func newScheduler(module string, runs sdk.ScheduleRunRepositoryInterface, logger *sdk.Logger) *scheduler {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+	return &scheduler{
+		module: module,
+		handlers: func() ([]sdk.EndorHandler, error) {
+			return sdk_entity.NewEndorEntityRepository(sdk.Session{}).EndorHandlerList()
+		},
+		invoke:   invokeAsSystem(module, logger),
+		runs:     runs,
+		instance: instance,
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// invokeAsSystem runs the action in-process with the system session.
+func invokeAsSystem(module string, logger *sdk.Logger) func(actionId string, payload map[string]any) error {
+	return func(actionId string, payload map[string]any) error {
+		session := sdk.SystemSession()
+		container, err := sdk_entity.GetRegistryCore().Container(session)
+		if err != nil {
+			return err
+		}
+		if payload == nil {
+			payload = map[string]any{}
+		}
+		_, err = container.InvokeAction(&sdk.EndorContext[sdk.NoPayload]{
+			MicroServiceId: module,
+			Session:        session,
+			DIContainer:    container,
+			Logger:         *logger,
+		}, actionId, payload)
+		return err
+	}
+}
+
+// start fires the schedules at the beginning of every minute until ctx is done.
+func (s *scheduler) start(ctx context.Context) {
+	go func() {
+		for {
+			now := s.now()
+			timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case tick := <-timer.C:
+				s.tick(ctx, tick.Truncate(time.Minute))
+			}
+		}
+	}()
+}
+
+// tick fires, concurrently, every schedule matching the minute of tick without waiting for
+// them: a run longer than a minute must not delay the next ticks. The lease of each run
+// keeps a tick from being fired twice.
+func (s *scheduler) tick(ctx context.Context, tick time.Time) {
+	actions, err := s.scheduledActions()
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("scheduler: failed to read the schedules: %s", err.Error()))
+		return
+	}
+	for _, action := range actions {
+		if !action.cron.matches(tick) {
+			continue
+		}
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			s.fire(ctx, action, tick)
+		}()
+	}
+}
+
+// fire invokes the action if this instance acquires the run of the tick.
+func (s *scheduler) fire(ctx context.Context, action scheduledAction, tick time.Time) {
+	run := sdk.ScheduleRun{
+		Id:         fmt.Sprintf("%s@%s", action.Id, tick.UTC().Format(time.RFC3339)),
+		ScheduleId: action.Id,
+		ActionId:   action.ActionId,
+		Tick:       tick.UTC(),
+		Instance:   s.instance,
+		Status:     sdk.ScheduleRunStatusRunning,
+		Messages:   []sdk.ResponseMessage{},
+		StartedAt:  s.now().UTC(),
+	}
+	acquired, err := s.runs.Acquire(ctx, run)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("scheduler: failed to acquire %s: %s", run.Id, err.Error()))
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	run.Status = sdk.ScheduleRunStatusDone
+	if err := s.invoke(action.ActionId, action.payload); err != nil {
+		run.Status = sdk.ScheduleRunStatusFailed
+		run.Messages = append(run.Messages, sdk.NewMessage(sdk.ResponseMessageGravityError, err.Error()))
+		s.logger.Error(fmt.Sprintf("scheduler: %s failed: %s", run.Id, err.Error()))
+	}
+	completedAt := s.now().UTC()
+	run.CompletedAt = &completedAt
+	if err := s.runs.Update(ctx, run); err != nil {
+		s.logger.Error(fmt.Sprintf("scheduler: failed to record %s: %s", run.Id, err.Error()))
+	}
+}
+
+// scheduledActions returns the valid schedules declared by the handlers; the invalid
+// ones are skipped.
+func (s *scheduler) scheduledActions() ([]scheduledAction, error) {
+	handlers, err := s.handlers()
+	if err != nil {
+		return nil, err
+	}
+	actions := []scheduledAction{}
+	for _, handler := range handlers {
+		for _, schedule := range handler.Schedules {
+			actionId := path.Join(s.module, handler.Entity, schedule.Action)
+			id := fmt.Sprintf("%s@%s", actionId, schedule.Cron)
+			if _, ok := handler.Actions[schedule.Action]; !ok {
+				s.warnOnce(id, fmt.Sprintf("scheduler: schedule %s skipped: action not found", id))
+				continue
+			}
+			cron, err := parseCron(schedule.Cron, schedule.Timezone)
+			if err != nil {
+				s.warnOnce(id, fmt.Sprintf("scheduler: schedule %s skipped: %s", id, err.Error()))
+				continue
+			}
+			actions = append(actions, scheduledAction{
+				ScheduledAction: sdk.ScheduledAction{
+					Id:       id,
+					ActionId: actionId,
+					Cron:     schedule.Cron,
+					Timezone: schedule.Timezone,
+				},
+				payload: schedule.Payload,
+				cron:    cron,
+			})
+		}
+	}
+	return actions, nil
+}
+
+func (s *scheduler) warnOnce(id string, msg string) {
+	if _, warned := s.warned.LoadOrStore(id, true); !warned {
+		s.logger.Warn(msg)
+	}
+}
+
+// #region Schedule handler
+
+// newScheduleHandler exposes the schedules of the module and their run history to the
+// sessions granted sdk.ScheduleReadPermission.
+func newScheduleHandler(s *scheduler) sdk.EndorHandlerInterface {
+	return sdk_entity.NewEndorBaseHandler[*sdk.ScheduledAction]("schedule", "${t.sdk.schedule.handler.title}").
+		WithActions(sdk.WithActionRequiredPermissions(map[string]sdk.EndorHandlerActionInterface{
+			"list": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.schedule.handler.actions.list}",
+					ReadOnly:    true,
+				},
+				s.list,
+			),
+			"runs": sdk.NewConfigurableAction(
+				sdk.EndorHandlerActionOptions{
+					Description: "${t.sdk.schedule.handler.actions.runs}",
+					ReadOnly:    true,
+				},
+				s.listRuns,
+			),
+		}, sdk.ScheduleReadPermission))
+}
+
+func (s *scheduler) list(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[[]sdk.ScheduledAction], error) {
+	actions, err := s.scheduledActions()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	schedules := make([]sdk.ScheduledAction, 0, len(actions))
+	for _, action := range actions {
+		if next, ok := action.cron.next(now); ok {
+			action.NextRun = &next
+		}
+		schedules = append(schedules, action.ScheduledAction)
+	}
+	return sdk.NewResponseBuilder[[]sdk.ScheduledAction]().AddData(&schedules).AddSchema(sdk.NewSchema(&sdk.ScheduledAction{})).Build(), nil
+}
+
+func (s *scheduler) listRuns(c *sdk.EndorContext[sdk.ReadScheduleRunsDTO]) (*sdk.Response[[]sdk.ScheduleRun], error) {
+	runs, err := s.runs.List(c.Context(), c.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.NewResponseBuilder[[]sdk.ScheduleRun]().AddData(&runs).AddSchema(sdk.NewSchema(&sdk.ScheduleRun{})).Build(), nil
+}
+
+// #endregion
@@ -0,0 +1,218 @@
+package sdk_server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expr := range []string{"0 2 * * *", "*/15 8-18 * * MON-FRI", "0 0 1,15 jan,jul *", "@daily", "30 4 * * 7"} {
+		_, err := parseCron(expr, "")
+		assert.NoError(t, err, expr)
+	}
+	for _, expr := range []string{"", "0 2 * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * FOO *"} {
+		_, err := parseCron(expr, "")
+		assert.Error(t, err, expr)
+	}
+	_, err := parseCron("0 2 * * *", "Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func TestCronSchedule_Matches(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return parsed
+	}
+	weekdays, err := parseCron("*/15 8-18 * * MON-FRI", "")
+	require.NoError(t, err)
+	assert.True(t, weekdays.matches(at("2025-03-03T08:45:00Z")))  // Monday
+	assert.False(t, weekdays.matches(at("2025-03-03T08:50:00Z"))) // not a quarter
+	assert.False(t, weekdays.matches(at("2025-03-02T08:45:00Z"))) // Sunday
+
+	sunday, err := parseCron("0 0 * * 7", "")
+	require.NoError(t, err)
+	assert.True(t, sunday.matches(at("2025-03-02T00:00:00Z")))
+
+	// with both day fields restricted either one matches
+	either, err := parseCron("0 0 1 * MON", "")
+	require.NoError(t, err)
+	assert.True(t, either.matches(at("2025-03-01T00:00:00Z")))
+	assert.True(t, either.matches(at("2025-03-03T00:00:00Z")))
+	assert.False(t, either.matches(at("2025-03-04T00:00:00Z")))
+
+	rome, err := parseCron("0 2 * * *", "Europe/Rome")
+	require.NoError(t, err)
+	assert.True(t, rome.matches(at("2025-01-10T01:00:00Z")))
+	assert.False(t, rome.matches(at("2025-01-10T02:00:00Z")))
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	from := time.Date(2025, 3, 3, 10, 20, 30, 0, time.UTC)
+
+	nightly, err := parseCron("0 2 * * *", "")
+	require.NoError(t, err)
+	next, ok := nightly.next(from)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC), next)
+
+	leap, err := parseCron("0 0 29 2 *", "")
+	require.NoError(t, err)
+	next, ok = leap.next(from)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), next)
+
+	never, err := parseCron("0 0 30 2 *", "")
+	require.NoError(t, err)
+	_, ok = never.next(from)
+	assert.False(t, ok)
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	halfHour, err := parseCron("0 11 * * *", "Asia/Kolkata")
+	require.NoError(t, err)
+	next, ok = halfHour.next(time.Date(2025, 3, 3, 10, 37, 0, 0, kolkata))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 3, 3, 11, 0, 0, 0, kolkata), next, "the hours start at :00 in a half-hour zone")
+}
+
+func newTestScheduler(runs sdk.ScheduleRunRepositoryInterface, instance string, invoke func(actionId string, payload map[string]any) error) *scheduler {
+	noAction := sdk.NewAction(func(c *sdk.EndorContext[sdk.NoPayload]) (*sdk.Response[any], error) {
+		return nil, nil
+	}, "noop")
+	return &scheduler{
+		module: "sdk",
+		handlers: func() ([]sdk.EndorHandler, error) {
+			return []sdk.EndorHandler{{
+				Entity: "order",
+				Actions: map[string]sdk.EndorHandlerActionInterface{
+					"cleanup": noAction,
+					"archive": noAction,
+				},
+				Schedules: []sdk.Schedule{
+					{Action: "cleanup", Cron: "0 2 * * *", Payload: map[string]any{"days": 30}},
+					{Action: "archive", Cron: "@hourly"},
+					{Action: "missing", Cron: "0 2 * * *"},
+					{Action: "cleanup", Cron: "not a cron"},
+				},
+			}}, nil
+		},
+		invoke:   invoke,
+		runs:     runs,
+		instance: instance,
+		now:      time.Now,
+		logger:   sdk.NewLogger(sdk.LogConfig{}, sdk.LogContext{}),
+	}
+}
+
+func TestScheduler_FiresOncePerTick(t *testing.T) {
+	runs := sdk.NewInMemoryScheduleRunRepository()
+	var mu sync.Mutex
+	invoked := map[string]int{}
+	invoke := func(actionId string, payload map[string]any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		invoked[actionId]++
+		if actionId == "sdk/order/cleanup" {
+			assert.Equal(t, 30, payload["days"])
+		}
+		return nil
+	}
+	replicas := []*scheduler{newTestScheduler(runs, "replica-1", invoke), newTestScheduler(runs, "replica-2", invoke)}
+
+	tick := time.Date(2025, 3, 3, 2, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replica.tick(context.Background(), tick)
+		}()
+	}
+	wg.Wait()
+	replicas[0].tick(context.Background(), tick.Add(time.Minute))
+	for _, replica := range replicas {
+		replica.running.Wait()
+	}
+
+	assert.Equal(t, map[string]int{"sdk/order/cleanup": 1, "sdk/order/archive": 1}, invoked)
+	history, err := runs.List(context.Background(), sdk.ReadScheduleRunsDTO{ScheduleId: "sdk/order/cleanup@0 2 * * *"})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, sdk.ScheduleRunStatusDone, history[0].Status)
+	assert.Equal(t, tick, history[0].Tick)
+	assert.NotNil(t, history[0].CompletedAt)
+}
+
+func TestScheduler_FailureIsRecorded(t *testing.T) {
+	runs := sdk.NewInMemoryScheduleRunRepository()
+	var calls atomic.Int32
+	s := newTestScheduler(runs, "replica-1", func(actionId string, payload map[string]any) error {
+		calls.Add(1)
+		return errors.New("cleanup failed")
+	})
+
+	s.tick(context.Background(), time.Date(2025, 3, 3, 2, 0, 0, 0, time.UTC))
+	s.running.Wait()
+
+	assert.Equal(t, int32(2), calls.Load())
+	history, err := runs.List(context.Background(), sdk.ReadScheduleRunsDTO{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	for _, run := range history {
+		assert.Equal(t, sdk.ScheduleRunStatusFailed, run.Status)
+		assert.Equal(t, "replica-1", run.Instance)
+		require.Len(t, run.Messages, 1)
+		assert.Equal(t, "cleanup failed", run.Messages[0].Value)
+	}
+}
+
+func TestScheduler_SlowRunDoesNotDelayNextTick(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	s := newTestScheduler(sdk.NewInMemoryScheduleRunRepository(), "replica-1", func(actionId string, payload map[string]any) error {
+		if calls.Add(1) == 1 {
+			<-release
+		}
+		return nil
+	})
+
+	tick := time.Date(2025, 3, 3, 3, 0, 0, 0, time.UTC)
+	s.tick(context.Background(), tick)
+	s.tick(context.Background(), tick.Add(time.Hour))
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond, "the next tick fires while the first run is still going")
+	close(release)
+	s.running.Wait()
+}
+
+func TestScheduleHandler_ListsNextRun(t *testing.T) {
+	s := newTestScheduler(sdk.NewInMemoryScheduleRunRepository(), "replica-1", nil)
+	s.now = func() time.Time { return time.Date(2025, 3, 3, 10, 20, 0, 0, time.UTC) }
+
+	response, err := s.list(&sdk.EndorContext[sdk.NoPayload]{})
+	require.NoError(t, err)
+	schedules := *response.Data
+	require.Len(t, schedules, 2)
+	assert.Equal(t, "sdk/order/cleanup@0 2 * * *", schedules[0].Id)
+	assert.Equal(t, time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC), *schedules[0].NextRun)
+	assert.Equal(t, time.Date(2025, 3, 3, 11, 0, 0, 0, time.UTC), *schedules[1].NextRun)
+}
+
+func TestScheduleHandler_ReadOnlyWithPermission(t *testing.T) {
+	s := newTestScheduler(sdk.NewInMemoryScheduleRunRepository(), "replica-1", nil)
+	handler := newScheduleHandler(s).(sdk.EndorBaseHandlerInterface).ToEndorHandler()
+
+	for _, name := range []string{"list", "runs"} {
+		options := handler.Actions[name].GetOptions()
+		assert.True(t, options.ReadOnly, name)
+		assert.Equal(t, []string{sdk.ScheduleReadPermission}, options.RequiredPermissions, name)
+	}
+}
@@ -1,16 +1,20 @@
 package sdk_server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mattiabonardi/endor-sdk-go/internal/api_gateway"
 	"github.com/mattiabonardi/endor-sdk-go/internal/swagger"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_client"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_entity"
 	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_entity_aggregation"
@@ -25,11 +29,15 @@ type EndorHTTPRoute struct {
 }
 
 type Endor struct {
-	endorHandlers *[]sdk.EndorHandlerInterface
-	customRoutes  []EndorHTTPRoute
-	postInitFunc  func()
-	version       string
-	localesFS     fs.FS
+	endorHandlers     *[]sdk.EndorHandlerInterface
+	customRoutes      []EndorHTTPRoute
+	postInitFunc      func()
+	version           string
+	localesFS         fs.FS
+	actionMiddlewares []sdk.EndorActionMiddleware
+	rolePermissions   sdk.RolePermissions
+	outboxPublishers  []sdk.OutboxPublisher
+	referenceResolver sdk.ReferenceResolver
 }
 
 type EndorInitializer struct {
@@ -59,6 +67,32 @@ func (b *EndorInitializer) WithPostInitFunc(f func()) *EndorInitializer {
 	return b
 }
 
+// WithActionMiddlewares registers middlewares that wrap every action of the microservice.
+func (b *EndorInitializer) WithActionMiddlewares(middlewares ...sdk.EndorActionMiddleware) *EndorInitializer {
+	b.endor.actionMiddlewares = append(b.endor.actionMiddlewares, middlewares...)
+	return b
+}
+
+// WithRolePermissions maps the roles received from the gateway to the permissions they grant.
+func (b *EndorInitializer) WithRolePermissions(rolePermissions sdk.RolePermissions) *EndorInitializer {
+	b.endor.rolePermissions = rolePermissions
+	return b
+}
+
+// WithOutboxPublishers enables the transactional outbox: the entity events are recorded
+// together with the writes and relayed to the publishers.
+func (b *EndorInitializer) WithOutboxPublishers(publishers ...sdk.OutboxPublisher) *EndorInitializer {
+	b.endor.outboxPublishers = append(b.endor.outboxPublishers, publishers...)
+	return b
+}
+
+// WithReferenceResolver resolves the references to the entities of other modules with
+// resolver instead of the one configured by REFERENCE_RESOLVER_URL.
+func (b *EndorInitializer) WithReferenceResolver(resolver sdk.ReferenceResolver) *EndorInitializer {
+	b.endor.referenceResolver = resolver
+	return b
+}
+
 func (b *EndorInitializer) WithLocalesFS(localesFS fs.FS) *EndorInitializer {
 	if sub, err := fs.Sub(localesFS, "locales"); err == nil {
 		b.endor.localesFS = sub
@@ -127,9 +161,90 @@ func (h *Endor) Init(module string) {
 		*h.endorHandlers = append(*h.endorHandlers, sdk_entity.NewEntityActionHandler(microServiceId, module, h.endorHandlers, logger))
 	}
 
+	// Check if an EndorHandler with entity == "job" is already defined
+	jobServiceExists := false
+	for _, svc := range *h.endorHandlers {
+		if svc.GetEntity() == "job" {
+			jobServiceExists = true
+			break
+		}
+	}
+	if !jobServiceExists {
+		*h.endorHandlers = append(*h.endorHandlers, sdk_entity.NewJobHandler())
+	}
+	services := sdk_entity.Services{
+		JobManager: sdk.NewJobManager(sdk_entity.NewJobRepository(), config.JobWorkers, config.JobQueueSize),
+	}
+
+	// Check if an EndorHandler with entity == "webhook-delivery" is already defined
+	webhookDeliveryServiceExists := false
+	for _, svc := range *h.endorHandlers {
+		if svc.GetEntity() == "webhook-delivery" {
+			webhookDeliveryServiceExists = true
+			break
+		}
+	}
+	if !webhookDeliveryServiceExists {
+		*h.endorHandlers = append(*h.endorHandlers, sdk_entity.NewWebhookDeliveryHandler())
+	}
+	services.WebhookDispatcher = sdk.NewWebhookDispatcher(sdk_entity.NewWebhookDeliveryRepository())
+
+	// transactional outbox: it delivers the webhooks of the entities too, so that their
+	// retries survive restarts
+	outboxPublishers := h.outboxPublishers
+	if config.OutboxWebhookURL != "" {
+		outboxPublishers = append(outboxPublishers, sdk.NewWebhookOutboxPublisher(config.OutboxWebhookURL))
+	}
+	if config.OutboxEnabled || len(outboxPublishers) > 0 {
+		webhooks := func(entityId string) []sdk.Webhook {
+			return sdk_entity.GetRegistryCore().Webhooks(entityId)
+		}
+		schemas := func(event sdk.EntityEvent) *sdk.RootSchema {
+			return sdk_entity.GetRegistryCore().EventSchema(event)
+		}
+		outboxPublishers = append(outboxPublishers, services.WebhookDispatcher.OutboxPublisher(webhooks, schemas))
+		services.Outbox = sdk.NewOutbox(sdk_entity.NewOutboxRepository(), outboxPublishers, sdk.OutboxOptions{}, logger)
+	}
+
+	// Check if an EndorHandler with entity == "audit" is already defined
+	auditServiceExists := false
+	for _, svc := range *h.endorHandlers {
+		if svc.GetEntity() == "audit" {
+			auditServiceExists = true
+			break
+		}
+	}
+	if !auditServiceExists {
+		*h.endorHandlers = append(*h.endorHandlers, sdk_entity.NewAuditHandler())
+	}
+	if config.AuditEnabled {
+		services.AuditTrail = sdk.NewAuditTrail(sdk_entity.NewAuditRepository(), logger)
+	}
+
+	// references to the entities of other modules
+	services.ReferenceResolver = h.referenceResolver
+	if services.ReferenceResolver == nil && config.ReferenceResolverURL != "" {
+		services.ReferenceResolver = sdk_client.NewReferenceResolver(sdk_client.NewClient(config.ReferenceResolverURL), sdk_client.ReferenceResolverOptions{Logger: logger})
+	}
+
+	// Check if an EndorHandler with entity == "schedule" is already defined
+	scheduler := newScheduler(module, sdk_entity.NewScheduleRunRepository(), logger)
+	scheduleServiceExists := false
+	for _, svc := range *h.endorHandlers {
+		if svc.GetEntity() == "schedule" {
+			scheduleServiceExists = true
+			break
+		}
+	}
+	if !scheduleServiceExists {
+		*h.endorHandlers = append(*h.endorHandlers, newScheduleHandler(scheduler))
+	}
+
 	// Initialize the singleton repository after all handlers are registered.
 	// Must be called after all handler appends so the registry is complete.
 	EndorHandlerRepository := sdk_entity.InitEndorEntityRepository(microServiceId, module, h.endorHandlers, logger, h.localesFS)
+	sdk_entity.GetRegistryCore().ActionMiddlewares = h.actionMiddlewares
+	sdk_entity.GetRegistryCore().Services = services
 	entities, err := EndorHandlerRepository.EndorHandlerList()
 	if err != nil {
 		log.Fatal(err)
@@ -137,34 +252,68 @@ func (h *Endor) Init(module string) {
 
 	actionRepo := EndorHandlerRepository.ActionRepository()
 
-	router.NoRoute(func(c *gin.Context) {
-		urlPath := c.Request.URL.Path
-		if strings.HasPrefix(urlPath, "/api/v1/") {
-			// actionId format: module/entity/[category/]action
-			actionId := strings.TrimPrefix(urlPath, "/api/v1/")
-			_, entity, category, action, err := sdk.ParseEntityActionID(actionId)
+	jwtVerifier, err := NewJWTVerifier(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// serveAction resolves the action for the session and runs its HTTP callback on c.
+	// It is shared by the single action routes and by the items of a batch.
+	serveAction := func(c *gin.Context, session sdk.Session, actionId string) {
+		// actionId format: module/entity/[category/]action
+		_, entity, category, action, err := sdk.ParseEntityActionID(actionId)
+		if err == nil {
+			dict, err := actionRepo.DictionaryActionInstance(session, sdk.ReadInstanceDTO{Id: actionId})
 			if err == nil {
-				// Build the session here: single point of header parsing.
-				// Development=true activates the per-user ephemeral registry overlay.
-				session := sdk.Session{
-					Id:          c.GetHeader(sdk.X_ENDOR_SESSION_ID),
-					UserId:      c.GetHeader(sdk.X_ENDOR_USER_ID),
-					Username:    c.GetHeader(sdk.X_ENDOR_USERNAME),
-					Development: c.GetHeader(sdk.X_ENDOR_DEVELOPMENT) == "true",
-					Locale:      sdk_i18n.NormalizeLocale(c.GetHeader("Accept-Language")),
-					AccessToken: c.GetHeader("Authorization"),
-				}
-				dict, err := actionRepo.DictionaryActionInstance(session, sdk.ReadInstanceDTO{Id: actionId})
-				if err == nil {
-					dict.EndorHandlerAction.CreateHTTPCallback(module, entity, action, category, session, dict.Container)(c)
+				// With local JWT verification requests without a token are anonymous
+				// and may only reach public actions.
+				if jwtVerifier != nil && session.AccessToken == "" && !dict.EndorHandlerAction.GetOptions().Public {
+					writeErrorResponse(c, translator, session.Locale, sdk.NewUnauthorizedError(fmt.Errorf("missing bearer token")).WithTranslation("sdk.authentication.missing_token", nil))
 					return
 				}
+				dict.EndorHandlerAction.CreateHTTPCallback(module, entity, action, category, session, dict.Container)(c)
+				return
 			}
 		}
-		response := sdk.NewDefaultResponseBuilder()
-		locale := sdk_i18n.NormalizeLocale(c.GetHeader("Accept-Language"))
-		response.AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityFatal, translator.T(locale, "sdk.commons.not_found", map[string]any{"uri": c.Request.RequestURI, "method": c.Request.Method})))
-		c.JSON(http.StatusNotFound, response.Build())
+		writeNotFound(c, translator, session.Locale)
+	}
+
+	batchItems := newBatchHandler(serveAction)
+
+	// resolveSubscription resolves the list action guarding the subscribe stream of an entity.
+	resolveSubscription := func(session sdk.Session, listActionId string) (sdk.EndorHandlerActionOptions, *sdk.EventBus, error) {
+		dict, err := actionRepo.DictionaryActionInstance(session, sdk.ReadInstanceDTO{Id: listActionId})
+		if err != nil {
+			return sdk.EndorHandlerActionOptions{}, nil, err
+		}
+		options := dict.EndorHandlerAction.GetOptions()
+		if jwtVerifier != nil && session.AccessToken == "" && !options.Public {
+			return options, nil, sdk.NewUnauthorizedError(fmt.Errorf("missing bearer token")).WithTranslation("sdk.authentication.missing_token", nil)
+		}
+		return options, dict.Container.GetEventBus(), nil
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		urlPath := c.Request.URL.Path
+		if !strings.HasPrefix(urlPath, "/api/v1/") {
+			writeNotFound(c, translator, sdk_i18n.NormalizeLocale(c.GetHeader("Accept-Language")))
+			return
+		}
+		actionId := strings.TrimPrefix(urlPath, "/api/v1/")
+		session, err := h.session(c, jwtVerifier)
+		if err != nil {
+			writeErrorResponse(c, translator, session.Locale, err)
+			return
+		}
+		if actionId == path.Join(module, batchAction) && c.Request.Method == http.MethodPost {
+			serveBatch(c, session, translator, batchItems)
+			return
+		}
+		if path.Base(actionId) == subscribeAction && c.Request.Method == http.MethodGet {
+			serveSubscribe(c, session, translator, actionId, resolveSubscription)
+			return
+		}
+		serveAction(c, session, actionId)
 	})
 
 	err = api_gateway.InitializeApiGatewayConfiguration(microServiceId, module, fmt.Sprintf("http://%s:%s", microServiceId, config.ServerPort), entities)
@@ -184,6 +333,12 @@ func (h *Endor) Init(module string) {
 	// swagger
 	router.StaticFS("/swagger", http.Dir(swaggerPath))
 
+	// scheduled actions
+	scheduler.start(context.Background())
+	if services.Outbox != nil {
+		services.Outbox.Start(context.Background())
+	}
+
 	// post initialization
 	if h.postInitFunc != nil {
 		h.postInitFunc()
@@ -192,3 +347,52 @@ func (h *Endor) Init(module string) {
 	// start http server
 	router.Run()
 }
+
+// session builds the session from the request headers: single point of header parsing.
+// Development=true activates the per-user ephemeral registry overlay.
+func (h *Endor) session(c *gin.Context, jwtVerifier *JWTVerifier) (sdk.Session, error) {
+	session := sdk.Session{
+		Id:          c.GetHeader(sdk.X_ENDOR_SESSION_ID),
+		UserId:      c.GetHeader(sdk.X_ENDOR_USER_ID),
+		Username:    c.GetHeader(sdk.X_ENDOR_USERNAME),
+		Development: c.GetHeader(sdk.X_ENDOR_DEVELOPMENT) == "true",
+		Locale:      sdk_i18n.NormalizeLocale(c.GetHeader("Accept-Language")),
+		AccessToken: c.GetHeader("Authorization"),
+		Roles:       sdk.ParseHeaderList(c.GetHeader(sdk.X_ENDOR_ROLES)),
+		Permissions: sdk.ParseHeaderList(c.GetHeader(sdk.X_ENDOR_PERMISSIONS)),
+	}
+	// With local JWT verification the identity comes from the token claims;
+	// requests without a token are anonymous.
+	if jwtVerifier != nil {
+		if session.AccessToken == "" {
+			session = sdk.Session{Locale: session.Locale}
+		} else {
+			verified, err := jwtVerifier.VerifySession(session)
+			if err != nil {
+				return sdk.Session{Locale: session.Locale}, err
+			}
+			session = verified
+		}
+	}
+	session.Permissions = h.rolePermissions.Resolve(session.Roles, session.Permissions)
+	return session, nil
+}
+
+func writeNotFound(c *gin.Context, translator *sdk_i18n.Translator, locale string) {
+	response := sdk.NewDefaultResponseBuilder()
+	response.AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityFatal, translator.T(locale, "sdk.commons.not_found", map[string]any{"uri": c.Request.URL.RequestURI(), "method": c.Request.Method})))
+	c.JSON(http.StatusNotFound, response.Build())
+}
+
+func writeErrorResponse(c *gin.Context, translator *sdk_i18n.Translator, locale string, err error) {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	var endorError *sdk.EndorError
+	if errors.As(err, &endorError) {
+		status = endorError.StatusCode
+		if endorError.TranslationKey != "" {
+			message = translator.T(locale, endorError.TranslationKey, endorError.TranslationArgs)
+		}
+	}
+	c.JSON(status, sdk.NewDefaultResponseBuilder().AddMessage(sdk.NewMessage(sdk.ResponseMessageGravityFatal, message)).Build())
+}
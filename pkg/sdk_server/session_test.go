@@ -0,0 +1,89 @@
+package sdk_server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_client"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_configuration"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHMACVerifier returns a verifier of the tokens signed with secret, and a signer of them.
+func newHMACVerifier(t *testing.T, secret []byte) (*JWTVerifier, func(claims map[string]any) string) {
+	t.Helper()
+	encode := base64.RawURLEncoding.EncodeToString
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{"kty": "oct", "kid": "hs-1", "k": encode(secret)}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+	verifier, err := NewJWTVerifier(&sdk_configuration.ServerConfig{JWTJWKSPath: path})
+	require.NoError(t, err)
+
+	sign := func(claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT", "kid": "hs-1"})
+		payload, _ := json.Marshal(claims)
+		signingInput := encode(header) + "." + encode(payload)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		return "Bearer " + signingInput + "." + encode(mac.Sum(nil))
+	}
+	return verifier, sign
+}
+
+// TestSession_ClientCallThroughJWTVerifier verifies that a session whose permissions were
+// extended by the role mapping can call another service verifying the tokens.
+func TestSession_ClientCallThroughJWTVerifier(t *testing.T) {
+	verifier, sign := newHMACVerifier(t, []byte("a-very-secret-hmac-key"))
+	endor := &Endor{rolePermissions: sdk.RolePermissions{"sales": {"invoice:read"}}}
+	translator := sdk_i18n.NewTranslator(nil)
+
+	router := gin.New()
+	router.NoRoute(func(c *gin.Context) {
+		session, err := endor.session(c, verifier)
+		if err != nil {
+			writeErrorResponse(c, translator, session.Locale, err)
+			return
+		}
+		c.JSON(http.StatusOK, sdk.NewResponseBuilder[sdk.Session]().AddData(&session).Build())
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	token := sign(map[string]any{
+		"sub":         "user-1",
+		"sid":         "session-1",
+		"username":    "mario",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"roles":       []string{"sales"},
+		"permissions": []string{"order:read"},
+	})
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/sales/order/list", nil)
+	request.Header.Set("Authorization", token)
+	request.Header.Set("Accept-Language", "it")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = request
+	caller, err := endor.session(c, verifier)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"order:read", "invoice:read"}, caller.Permissions, "the role mapping extends the permissions")
+
+	client := sdk_client.NewClient(server.URL).WithSession(caller)
+	response, err := sdk_client.Call[sdk.Session](context.Background(), client, "billing/invoice/list", nil)
+	require.NoError(t, err)
+	callee := *response.Data
+	assert.Equal(t, "user-1", callee.UserId)
+	assert.Equal(t, "it", callee.Locale)
+	assert.ElementsMatch(t, caller.Permissions, callee.Permissions)
+}
@@ -0,0 +1,179 @@
+package sdk_server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+)
+
+const (
+	// subscribeAction is served at GET /api/v1/<module>/<entity>/[<category>/]subscribe.
+	subscribeAction = "subscribe"
+	// subscribeHeartbeat is the interval of the keep-alive comments, which also pick up a
+	// rebuilt DI container (e.g. after a DSL change).
+	subscribeHeartbeat = 15 * time.Second
+	// subscribeBuffer bounds the events waiting to be written to a slow client.
+	subscribeBuffer = 256
+)
+
+// SubscriptionEvent is the data of the Server-Sent Events of the subscribe endpoint: the
+// entity event without the session of the write, except for the user.
+type SubscriptionEvent struct {
+	Id         string              `json:"id"`
+	Type       sdk.EntityEventType `json:"type"`
+	EntityId   string              `json:"entityId"`
+	Category   string              `json:"category,omitempty"`
+	InstanceId string              `json:"instanceId"`
+	Before     map[string]any      `json:"before,omitempty"`
+	After      map[string]any      `json:"after,omitempty"`
+	UserId     string              `json:"userId,omitempty"`
+	OccurredAt time.Time           `json:"occurredAt"`
+}
+
+// subscriptionResolver returns the options of the list action listActionId for the session
+// and the event bus of the DI container serving it.
+type subscriptionResolver func(session sdk.Session, listActionId string) (sdk.EndorHandlerActionOptions, *sdk.EventBus, error)
+
+// subscriptionFilter selects the events streamed to a client.
+type subscriptionFilter struct {
+	entity   string
+	category string
+	filter   map[string]any
+}
+
+// matches reports whether the event concerns an instance selected by the filter; updates
+// match if the instance is selected before or after the write, so that clients see the
+// instances leaving the selection too.
+func (f subscriptionFilter) matches(event sdk.EntityEvent) bool {
+	if event.EntityId != f.entity || (f.category != "" && event.Category != f.category) {
+		return false
+	}
+	if len(f.filter) == 0 {
+		return true
+	}
+	switch event.Type {
+	case sdk.EntityEventCreated:
+		return sdk.MatchFilter(event.After, f.filter)
+	case sdk.EntityEventDeleted:
+		return sdk.MatchFilter(event.Before, f.filter)
+	}
+	return sdk.MatchFilter(event.After, f.filter) || sdk.MatchFilter(event.Before, f.filter)
+}
+
+// serveSubscribe streams the changes of the entity of actionId as Server-Sent Events. The
+// session must be allowed to run the list action of the entity; the optional filter query
+// parameter is a JSON ReadDTO.Filter.
+func serveSubscribe(c *gin.Context, session sdk.Session, translator *sdk_i18n.Translator, actionId string, resolve subscriptionResolver) {
+	_, entity, category, _, err := sdk.ParseEntityActionID(actionId)
+	if err != nil {
+		writeNotFound(c, translator, session.Locale)
+		return
+	}
+	listActionId := path.Join(path.Dir(actionId), "list")
+	options, bus, err := resolve(session, listActionId)
+	if err != nil {
+		var endorError *sdk.EndorError
+		if errors.As(err, &endorError) && endorError.StatusCode != http.StatusNotFound {
+			writeErrorResponse(c, translator, session.Locale, err)
+			return
+		}
+		writeNotFound(c, translator, session.Locale)
+		return
+	}
+	if err := session.AuthorizeAction(listActionId, options.RequiredPermissions); err != nil {
+		writeErrorResponse(c, translator, session.Locale, err)
+		return
+	}
+	selection := subscriptionFilter{entity: entity, category: category}
+	if raw := c.Query("filter"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &selection.filter); err != nil {
+			writeErrorResponse(c, translator, session.Locale, sdk.NewBadRequestError(err).WithTranslation("sdk.subscribe.invalid_filter", map[string]any{"error": err.Error()}))
+			return
+		}
+	}
+
+	events := make(chan sdk.EntityEvent, subscribeBuffer)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+	subscribe := func(bus *sdk.EventBus) func() {
+		return bus.Subscribe(sdk.EventSubscription{Entity: entity, Handler: func(ec *sdk.EndorContext[sdk.EntityEvent]) error {
+			if !selection.matches(ec.Payload) {
+				return nil
+			}
+			// never block the write: a client that cannot keep up is disconnected
+			select {
+			case events <- ec.Payload:
+			default:
+				overflowOnce.Do(func() { close(overflow) })
+			}
+			return nil
+		}})
+	}
+	unsubscribe := subscribe(bus)
+	defer func() { unsubscribe() }()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// tell the browser to reconnect after a second if the stream is closed
+	fmt.Fprint(c.Writer, "retry: 1000\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(subscribeHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-overflow:
+			fmt.Fprint(c.Writer, "event: overflow\ndata: {}\n\n")
+			c.Writer.Flush()
+			return
+		case event := <-events:
+			if err := writeSubscriptionEvent(c, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, current, err := resolve(session, listActionId); err == nil && current != bus {
+				unsubscribe()
+				bus = current
+				unsubscribe = subscribe(bus)
+			}
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeSubscriptionEvent(c *gin.Context, event sdk.EntityEvent) error {
+	data, err := json.Marshal(SubscriptionEvent{
+		Id:         event.Id,
+		Type:       event.Type,
+		EntityId:   event.EntityId,
+		Category:   event.Category,
+		InstanceId: event.InstanceId,
+		Before:     event.Before,
+		After:      event.After,
+		UserId:     event.Session.UserId,
+		OccurredAt: event.OccurredAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
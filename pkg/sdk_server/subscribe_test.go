@@ -0,0 +1,131 @@
+package sdk_server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk"
+	"github.com/mattiabonardi/endor-sdk-go/pkg/sdk_i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSubscribeServer serves the subscribe endpoint with the list action of sdk/order
+// requiring order:read and publishing on bus.
+func newSubscribeServer(bus *sdk.EventBus, session sdk.Session) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	resolve := func(session sdk.Session, listActionId string) (sdk.EndorHandlerActionOptions, *sdk.EventBus, error) {
+		if listActionId != "sdk/order/list" && listActionId != "sdk/order/paid/list" {
+			return sdk.EndorHandlerActionOptions{}, nil, sdk.NewNotFoundError(fmt.Errorf("action %s not found", listActionId))
+		}
+		return sdk.EndorHandlerActionOptions{RequiredPermissions: []string{"order:read"}}, bus, nil
+	}
+	router.NoRoute(func(c *gin.Context) {
+		serveSubscribe(c, session, sdk_i18n.NewTranslator(nil), strings.TrimPrefix(c.Request.URL.Path, "/api/v1/"), resolve)
+	})
+	return httptest.NewServer(router)
+}
+
+// readSubscriptionEvents reads the stream until count events are received, calling ready
+// once the stream is open.
+func readSubscriptionEvents(t *testing.T, response *http.Response, count int, ready func()) []SubscriptionEvent {
+	scanner := bufio.NewScanner(response.Body)
+	events := []SubscriptionEvent{}
+	for len(events) < count && scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "retry:") {
+			ready()
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var event SubscriptionEvent
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestServeSubscribe_StreamsFilteredEvents(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", nil, nil)
+	server := newSubscribeServer(bus, sdk.Session{UserId: "user-1", Permissions: []string{"order:read"}})
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/v1/sdk/order/subscribe?filter=" + url.QueryEscape(`{"status":"paid"}`))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	writer := sdk.Session{UserId: "user-2", AccessToken: "Bearer secret", Permissions: []string{"*"}}
+	events := readSubscriptionEvents(t, response, 3, func() {
+		require.True(t, bus.HasSubscribers("order"))
+		publish := func(event sdk.EntityEvent) {
+			require.NoError(t, bus.Publish(context.Background(), event))
+		}
+		publish(sdk.NewEntityEvent(sdk.EntityEventCreated, "order", "", "order-1", nil, map[string]any{"status": "draft"}, writer))
+		publish(sdk.NewEntityEvent(sdk.EntityEventUpdated, "order", "", "order-1", map[string]any{"status": "draft"}, map[string]any{"status": "paid"}, writer))
+		publish(sdk.NewEntityEvent(sdk.EntityEventCreated, "customer", "", "customer-1", nil, map[string]any{"status": "paid"}, writer))
+		// leaving the selection is notified too
+		publish(sdk.NewEntityEvent(sdk.EntityEventUpdated, "order", "", "order-1", map[string]any{"status": "paid"}, map[string]any{"status": "shipped"}, writer))
+		publish(sdk.NewEntityEvent(sdk.EntityEventDeleted, "order", "", "order-1", map[string]any{"status": "paid"}, nil, writer))
+	})
+
+	require.Len(t, events, 3)
+	assert.Equal(t, sdk.EntityEventUpdated, events[0].Type)
+	assert.Equal(t, "paid", events[0].After["status"])
+	assert.Equal(t, "user-2", events[0].UserId)
+	assert.Equal(t, "shipped", events[1].After["status"])
+	assert.Equal(t, sdk.EntityEventDeleted, events[2].Type)
+}
+
+func TestServeSubscribe_Category(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", nil, nil)
+	server := newSubscribeServer(bus, sdk.Session{Permissions: []string{"order:*"}})
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/v1/sdk/order/paid/subscribe")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	events := readSubscriptionEvents(t, response, 1, func() {
+		require.NoError(t, bus.Publish(context.Background(), sdk.NewEntityEvent(sdk.EntityEventCreated, "order", "draft", "order-1", nil, map[string]any{}, sdk.Session{})))
+		require.NoError(t, bus.Publish(context.Background(), sdk.NewEntityEvent(sdk.EntityEventCreated, "order", "paid", "order-2", nil, map[string]any{}, sdk.Session{})))
+	})
+	require.Len(t, events, 1)
+	assert.Equal(t, "order-2", events[0].InstanceId)
+	assert.Equal(t, "paid", events[0].Category)
+}
+
+func TestServeSubscribe_Rejections(t *testing.T) {
+	bus := sdk.NewEventBus("test-service", nil, nil)
+
+	forbidden := newSubscribeServer(bus, sdk.Session{Permissions: []string{"customer:read"}})
+	defer forbidden.Close()
+	response, err := http.Get(forbidden.URL + "/api/v1/sdk/order/subscribe")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	allowed := newSubscribeServer(bus, sdk.Session{Permissions: []string{"order:read"}})
+	defer allowed.Close()
+	response, err = http.Get(allowed.URL + "/api/v1/sdk/invoice/subscribe")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	response, err = http.Get(allowed.URL + "/api/v1/sdk/order/subscribe?filter=" + url.QueryEscape(`{"status":`))
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	assert.False(t, bus.HasSubscribers("order"))
+}